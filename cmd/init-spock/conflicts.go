@@ -0,0 +1,105 @@
+// cmd/init-spock/conflicts.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pgEdge/pgedge-helm/internal/spock"
+)
+
+// maxTupleWidth truncates tuples in the table output; JSON has them whole.
+const maxTupleWidth = 60
+
+// conflictsCommand reports the conflicts Spock resolved across the mesh
+// within a time window. It exits 1 if a node's log cannot be read or the
+// number of conflicts exceeds -fail-above.
+func conflictsCommand(ctx context.Context, args []string) int {
+	logToStderr()
+
+	fs, f := newFlagSet("conflicts")
+	report := addReportFlags(fs)
+	window := fs.Duration("since", time.Hour, "report conflicts resolved within this window")
+	recent := fs.Int("recent", 10, "number of most recent conflicts to show")
+	failAbove := fs.Int("fail-above", -1, "exit 1 if more conflicts than this were resolved in the window (-1 disables)")
+	if err := parse(fs, args); err != nil {
+		return flagExitCode(err)
+	}
+	if err := report.validate(); err != nil {
+		return flagExitCode(err)
+	}
+	if *window <= 0 {
+		return flagExitCode(fmt.Errorf("invalid -since %s: must be positive", *window))
+	}
+	if *recent < 0 {
+		return flagExitCode(fmt.Errorf("invalid -recent %d: must not be negative", *recent))
+	}
+
+	cfg, pgOpts, err := loadConfig(f)
+	if err != nil {
+		slog.Error("load configuration", "error", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, report.timeout)
+	defer cancel()
+
+	conns := connectPools(ctx, cfg, pgOpts)
+	defer closePools(conns)
+
+	conflicts := spock.ReadConflicts(ctx, cfg, spock.PoolDBs(conns), time.Now().Add(-*window), *recent)
+	if report.format == formatJSON {
+		err = printJSON(os.Stdout, conflicts)
+	} else {
+		err = printConflicts(os.Stdout, conflicts)
+	}
+	if err != nil {
+		slog.Error("write conflicts", "error", err)
+		return 1
+	}
+	if len(conflicts.Errors) > 0 || (*failAbove >= 0 && conflicts.Total > *failAbove) {
+		return 1
+	}
+	return 0
+}
+
+func printConflicts(w io.Writer, r *spock.ConflictReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%d conflicts since %s\n", r.Total, r.Since.Format(time.RFC3339))
+	nodes := make([]string, 0, len(r.Errors))
+	for node := range r.Errors {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	for _, node := range nodes {
+		fmt.Fprintf(tw, "error reading %s: %s\n", node, r.Errors[node])
+	}
+	if len(r.Groups) > 0 {
+		fmt.Fprintln(tw, "\nTABLE\tTYPE\tORIGIN\tRECEIVER\tCOUNT\tFIRST\tLAST")
+		for _, g := range r.Groups {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", g.Table, g.Type, g.Origin, g.Receiver, g.Count,
+				g.First.Format(time.RFC3339), g.Last.Format(time.RFC3339))
+		}
+	}
+	if len(r.Recent) > 0 {
+		fmt.Fprintln(tw, "\nTIME\tTABLE\tTYPE\tRESOLUTION\tORIGIN\tRECEIVER\tREMOTE TUPLE")
+		for _, c := range r.Recent {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.Time.Format(time.RFC3339), c.Table, c.Type,
+				c.Resolution, c.Origin, c.Receiver, truncate(orDash(c.RemoteTuple), maxTupleWidth))
+		}
+	}
+	return tw.Flush()
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,45 @@
+// cmd/init-spock/conflicts_test.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pgEdge/pgedge-helm/internal/spock"
+)
+
+func TestPrintConflicts(t *testing.T) {
+	at := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	r := &spock.ConflictReport{
+		Since:  at.Add(-time.Hour),
+		Total:  2,
+		Groups: []spock.ConflictGroup{{Table: "public.a", Type: "update_update", Origin: "n1", Receiver: "n2", Count: 2, First: at, Last: at}},
+		Recent: []spock.Conflict{{Time: at, Table: "public.a", Type: "update_update", Resolution: "apply_remote",
+			Origin: "n1", Receiver: "n2", RemoteTuple: strings.Repeat("x", 100)}},
+		Errors: map[string]string{"n3": "not connected"},
+	}
+	var buf bytes.Buffer
+	if err := printConflicts(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"2 conflicts since 2026-10-19T11:00:00Z", "error reading n3: not connected", "apply_remote", "…"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, strings.Repeat("x", maxTupleWidth)) {
+		t.Errorf("expected the remote tuple to be truncated:\n%s", out)
+	}
+}
+
+func TestConflictsCommandRejectsBadFlags(t *testing.T) {
+	for _, args := range [][]string{{"-recent", "-1"}, {"-since", "0s"}, {"-since", "-1h"}} {
+		if code := conflictsCommand(context.Background(), args); code != 2 {
+			t.Errorf("%v: expected a usage error, got exit code %d", args, code)
+		}
+	}
+}
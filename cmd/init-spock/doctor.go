@@ -0,0 +1,62 @@
+// cmd/init-spock/doctor.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/pgEdge/pgedge-helm/internal/doctor"
+)
+
+// doctorCommand checks every node for the Spock prerequisites and exits 1
+// if any check fails with an error.
+func doctorCommand(ctx context.Context, args []string) int {
+	logToStderr()
+
+	f, report, err := parseReportFlags("doctor", args)
+	if err != nil {
+		return flagExitCode(err)
+	}
+	cfg, pgOpts, err := loadConfig(f)
+	if err != nil {
+		slog.Error("load configuration", "error", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, report.timeout)
+	defer cancel()
+
+	conns := connectPools(ctx, cfg, pgOpts)
+	defer closePools(conns)
+
+	findings := doctor.Check(ctx, cfg, conns, len(cfg.Nodes)-1)
+	if report.format == formatJSON {
+		if findings == nil {
+			findings = []doctor.Finding{}
+		}
+		err = printJSON(os.Stdout, findings)
+	} else {
+		err = printFindingsTable(os.Stdout, findings)
+	}
+	if err != nil {
+		slog.Error("write findings", "error", err)
+		return 1
+	}
+	if doctor.HasErrors(findings) {
+		return 1
+	}
+	return 0
+}
+
+func printFindingsTable(w io.Writer, findings []doctor.Finding) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tSEVERITY\tCHECK\tMESSAGE\tHINT")
+	for _, f := range findings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", f.Node, f.Severity, f.Check, f.Message, orDash(f.Hint))
+	}
+	return tw.Flush()
+}
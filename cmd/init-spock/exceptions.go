@@ -0,0 +1,105 @@
+// cmd/init-spock/exceptions.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pgEdge/pgedge-helm/internal/spock"
+)
+
+// exceptionsCommand lists the failed apply transactions in every node's
+// exception log, exiting 1 if there are any. With -skip or -retry it
+// resolves those of one subscription instead.
+func exceptionsCommand(ctx context.Context, args []string) int {
+	logToStderr()
+
+	fs, f := newFlagSet("exceptions")
+	report := addReportFlags(fs)
+	skip := fs.String("skip", "", "skip the oldest failed transaction of `subscription` and restart it")
+	retry := fs.String("retry", "", "clear the exception log of `subscription` and restart it to retry")
+	if err := parse(fs, args); err != nil {
+		return flagExitCode(err)
+	}
+	if err := report.validate(); err != nil {
+		return flagExitCode(err)
+	}
+	if *skip != "" && *retry != "" {
+		return flagExitCode(fmt.Errorf("-skip and -retry are mutually exclusive"))
+	}
+
+	cfg, pgOpts, err := loadConfig(f)
+	if err != nil {
+		slog.Error("load configuration", "error", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, report.timeout)
+	defer cancel()
+
+	conns := connectPools(ctx, cfg, pgOpts)
+	defer closePools(conns)
+	dbs := spock.PoolDBs(conns)
+
+	switch {
+	case *skip != "":
+		e, err := spock.SkipException(ctx, cfg, dbs, *skip)
+		if err != nil {
+			slog.Error("skip failed transaction", "error", err)
+			return 1
+		}
+		fmt.Printf("skipped transaction %d from %s committed at %s: %s\n",
+			e.RemoteXID, e.Origin, e.CommitTS.Format(time.RFC3339), e.Error)
+		return 0
+	case *retry != "":
+		if err := spock.RetryException(ctx, cfg, dbs, *retry); err != nil {
+			slog.Error("retry failed transactions", "error", err)
+			return 1
+		}
+		fmt.Printf("restarted %s; run init-spock status to follow it\n", *retry)
+		return 0
+	}
+
+	exceptions := spock.ReadExceptions(ctx, cfg, dbs)
+	if report.format == formatJSON {
+		err = printJSON(os.Stdout, exceptions)
+	} else {
+		err = printExceptions(os.Stdout, exceptions)
+	}
+	if err != nil {
+		slog.Error("write exceptions", "error", err)
+		return 1
+	}
+	if len(exceptions.Exceptions) > 0 || len(exceptions.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printExceptions(w io.Writer, r *spock.ExceptionReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	nodes := make([]string, 0, len(r.Errors))
+	for node := range r.Errors {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	for _, node := range nodes {
+		fmt.Fprintf(tw, "error reading %s: %s\n", node, r.Errors[node])
+	}
+	if len(r.Exceptions) == 0 {
+		fmt.Fprintln(tw, "no failed apply transactions")
+		return tw.Flush()
+	}
+	fmt.Fprintln(tw, "SUBSCRIPTION\tORIGIN\tRECEIVER\tCOMMITTED\tXID\tTABLE\tOPERATION\tERROR")
+	for _, e := range r.Exceptions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n", orDash(e.Subscription), e.Origin, e.Receiver,
+			e.CommitTS.Format(time.RFC3339), e.RemoteXID, orDash(e.Table), orDash(e.Operation), e.Error)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,39 @@
+// cmd/init-spock/exceptions_test.go
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pgEdge/pgedge-helm/internal/spock"
+)
+
+func TestPrintExceptions(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printExceptions(&buf, &spock.ExceptionReport{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "no failed apply transactions") {
+		t.Errorf("unexpected output for an empty log:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	r := &spock.ExceptionReport{
+		Exceptions: []spock.Exception{{
+			Subscription: "sub_n1_n2", Origin: "n1", Receiver: "n2",
+			CommitTS: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC), RemoteXID: 4711,
+			Table: "public.orders", Operation: "INSERT", Error: "duplicate key value violates unique constraint",
+		}},
+		Errors: map[string]string{"n3": "not connected"},
+	}
+	if err := printExceptions(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"error reading n3: not connected", "sub_n1_n2", "2026-10-19T12:00:00Z", "4711", "public.orders", "duplicate key"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// cmd/init-spock/flags.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// flags are the command-line options. The defaults match the paths
+// mounted into the init-spock Job's pod, so the Job passes none.
+type flags struct {
+	configPath  string
+	kubeconfig  string
+	kubeContext string
+	skipWait    bool
+	certPath    string
+	keyPath     string
+	endpoints   endpointFlag
+}
+
+// endpointFlag collects repeated -endpoint node=host:port overrides.
+type endpointFlag map[string]string
+
+func (e endpointFlag) String() string {
+	pairs := make([]string, 0, len(e))
+	for name, endpoint := range e {
+		pairs = append(pairs, name+"="+endpoint)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (e endpointFlag) Set(value string) error {
+	name, endpoint, ok := strings.Cut(value, "=")
+	if !ok || name == "" || endpoint == "" {
+		return fmt.Errorf("expected node=host[:port], got %q", value)
+	}
+	e[name] = endpoint
+	return nil
+}
+
+// newFlagSet returns a flag set for the named command with the options
+// every command needs to reach the nodes.
+func newFlagSet(name string) (*flag.FlagSet, *flags) {
+	f := &flags{endpoints: endpointFlag{}}
+	fs := flag.NewFlagSet("init-spock "+name, flag.ContinueOnError)
+	fs.StringVar(&f.configPath, "config", "/config/pgedge.yaml", "path to the node configuration rendered by the chart")
+	fs.StringVar(&f.certPath, "cert", "/certificates/admin/tls.crt", "admin client certificate")
+	fs.StringVar(&f.keyPath, "key", "/certificates/admin/tls.key", "admin client certificate key")
+	fs.Var(f.endpoints, "endpoint", "connect to a node at `node=host:port` instead of its configured hostname, e.g. through a port-forward (repeatable)")
+	return fs, f
+}
+
+// parse parses args and rejects positional arguments.
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	return nil
+}
+
+// Output formats of the commands that print a report.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// reportFlags are the options of the commands that print a report.
+type reportFlags struct {
+	format  string
+	timeout time.Duration
+}
+
+func addReportFlags(fs *flag.FlagSet) *reportFlags {
+	r := &reportFlags{}
+	fs.StringVar(&r.format, "o", formatTable, "output format: table or json")
+	fs.DurationVar(&r.timeout, "timeout", 30*time.Second, "time allowed for querying all nodes")
+	return r
+}
+
+func (r *reportFlags) validate() error {
+	if r.format != formatTable && r.format != formatJSON {
+		return fmt.Errorf("invalid output format %q", r.format)
+	}
+	return nil
+}
+
+// parseReportFlags parses the arguments of a command that prints a report.
+func parseReportFlags(name string, args []string) (*flags, *reportFlags, error) {
+	fs, f := newFlagSet(name)
+	r := addReportFlags(fs)
+	if err := parse(fs, args); err != nil {
+		return nil, nil, err
+	}
+	if err := r.validate(); err != nil {
+		return nil, nil, err
+	}
+	return f, r, nil
+}
+
+// parseFlags parses the arguments of the run command.
+func parseFlags(args []string) (*flags, error) {
+	fs, f := newFlagSet("run")
+	fs.StringVar(&f.kubeconfig, "kubeconfig", "", "kubeconfig to use instead of the in-cluster service account")
+	fs.StringVar(&f.kubeContext, "context", "", "kubeconfig context to use")
+	fs.BoolVar(&f.skipWait, "skip-wait", false, "do not wait for CNPG Clusters to become Ready; run without Kubernetes access if none is configured")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,39 @@
+// cmd/init-spock/flags_test.go
+package main
+
+import "testing"
+
+func TestParseFlagsDefaults(t *testing.T) {
+	f, err := parseFlags(nil)
+	if err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	if f.configPath != "/config/pgedge.yaml" || f.certPath != "/certificates/admin/tls.crt" || f.keyPath != "/certificates/admin/tls.key" {
+		t.Errorf("expected in-pod defaults, got %+v", f)
+	}
+	if f.skipWait || f.kubeconfig != "" || len(f.endpoints) != 0 {
+		t.Errorf("expected no overrides by default, got %+v", f)
+	}
+}
+
+func TestParseFlagsEndpoints(t *testing.T) {
+	f, err := parseFlags([]string{
+		"-kubeconfig", "/tmp/kubeconfig", "-context", "east",
+		"-endpoint", "n1=localhost:15432", "-endpoint", "n2=localhost:15433",
+	})
+	if err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	if f.kubeconfig != "/tmp/kubeconfig" || f.kubeContext != "east" {
+		t.Errorf("unexpected kube flags: %+v", f)
+	}
+	if f.endpoints["n1"] != "localhost:15432" || f.endpoints["n2"] != "localhost:15433" {
+		t.Errorf("unexpected endpoints: %v", f.endpoints)
+	}
+}
+
+func TestParseFlagsRejectsBadEndpoint(t *testing.T) {
+	if _, err := parseFlags([]string{"-endpoint", "localhost:15432"}); err == nil {
+		t.Error("expected error for endpoint without node name")
+	}
+}
@@ -0,0 +1,72 @@
+// cmd/init-spock/graph.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/pgEdge/pgedge-helm/internal/resource"
+	"github.com/pgEdge/pgedge-helm/internal/spock"
+)
+
+// graphCommand prints the resource dependency graph the configuration
+// produces, grouped by plan phase. With -refresh it inspects the nodes,
+// colors resources by state and plans against what exists; otherwise it
+// plans as for an empty mesh and needs no database access.
+func graphCommand(ctx context.Context, args []string) int {
+	logToStderr()
+
+	fs, f := newFlagSet("graph")
+	format := fs.String("format", resource.GraphDOT, "output format: dot or mermaid")
+	refresh := fs.Bool("refresh", false, "inspect the nodes to color resources by state and plan against them")
+	timeout := fs.Duration("timeout", 30*time.Second, "time allowed for inspecting all nodes with -refresh")
+	if err := parse(fs, args); err != nil {
+		return flagExitCode(err)
+	}
+	if *format != resource.GraphDOT && *format != resource.GraphMermaid {
+		return flagExitCode(fmt.Errorf("invalid graph format %q", *format))
+	}
+
+	cfg, pgOpts, err := loadConfig(f)
+	if err != nil {
+		slog.Error("load configuration", "error", err)
+		return 1
+	}
+
+	// Without -refresh no pools are needed: ComputeDesired only records
+	// them in the resources, and a nil actual map plans for an empty mesh.
+	var conns map[string]*pgxpool.Pool
+	if *refresh {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+		conns = connectPools(ctx, cfg, pgOpts)
+		defer closePools(conns)
+	}
+
+	dbs := spock.PoolDBs(conns)
+	desired := spock.ComputeDesired(cfg, dbs, nil)
+	var actual map[resource.Identifier]resource.Resource
+	if *refresh {
+		if actual, err = spock.RefreshActual(ctx, cfg, dbs, desired); err != nil {
+			slog.Error("refresh", "error", err)
+			return 1
+		}
+	}
+
+	plan, err := resource.Plan(actual, desired)
+	if err != nil {
+		slog.Error("plan", "error", err)
+		return 1
+	}
+	if err := resource.WriteGraph(os.Stdout, *format, desired, actual, plan); err != nil {
+		slog.Error("write graph", "error", err)
+		return 1
+	}
+	return 0
+}
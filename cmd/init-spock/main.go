@@ -3,41 +3,211 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"sort"
 	"strconv"
+	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/pgEdge/pgedge-helm/internal/cluster"
 	"github.com/pgEdge/pgedge-helm/internal/config"
+	"github.com/pgEdge/pgedge-helm/internal/doctor"
+	"github.com/pgEdge/pgedge-helm/internal/kube"
+	"github.com/pgEdge/pgedge-helm/internal/lock"
 	"github.com/pgEdge/pgedge-helm/internal/pg"
 	"github.com/pgEdge/pgedge-helm/internal/resource"
 	"github.com/pgEdge/pgedge-helm/internal/spock"
+	"github.com/pgEdge/pgedge-helm/internal/status"
 )
 
-func main() {
-	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo})))
+// Retry budget for resources interrupted by a failover: 2s doubling up to
+// 30s between attempts, roughly four minutes in total.
+const (
+	retryAttempts = 10
+	retryBackoff  = 2 * time.Second
+)
+
+// logLevel is the level of the run command's logger. Tracing SQL at debug
+// level lowers it to debug.
+var logLevel = new(slog.LevelVar)
+
+// exitDegraded is the exit code of a run that reconciled only the nodes
+// that were ready, in degraded mode.
+const exitDegraded = 3
+
+// degradedError reports the work a degraded run skipped.
+type degradedError struct {
+	skipped []string
+}
+
+func (e *degradedError) Error() string {
+	return "reconciled the ready nodes only, skipped: " + strings.Join(e.skipped, "; ")
+}
+
+// publishTimeout bounds reporting the run's outcome, which happens after
+// the run's own context may already have expired.
+const publishTimeout = 10 * time.Second
+
+// commands are the init-spock subcommands; each returns the process exit
+// code. Without a command name, init-spock runs "run" as the Job does.
+var commands = map[string]struct {
+	summary string
+	run     func(ctx context.Context, args []string) int
+}{
+	"run":        {"reconcile Spock on every node with the configuration (default)", runCommand},
+	"status":     {"print the replication health of every node pair", statusCommand},
+	"doctor":     {"check every node for the Spock prerequisites", doctorCommand},
+	"graph":      {"print the resource dependency graph and plan as DOT or Mermaid", graphCommand},
+	"conflicts":  {"summarize the conflicts Spock resolved across the mesh", conflictsCommand},
+	"exceptions": {"list failed apply transactions, or skip or retry those of a subscription", exceptionsCommand},
+}
 
+func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
-	defer cancel()
 
-	if err := run(ctx); err != nil {
+	name, args := "run", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+	code := cmd.run(ctx, args)
+	cancel()
+	os.Exit(code)
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(os.Stderr, "Usage: init-spock [command] [flags]\n\nCommands:")
+	tw := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "  %s\t%s\n", name, commands[name].summary)
+	}
+	tw.Flush()
+}
+
+// flagExitCode is the exit code for a flag parsing error.
+func flagExitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	slog.Error("invalid arguments", "error", err)
+	return 2
+}
+
+func runCommand(ctx context.Context, args []string) int {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel})))
+
+	f, err := parseFlags(args)
+	if err != nil {
+		return flagExitCode(err)
+	}
+
+	if err := run(ctx, f); err != nil {
+		if errors.Is(err, lock.ErrHeld) {
+			slog.Warn("exiting without changes", "reason", err)
+			return 0
+		}
+		var degraded *degradedError
+		if errors.As(err, &degraded) {
+			slog.Warn("spock configuration partially updated", "skipped", degraded.skipped)
+			return exitDegraded
+		}
 		slog.Error("init-spock failed", "error", err)
-		os.Exit(1)
+		return 1
 	}
 	slog.Info("spock configuration successfully updated")
+	return 0
+}
+
+// loadConfig loads the configuration with the command-line overrides
+// applied and returns it with the options for connecting to its nodes.
+func loadConfig(f *flags) (*config.Config, pg.Options, error) {
+	cfg, err := config.Load(f.configPath)
+	if err != nil {
+		return nil, pg.Options{}, err
+	}
+	if err := cfg.OverrideEndpoints(f.endpoints); err != nil {
+		return nil, pg.Options{}, err
+	}
+	pgOpts := pg.Options{DBName: cfg.DBName, User: cfg.AdminUser, CertPath: f.certPath, KeyPath: f.keyPath}
+	switch cfg.SQLTrace {
+	case config.SQLTraceInfo:
+		pgOpts.Trace = pg.Trace{Enabled: true, Level: slog.LevelInfo, Args: cfg.SQLTraceArgs}
+	case config.SQLTraceDebug:
+		pgOpts.Trace = pg.Trace{Enabled: true, Level: slog.LevelDebug, Args: cfg.SQLTraceArgs}
+		logLevel.Set(slog.LevelDebug)
+	}
+	return cfg, pgOpts, nil
+}
+
+// connectPools creates a pool for every node without waiting for it.
+// Pools connect lazily, so an unreachable node surfaces as an error from
+// its first query; a node whose pool cannot be configured is left out.
+func connectPools(ctx context.Context, cfg *config.Config, pgOpts pg.Options) map[string]*pgxpool.Pool {
+	conns := make(map[string]*pgxpool.Pool)
+	for _, node := range cfg.Nodes {
+		pool, err := pg.ConnectPool(ctx, node.Hostname, node.InternalHostname, pgOpts.ForNode(node.Name))
+		if err != nil {
+			slog.Warn("connect", "node", node.Name, "error", err)
+			continue
+		}
+		conns[node.Name] = pool
+	}
+	return conns
+}
+
+func closePools(conns map[string]*pgxpool.Pool) {
+	for _, pool := range conns {
+		pool.Close()
+	}
 }
 
-func run(ctx context.Context) error {
-	cfg, err := config.Load("/config/pgedge.yaml")
+func run(ctx context.Context, f *flags) (err error) {
+	cfg, pgOpts, err := loadConfig(f)
 	if err != nil {
 		return err
 	}
 
+	// Kubernetes access is only optional when the CNPG wait is skipped,
+	// e.g. when running from a workstation against port-forwarded nodes.
+	clients, kubeErr := kube.NewClients(f.kubeconfig, f.kubeContext)
+	if kubeErr != nil {
+		if !f.skipWait || cfg.Lease {
+			return kubeErr
+		}
+		slog.Warn("running without Kubernetes access", "error", kubeErr)
+	}
+
+	conns := make(map[string]*pgxpool.Pool)
+	defer func() { closePools(conns) }()
+
+	opts := []resource.Option{resource.WithRetry(pg.IsRetryable, retryAttempts, retryBackoff)}
+	if clients != nil {
+		recorder := status.NewRecorder(cfg, clients.Kubernetes, clients.Dynamic)
+		opts = append(opts, resource.WithObserver(recorder))
+		// Deferred after the pool cleanup so it runs first and can still
+		// query subscription health.
+		defer func() { publishStatus(recorder, cfg, conns, err) }()
+	}
+
 	// Apply timeout if configured. Use 30s less than the Kubernetes
 	// activeDeadlineSeconds so the process can log errors gracefully
 	// before the kubelet sends SIGTERM.
@@ -54,43 +224,164 @@ func run(ctx context.Context) error {
 		}
 	}
 
+	// Losing the Lease or the mesh lock cancels ctx so the run stops rather
+	// than go on unserialized. The steps then fail with a canceled context;
+	// report why.
+	defer func() {
+		if cause := context.Cause(ctx); err != nil && errors.Is(cause, lock.ErrLost) {
+			err = cause
+		}
+	}()
+
+	identity := runIdentity(cfg)
+	wait := cfg.LockMode == config.LockModeWait
+	if cfg.Lease {
+		lease, err := lock.AcquireLease(ctx, clients.Kubernetes, cfg.Namespace, lock.LeaseName(cfg.AppName), identity, wait)
+		if err != nil {
+			return err
+		}
+		defer lease.Release(context.Background())
+		ctx = lease.Hold(ctx)
+	}
+
 	slog.Info("configuring spock", "nodes", len(cfg.Nodes))
 	for _, node := range cfg.Nodes {
 		slog.Info("node", "name", node.Name, "hostname", node.Hostname,
 			"bootstrap_mode", node.Bootstrap.Mode)
 	}
 
-	// Step 1: Wait for CNPG clusters
-	if err := cluster.WaitForAll(ctx, cfg.Namespace, cfg.AppName); err != nil {
+	// Step 1: Wait for CNPG clusters. In degraded mode, clusters that are not
+	// ready in time are left to the node readiness check.
+	if f.skipWait {
+		slog.Info("skipping wait for CNPG clusters")
+	} else if err := cluster.WaitForAll(ctx, clients.Dynamic, cfg); err != nil {
+		if !cfg.Degraded || ctx.Err() != nil {
+			return err
+		}
+		slog.Warn("continuing without all CNPG clusters ready in degraded mode", "error", err)
+	}
+
+	// Step 2: Wait for nodes and establish connection pools. A degraded run
+	// reconciles only the part of the mesh in runCfg.
+	notReady, err := waitForNodes(ctx, cfg, pgOpts, conns)
+	if err != nil {
 		return err
 	}
+	runCfg, skipped := cfg, []string(nil)
+	if len(notReady) > 0 {
+		runCfg, skipped = degrade(cfg, notReady)
+	}
 
-	// Step 2: Wait for nodes and establish connection pools
-	conns := make(map[string]*pgxpool.Pool)
-	for _, node := range cfg.Nodes {
-		if err := pg.WaitReady(ctx, node.Hostname, node.InternalHostname, cfg.DBName, cfg.AdminUser); err != nil {
-			return err
-		}
-		pool, err := pg.ConnectPool(ctx, node.Hostname, node.InternalHostname, cfg.DBName, cfg.AdminUser)
-		if err != nil {
-			return err
+	// Drop pooled connections to a former primary as soon as CNPG reports
+	// a failover; new connections go through the -rw service to the new one.
+	// The failover also ends the session holding the mesh lock there, so
+	// the lock is checked at once.
+	failovers := make(chan struct{}, 1)
+	if clients != nil {
+		cluster.WatchPrimaries(ctx, clients.Dynamic, cfg, func(nodeName, _ string) {
+			if pool, ok := conns[nodeName]; ok {
+				pool.Reset()
+			}
+			select {
+			case failovers <- struct{}{}:
+			default:
+			}
+		})
+	} else {
+		slog.Warn("not watching for CNPG failovers without Kubernetes access")
+	}
+
+	// Serialize with init-spock runs from other releases of the mesh, which
+	// may live in other Kubernetes clusters.
+	meshLock, err := lock.AcquireMesh(ctx, conns, identity, wait)
+	if err != nil {
+		return err
+	}
+	defer meshLock.Release(context.Background())
+
+	// Stop once a node's lock session ends.
+	ctx = meshLock.Hold(ctx, failovers)
+
+	// Finish resets interrupted after their repset snapshot was saved
+	// before anything inspects Spock on those nodes.
+	var mirror spock.SnapshotMirror
+	if cfg.SnapshotConfigMap {
+		if clients == nil {
+			return errors.New("REPSET_SNAPSHOT_CONFIGMAP requires Kubernetes access")
 		}
-		defer pool.Close()
-		conns[node.Name] = pool
+		mirror = kube.NewSnapshotConfigMap(clients.Kubernetes, cfg.Namespace, cfg.AppName)
+	}
+	dbs := spock.PoolDBs(conns)
+	if err := spock.RestorePendingSnapshots(ctx, runCfg, dbs, mirror); err != nil {
+		return err
 	}
 
-	// Step 3: Reset Spock state where needed
-	if cfg.ResetSpock {
+	// Step 3: Check the Spock prerequisites before changing anything. The
+	// capacity every node needs depends on the whole mesh, including the
+	// nodes a degraded run leaves out.
+	findings := doctor.Check(ctx, runCfg, conns, len(cfg.Nodes)-1)
+	doctor.Log(findings)
+	if err := doctor.Err(findings); err != nil {
+		return err
+	}
+
+	// Step 4: Reset Spock state where needed. Resets involve every node, so
+	// a degraded run skips them.
+	if len(skipped) > 0 {
+		slog.Warn("skipping resets in degraded mode")
+	} else if cfg.ResetSpock {
 		slog.Info("resetSpock enabled — dropping and recreating spock on all nodes")
-		if err := spock.ResetSpock(ctx, cfg, conns); err != nil {
+		if err := spock.ResetSpock(ctx, cfg, dbs, mirror); err != nil {
 			return err
 		}
 	} else {
-		if err := spock.ResetBootstrappedNodes(ctx, cfg, conns); err != nil {
+		if err := spock.ResetBootstrappedNodes(ctx, cfg, dbs, mirror); err != nil {
 			return err
 		}
+		if len(cfg.ResetNodes) > 0 {
+			slog.Info("resetSpock enabled — dropping and recreating spock on selected nodes", "nodes", cfg.ResetNodes)
+			if err := spock.ResetNodes(ctx, cfg, dbs, cfg.ResetNodes, mirror); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Step 5: Reconcile Spock resources
+	dump := func(ctx context.Context, node config.Node, filters []string) (string, error) {
+		return pg.DumpSchema(ctx, node.Hostname, node.InternalHostname, pgOpts, filters)
+	}
+	if err := resource.Reconcile(ctx, spock.NewReconciler(runCfg, dbs, dump), opts...); err != nil {
+		return err
+	}
+	if len(skipped) > 0 {
+		return &degradedError{skipped: skipped}
 	}
+	return nil
+}
 
-	// Step 4: Reconcile Spock resources
-	return resource.Reconcile(ctx, spock.NewReconciler(cfg, conns))
+// publishStatus reports the run's outcome and the subscription health as
+// Events and the status ConfigMap.
+func publishStatus(recorder *status.Recorder, cfg *config.Config, conns map[string]*pgxpool.Pool, runErr error) {
+	if errors.Is(runErr, lock.ErrHeld) {
+		// The run holding the lock reports its own outcome.
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	var subs []spock.SubscriptionHealth
+	if len(conns) > 0 {
+		subs = spock.CheckSubscriptions(ctx, cfg, spock.PoolDBs(conns))
+	}
+	recorder.Publish(ctx, runErr, subs)
+}
+
+// runIdentity names this run in lock holders, e.g.
+// "pgedge-init-spock/default/pgedge-init-spock-x7k2p".
+func runIdentity(cfg *config.Config) string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("pgedge-init-spock/%s/%s", cfg.Namespace, host)
 }
@@ -0,0 +1,116 @@
+// cmd/init-spock/readiness.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/pgEdge/pgedge-helm/internal/config"
+	"github.com/pgEdge/pgedge-helm/internal/pg"
+)
+
+// nodeReadiness is the outcome of waiting for one node: its state from
+// pg.ConnectState and the last error, if it is not ready.
+type nodeReadiness struct {
+	Node  string
+	State string
+	Err   error
+}
+
+// waitForNodes waits for every node to accept connections and creates its
+// pool, for all nodes at once within cfg.Waits.NodeReady. The pools of
+// ready nodes are added to conns even when other nodes are not ready, so
+// they are closed with the rest. It logs one report of every node's state
+// and fails if any node is not ready, unless cfg.Degraded is set and at
+// least one node is ready: then it returns the nodes that are not.
+func waitForNodes(ctx context.Context, cfg *config.Config, pgOpts pg.Options, conns map[string]*pgxpool.Pool) ([]nodeReadiness, error) {
+	wait := cfg.Waits.NodeReady
+	results := make([]nodeReadiness, len(cfg.Nodes))
+	pools := make([]*pgxpool.Pool, len(cfg.Nodes))
+	var wg sync.WaitGroup
+	for i, node := range cfg.Nodes {
+		wg.Go(func() {
+			err := pg.WaitReady(ctx, node.Hostname, node.InternalHostname, pgOpts, wait.Timeout, wait.Interval)
+			if err == nil {
+				pools[i], err = pg.ConnectPool(ctx, node.Hostname, node.InternalHostname, pgOpts.ForNode(node.Name))
+			}
+			results[i] = nodeReadiness{Node: node.Name, State: pg.ConnectState(err), Err: err}
+		})
+	}
+	wg.Wait()
+
+	for i, node := range cfg.Nodes {
+		if pools[i] != nil {
+			conns[node.Name] = pools[i]
+		}
+	}
+	report := readinessReport(results)
+	var notReady []nodeReadiness
+	for _, r := range results {
+		if r.State != pg.StateReady {
+			notReady = append(notReady, r)
+		}
+	}
+	switch {
+	case len(notReady) == 0:
+		slog.Info("nodes ready", "report", report)
+		return nil, nil
+	case cfg.Degraded && len(notReady) < len(results) && ctx.Err() == nil:
+		slog.Warn("continuing with the ready nodes in degraded mode", "report", report)
+		return notReady, nil
+	default:
+		slog.Error("nodes not ready", "report", report)
+		return nil, fmt.Errorf("nodes not ready: %s", report)
+	}
+}
+
+// degrade returns the configuration of the part of the mesh a degraded run
+// reconciles, without the nodes that are not ready, and describes the work
+// it skips. Nodes being added are left out as well: adding a node involves
+// every existing node.
+func degrade(cfg *config.Config, notReady []nodeReadiness) (*config.Config, []string) {
+	var excluded, skipped []string
+	for _, r := range notReady {
+		excluded = append(excluded, r.Node)
+		skipped = append(skipped, fmt.Sprintf("subscriptions to and from %s (%s)", r.Node, r.State))
+	}
+	for _, node := range cfg.Nodes {
+		if node.Bootstrap.Mode != "" && !slices.Contains(excluded, node.Name) {
+			excluded = append(excluded, node.Name)
+			skipped = append(skipped, fmt.Sprintf("adding node %s", node.Name))
+		}
+	}
+	if cfg.ResetSpock || len(cfg.ResetNodes) > 0 {
+		skipped = append(skipped, "resetSpock")
+	}
+	return cfg.WithoutNodes(excluded), skipped
+}
+
+// readinessReport summarizes node states, ready nodes first, e.g.
+// "ready: n1, n2; unreachable: n3 (dial tcp: lookup pgedge-n3-rw: no such host)".
+func readinessReport(results []nodeReadiness) string {
+	var parts []string
+	for _, state := range []string{pg.StateReady, pg.StateUnreachable, pg.StateTLS, pg.StateAuth} {
+		var nodes []string
+		for _, r := range results {
+			if r.State != state {
+				continue
+			}
+			if r.Err != nil {
+				nodes = append(nodes, fmt.Sprintf("%s (%v)", r.Node, r.Err))
+			} else {
+				nodes = append(nodes, r.Node)
+			}
+		}
+		if len(nodes) > 0 {
+			parts = append(parts, state+": "+strings.Join(nodes, ", "))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
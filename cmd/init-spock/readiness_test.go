@@ -0,0 +1,52 @@
+// cmd/init-spock/readiness_test.go
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pgEdge/pgedge-helm/internal/config"
+	"github.com/pgEdge/pgedge-helm/internal/pg"
+)
+
+func TestReadinessReport(t *testing.T) {
+	report := readinessReport([]nodeReadiness{
+		{Node: "n1", State: pg.StateReady},
+		{Node: "n2", State: pg.StateAuth, Err: errors.New("role \"admin\" does not exist")},
+		{Node: "n3", State: pg.StateUnreachable, Err: errors.New("no such host")},
+		{Node: "n4", State: pg.StateReady},
+	})
+	want := `ready: n1, n4; unreachable: n3 (no such host); auth: n2 (role "admin" does not exist)`
+	if report != want {
+		t.Errorf("got %q\nwant %q", report, want)
+	}
+}
+
+func TestDegrade(t *testing.T) {
+	cfg := &config.Config{
+		ResetNodes: []string{"n2"},
+		Nodes: []config.Node{
+			{Name: "n1"},
+			{Name: "n2"},
+			{Name: "n3"},
+			{Name: "n4", Bootstrap: config.NodeBootstrap{Mode: "spock", SourceNode: "n1"}},
+		},
+	}
+	runCfg, skipped := degrade(cfg, []nodeReadiness{{Node: "n3", State: pg.StateUnreachable}})
+
+	var names []string
+	for _, n := range runCfg.Nodes {
+		names = append(names, n.Name)
+	}
+	if strings.Join(names, ",") != "n1,n2" {
+		t.Errorf("expected to reconcile n1 and n2, got %v", names)
+	}
+	if strings.Join(runCfg.Excluded, ",") != "n3,n4" {
+		t.Errorf("expected n3 and n4 excluded, got %v", runCfg.Excluded)
+	}
+	want := "subscriptions to and from n3 (unreachable); adding node n4; resetSpock"
+	if got := strings.Join(skipped, "; "); got != want {
+		t.Errorf("skipped: got %q\nwant %q", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+// cmd/init-spock/status.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pgEdge/pgedge-helm/internal/spock"
+)
+
+// statusCommand prints the health of every subscription in the mesh and
+// exits 1 if any is unhealthy.
+func statusCommand(ctx context.Context, args []string) int {
+	logToStderr()
+
+	f, report, err := parseReportFlags("status", args)
+	if err != nil {
+		return flagExitCode(err)
+	}
+
+	cfg, pgOpts, err := loadConfig(f)
+	if err != nil {
+		slog.Error("load configuration", "error", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, report.timeout)
+	defer cancel()
+
+	conns := connectPools(ctx, cfg, pgOpts)
+	defer closePools(conns)
+
+	health := spock.CheckSubscriptions(ctx, cfg, spock.PoolDBs(conns))
+	if report.format == formatJSON {
+		err = printStatusJSON(os.Stdout, health)
+	} else {
+		err = printStatusTable(os.Stdout, health)
+	}
+	if err != nil {
+		slog.Error("write status", "error", err)
+		return 1
+	}
+	for _, h := range health {
+		if !h.Healthy() {
+			return 1
+		}
+	}
+	return 0
+}
+
+func printStatusJSON(w io.Writer, health []spock.SubscriptionHealth) error {
+	if health == nil {
+		health = []spock.SubscriptionHealth{}
+	}
+	return printJSON(w, health)
+}
+
+// logToStderr keeps stdout for a command's report.
+func logToStderr() {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+}
+
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func printStatusTable(w io.Writer, health []spock.SubscriptionHealth) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROVIDER\tSUBSCRIBER\tSTATUS\tSLOT\tLAG BYTES\tCOMMIT LAG\tHEALTHY\tERROR")
+	for _, h := range health {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%t\t%s\n",
+			h.Provider, h.Subscriber, orDash(h.Status), slotColumn(h),
+			formatBytes(h.LagBytes), formatLag(h.CommitLagMs), h.Healthy(), orDash(h.Error))
+	}
+	return tw.Flush()
+}
+
+func slotColumn(h spock.SubscriptionHealth) string {
+	switch {
+	case !h.SlotExists:
+		return "missing"
+	case h.SlotActive:
+		return "active"
+	default:
+		return "inactive"
+	}
+}
+
+func formatBytes(n *int64) string {
+	if n == nil {
+		return "-"
+	}
+	return strconv.FormatInt(*n, 10)
+}
+
+func formatLag(ms *int64) string {
+	if ms == nil {
+		return "-"
+	}
+	return (time.Duration(*ms) * time.Millisecond).String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
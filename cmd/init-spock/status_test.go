@@ -0,0 +1,64 @@
+// cmd/init-spock/status_test.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pgEdge/pgedge-helm/internal/spock"
+)
+
+func statusFixture() []spock.SubscriptionHealth {
+	lag, commitLag := int64(4096), int64(1500)
+	return []spock.SubscriptionHealth{
+		{Name: "sub_n1_n2", Provider: "n1", Subscriber: "n2", Status: "replicating",
+			Slot: "spk_app_n1_sub_n1_n2", SlotExists: true, SlotActive: true, LagBytes: &lag, CommitLagMs: &commitLag},
+		{Name: "sub_n2_n1", Provider: "n2", Subscriber: "n1", Error: "subscription not found",
+			Slot: "spk_app_n2_sub_n2_n1"},
+	}
+}
+
+func TestPrintStatusTable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printStatusTable(&buf, statusFixture()); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and two rows, got:\n%s", buf.String())
+	}
+	for _, want := range []string{"n1", "n2", "replicating", "active", "4096", "1.5s", "true"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("healthy row missing %q: %s", want, lines[1])
+		}
+	}
+	for _, want := range []string{"missing", "false", "subscription not found"} {
+		if !strings.Contains(lines[2], want) {
+			t.Errorf("unhealthy row missing %q: %s", want, lines[2])
+		}
+	}
+}
+
+func TestPrintStatusJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printStatusJSON(&buf, statusFixture()); err != nil {
+		t.Fatal(err)
+	}
+	var got []spock.SubscriptionHealth
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("parse output: %v", err)
+	}
+	if len(got) != 2 || *got[0].LagBytes != 4096 || got[1].Error != "subscription not found" {
+		t.Errorf("unexpected round trip: %+v", got)
+	}
+
+	buf.Reset()
+	if err := printStatusJSON(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("expected empty array, got %s", buf.String())
+	}
+}
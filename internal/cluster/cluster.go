@@ -6,13 +6,17 @@ import (
 	"fmt"
 	"log/slog"
 	"sort"
+	"strings"
 	"time"
 
+	"golang.org/x/sync/errgroup"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/rest"
+
+	"github.com/pgEdge/pgedge-helm/internal/config"
 )
 
 var cnpgGVR = schema.GroupVersionResource{
@@ -21,12 +25,39 @@ var cnpgGVR = schema.GroupVersionResource{
 	Resource: "clusters",
 }
 
-const healthyPhase = "Cluster in healthy state"
+const (
+	// readyCondition is the CNPG Cluster condition reporting overall readiness.
+	readyCondition = "Ready"
+
+	// resyncInterval bounds how long a single watch is trusted before the
+	// cluster list is re-read, guarding against silently stalled watches.
+	resyncInterval = time.Minute
+
+	// retryInterval is the pause after a failed list or watch call when
+	// watching primaries; waits use config.Waits.Clusters.Interval.
+	retryInterval = 5 * time.Second
+)
+
+// ClusterName returns the CNPG Cluster name the chart creates for a node.
+func ClusterName(appName, nodeName string) string {
+	return fmt.Sprintf("%s-%s", appName, nodeName)
+}
+
+// expectedClusters returns the sorted CNPG Cluster names for the nodes
+// managed by this release. External nodes are skipped.
+func expectedClusters(cfg *config.Config) []string {
+	var names []string
+	for _, node := range cfg.LocalNodes() {
+		names = append(names, ClusterName(cfg.AppName, node.Name))
+	}
+	sort.Strings(names)
+	return names
+}
 
 // getClusters returns sorted CNPG cluster names matching the app label.
 func getClusters(ctx context.Context, client dynamic.Interface, namespace, appName string) ([]string, error) {
 	list, err := client.Resource(cnpgGVR).Namespace(namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("pgedge.com/app-name=%s", appName),
+		LabelSelector: appSelector(appName),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("list CNPG clusters: %w", err)
@@ -40,48 +71,266 @@ func getClusters(ctx context.Context, client dynamic.Interface, namespace, appNa
 	return names, nil
 }
 
-// waitForAll polls until all CNPG clusters with the app label are healthy.
-func waitForAll(ctx context.Context, client dynamic.Interface, namespace, appName string) error {
+func appSelector(appName string) string {
+	return fmt.Sprintf("pgedge.com/app-name=%s", appName)
+}
+
+// clusterReadiness reports whether a CNPG Cluster has a Ready=True
+// condition and, when it does not, a human-readable reason.
+func clusterReadiness(obj *unstructured.Unstructured) (bool, string) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["type"] != readyCondition {
+			continue
+		}
+		if cond["status"] == "True" {
+			return true, ""
+		}
+		reason, _ := cond["reason"].(string)
+		message, _ := cond["message"].(string)
+		switch {
+		case reason != "" && message != "":
+			return false, fmt.Sprintf("%s: %s", reason, message)
+		case message != "":
+			return false, message
+		case reason != "":
+			return false, reason
+		}
+		return false, "Ready condition is " + fmt.Sprint(cond["status"])
+	}
+
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	if phase == "" {
+		return false, "no Ready condition reported yet"
+	}
+	return false, fmt.Sprintf("no Ready condition reported yet (phase %q)", phase)
+}
+
+// readinessReport compares the expected cluster set with what exists.
+type readinessReport struct {
+	missing    []string
+	unhealthy  []string // "name: reason"
+	unexpected []string
+}
+
+func (r readinessReport) ready() bool {
+	return len(r.missing) == 0 && len(r.unhealthy) == 0
+}
+
+func (r readinessReport) String() string {
+	var parts []string
+	if len(r.missing) > 0 {
+		parts = append(parts, "missing: "+strings.Join(r.missing, ", "))
+	}
+	if len(r.unhealthy) > 0 {
+		parts = append(parts, "unhealthy: "+strings.Join(r.unhealthy, "; "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func evaluate(expected []string, observed map[string]*unstructured.Unstructured) readinessReport {
+	var report readinessReport
+	want := make(map[string]bool, len(expected))
+	for _, name := range expected {
+		want[name] = true
+		obj, ok := observed[name]
+		if !ok {
+			report.missing = append(report.missing, name)
+			continue
+		}
+		if ready, reason := clusterReadiness(obj); !ready {
+			report.unhealthy = append(report.unhealthy, fmt.Sprintf("%s: %s", name, reason))
+		}
+	}
+	for name := range observed {
+		if !want[name] {
+			report.unexpected = append(report.unexpected, name)
+		}
+	}
+	sort.Strings(report.unexpected)
+	return report
+}
+
+// waiter tracks observed clusters across list/watch cycles and logs only
+// when the readiness picture changes.
+type waiter struct {
+	namespace  string
+	expected   []string
+	observed   map[string]*unstructured.Unstructured
+	lastLogged string
+	warned     map[string]bool
+	last       readinessReport
+}
+
+// check evaluates the current state and returns true once every expected
+// cluster is Ready.
+func (w *waiter) check() bool {
+	report := evaluate(w.expected, w.observed)
+	w.last = report
+
+	for _, name := range report.unexpected {
+		if !w.warned[name] {
+			w.warned[name] = true
+			slog.Warn("ignoring CNPG cluster not present in config",
+				"name", name, "namespace", w.namespace)
+		}
+	}
+
+	if report.ready() {
+		slog.Info("all CNPG clusters ready", "count", len(w.expected))
+		return true
+	}
+	if summary := report.String(); summary != w.lastLogged {
+		w.lastLogged = summary
+		slog.Info("waiting for CNPG clusters", "namespace", w.namespace,
+			"missing", report.missing, "unhealthy", report.unhealthy)
+	}
+	return false
+}
+
+// listWatcher receives the state of the watched CNPG Clusters. reset is
+// called with every list result; apply with each subsequent watch event.
+// Either returns true to stop.
+type listWatcher interface {
+	reset(items []unstructured.Unstructured) bool
+	apply(eventType watch.EventType, obj *unstructured.Unstructured) bool
+}
+
+// listAndWatch lists the CNPG Clusters matching opts, then follows a watch
+// from the list's resource version, re-listing whenever the watch ends or
+// resyncInterval elapses, and pausing for retry after a failed call. It
+// returns nil once lw asks to stop, or ctx.Err().
+func listAndWatch(ctx context.Context, res dynamic.ResourceInterface, opts metav1.ListOptions, lw listWatcher, retry time.Duration) error {
 	for {
-		list, err := client.Resource(cnpgGVR).Namespace(namespace).List(ctx, metav1.ListOptions{
-			LabelSelector: fmt.Sprintf("pgedge.com/app-name=%s", appName),
-		})
+		list, err := res.List(ctx, opts)
 		if err != nil {
 			slog.Warn("failed to list clusters", "error", err)
-		} else if len(list.Items) == 0 {
-			slog.Info("no CNPG clusters found yet", "namespace", namespace, "appName", appName)
 		} else {
-			allHealthy := true
-			for _, item := range list.Items {
-				phase, _, _ := unstructured.NestedString(item.Object, "status", "phase")
-				if phase != healthyPhase {
-					slog.Info("waiting for cluster", "name", item.GetName(), "phase", phase)
-					allHealthy = false
-				}
+			if lw.reset(list.Items) {
+				return nil
 			}
-			if allHealthy {
-				slog.Info("all CNPG clusters healthy", "count", len(list.Items))
+			watchOpts := opts
+			watchOpts.ResourceVersion = list.GetResourceVersion()
+			done, err := follow(ctx, res, watchOpts, lw)
+			if done {
 				return nil
 			}
+			if err != nil {
+				slog.Warn("cluster watch ended", "error", err)
+			}
 		}
 
 		select {
 		case <-ctx.Done():
-			return fmt.Errorf("timed out waiting for CNPG clusters: %w", ctx.Err())
-		case <-time.After(5 * time.Second):
+			return ctx.Err()
+		case <-time.After(retry):
 		}
 	}
 }
 
-// WaitForAll creates an in-cluster K8s client and waits for all CNPG clusters.
-func WaitForAll(ctx context.Context, namespace, appName string) error {
-	cfg, err := rest.InClusterConfig()
+// follow consumes watch events until lw asks to stop, the watch closes, or
+// resyncInterval elapses. It returns true when lw asked to stop.
+func follow(ctx context.Context, res dynamic.ResourceInterface, opts metav1.ListOptions, lw listWatcher) (bool, error) {
+	watcher, err := res.Watch(ctx, opts)
 	if err != nil {
-		return fmt.Errorf("k8s in-cluster config: %w", err)
+		return false, fmt.Errorf("watch CNPG clusters: %w", err)
 	}
-	client, err := dynamic.NewForConfig(cfg)
-	if err != nil {
-		return fmt.Errorf("k8s dynamic client: %w", err)
+	defer watcher.Stop()
+
+	resync := time.NewTimer(resyncInterval)
+	defer resync.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false, nil
+		case <-resync.C:
+			return false, nil
+		case ev, ok := <-watcher.ResultChan():
+			if !ok {
+				return false, nil
+			}
+			switch ev.Type {
+			case watch.Added, watch.Modified, watch.Deleted:
+				obj, ok := ev.Object.(*unstructured.Unstructured)
+				if ok && lw.apply(ev.Type, obj) {
+					return true, nil
+				}
+			case watch.Error:
+				return false, fmt.Errorf("watch error: %v", ev.Object)
+			}
+		}
+	}
+}
+
+func (w *waiter) reset(items []unstructured.Unstructured) bool {
+	w.observed = make(map[string]*unstructured.Unstructured, len(items))
+	for i := range items {
+		w.observed[items[i].GetName()] = &items[i]
+	}
+	return w.check()
+}
+
+func (w *waiter) apply(eventType watch.EventType, obj *unstructured.Unstructured) bool {
+	if eventType == watch.Deleted {
+		delete(w.observed, obj.GetName())
+	} else {
+		w.observed[obj.GetName()] = obj
+	}
+	return w.check()
+}
+
+// waitForAll waits until every expected CNPG Cluster exists and reports
+// Ready=True.
+func waitForAll(ctx context.Context, client dynamic.Interface, namespace, appName string, expected []string, retry time.Duration) error {
+	if len(expected) == 0 {
+		slog.Info("no locally managed CNPG clusters to wait for")
+		return nil
+	}
+	return waitFor(ctx, client, namespace, metav1.ListOptions{LabelSelector: appSelector(appName)}, expected, retry)
+}
+
+// waitFor waits until every expected CNPG Cluster among those matching
+// opts exists and reports Ready=True.
+func waitFor(ctx context.Context, client dynamic.Interface, namespace string, opts metav1.ListOptions, expected []string, retry time.Duration) error {
+	w := &waiter{namespace: namespace, expected: expected, warned: map[string]bool{}}
+	res := client.Resource(cnpgGVR).Namespace(namespace)
+	if err := listAndWatch(ctx, res, opts, w, retry); err != nil {
+		return w.timeoutError(err)
+	}
+	return nil
+}
+
+func (w *waiter) timeoutError(err error) error {
+	if summary := w.last.String(); summary != "" {
+		return fmt.Errorf("timed out waiting for CNPG clusters (%s): %w", summary, err)
+	}
+	return fmt.Errorf("timed out waiting for CNPG clusters: %w", err)
+}
+
+// WaitForAll waits for the CNPG Clusters of every locally managed node in
+// cfg to become Ready, and concurrently for those of external nodes that
+// reference a remote Kubernetes cluster.
+//
+// The wait is bounded by cfg.Waits.Clusters.Timeout, if set.
+func WaitForAll(ctx context.Context, client dynamic.Interface, cfg *config.Config) error {
+	wait := cfg.Waits.Clusters
+	waitCtx, cancel := wait.Context(ctx)
+	defer cancel()
+
+	g, gctx := errgroup.WithContext(waitCtx)
+	g.Go(func() error {
+		return waitForAll(gctx, client, cfg.Namespace, cfg.AppName, expectedClusters(cfg), wait.Interval)
+	})
+	for _, remote := range remoteClusters(cfg) {
+		g.Go(func() error {
+			return waitForRemote(gctx, remote, newRemoteClient, wait.Interval)
+		})
+	}
+	err := g.Wait()
+	if err != nil && ctx.Err() == nil && waitCtx.Err() != nil {
+		return fmt.Errorf("CNPG clusters not ready within %s: %w", wait.Timeout, err)
 	}
-	return waitForAll(ctx, client, namespace, appName)
+	return err
 }
@@ -3,13 +3,20 @@ package cluster
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/pgEdge/pgedge-helm/internal/config"
 )
 
 func newCluster(name, namespace, phase string) *unstructured.Unstructured {
@@ -31,10 +38,32 @@ func newCluster(name, namespace, phase string) *unstructured.Unstructured {
 	}
 }
 
-func TestGetClusters(t *testing.T) {
-	scheme := runtime.NewScheme()
-	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme,
+// withReady sets the CNPG Ready condition on a cluster object.
+func withReady(obj *unstructured.Unstructured, status, reason, message string) *unstructured.Unstructured {
+	_ = unstructured.SetNestedSlice(obj.Object, []interface{}{
+		map[string]interface{}{
+			"type":    "Ready",
+			"status":  status,
+			"reason":  reason,
+			"message": message,
+		},
+	}, "status", "conditions")
+	return obj
+}
+
+func newReadyCluster(name string) *unstructured.Unstructured {
+	return withReady(newCluster(name, "default", "Cluster in healthy state"), "True", "ClusterIsReady", "Cluster is Ready")
+}
+
+func newFakeClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
 		map[schema.GroupVersionResource]string{cnpgGVR: "ClusterList"},
+		objects...,
+	)
+}
+
+func TestGetClusters(t *testing.T) {
+	client := newFakeClient(
 		newCluster("pgedge-n2", "default", "Cluster in healthy state"),
 		newCluster("pgedge-n1", "default", "Cluster in healthy state"),
 	)
@@ -53,10 +82,7 @@ func TestGetClusters(t *testing.T) {
 }
 
 func TestGetClustersEmpty(t *testing.T) {
-	scheme := runtime.NewScheme()
-	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme,
-		map[schema.GroupVersionResource]string{cnpgGVR: "ClusterList"},
-	)
+	client := newFakeClient()
 
 	names, err := getClusters(context.Background(), client, "default", "pgedge")
 	if err != nil {
@@ -67,35 +93,270 @@ func TestGetClustersEmpty(t *testing.T) {
 	}
 }
 
+func TestExpectedClustersSkipsExternalNodes(t *testing.T) {
+	cfg := &config.Config{
+		AppName: "pgedge",
+		Nodes: []config.Node{
+			{Name: "n2"},
+			{Name: "n1"},
+			{Name: "n3", External: true},
+		},
+	}
+	got := expectedClusters(cfg)
+	if len(got) != 2 || got[0] != "pgedge-n1" || got[1] != "pgedge-n2" {
+		t.Errorf("expected [pgedge-n1 pgedge-n2], got %v", got)
+	}
+}
+
+func TestClusterReadiness(t *testing.T) {
+	ready, reason := clusterReadiness(newReadyCluster("pgedge-n1"))
+	if !ready || reason != "" {
+		t.Errorf("expected ready, got ready=%v reason=%q", ready, reason)
+	}
+
+	notReady := withReady(newCluster("pgedge-n1", "default", "Setting up primary"),
+		"False", "ClusterIsNotReady", "Cluster Is Not Ready")
+	ready, reason = clusterReadiness(notReady)
+	if ready {
+		t.Error("expected not ready for Ready=False")
+	}
+	if reason != "ClusterIsNotReady: Cluster Is Not Ready" {
+		t.Errorf("unexpected reason %q", reason)
+	}
+
+	// The phase string alone no longer counts as healthy.
+	ready, reason = clusterReadiness(newCluster("pgedge-n1", "default", "Cluster in healthy state"))
+	if ready {
+		t.Error("expected not ready without a Ready condition")
+	}
+	if !strings.Contains(reason, "Cluster in healthy state") {
+		t.Errorf("reason should mention phase, got %q", reason)
+	}
+}
+
 func TestWaitForAllHealthy(t *testing.T) {
-	scheme := runtime.NewScheme()
-	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme,
-		map[schema.GroupVersionResource]string{cnpgGVR: "ClusterList"},
-		newCluster("pgedge-n1", "default", "Cluster in healthy state"),
-		newCluster("pgedge-n2", "default", "Cluster in healthy state"),
-	)
+	client := newFakeClient(newReadyCluster("pgedge-n1"), newReadyCluster("pgedge-n2"))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err := waitForAll(ctx, client, "default", "pgedge")
+	err := waitForAll(ctx, client, "default", "pgedge", []string{"pgedge-n1", "pgedge-n2"}, retryInterval)
 	if err != nil {
 		t.Fatalf("waitForAll: %v", err)
 	}
 }
 
 func TestWaitForAllTimeout(t *testing.T) {
-	scheme := runtime.NewScheme()
-	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme,
-		map[schema.GroupVersionResource]string{cnpgGVR: "ClusterList"},
-		newCluster("pgedge-n1", "default", "Setting up primary"),
-	)
+	client := newFakeClient(withReady(newCluster("pgedge-n1", "default", "Setting up primary"),
+		"False", "ClusterIsNotReady", "Cluster Is Not Ready"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := waitForAll(ctx, client, "default", "pgedge", []string{"pgedge-n1"}, retryInterval)
+	if err == nil {
+		t.Fatal("expected timeout error for unhealthy cluster")
+	}
+	if !strings.Contains(err.Error(), "pgedge-n1: ClusterIsNotReady") {
+		t.Errorf("error should name the unhealthy cluster and reason, got %v", err)
+	}
+}
+
+func TestWaitForAllReportsMissingCluster(t *testing.T) {
+	client := newFakeClient(newReadyCluster("pgedge-n1"))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	defer cancel()
 
-	err := waitForAll(ctx, client, "default", "pgedge")
+	err := waitForAll(ctx, client, "default", "pgedge", []string{"pgedge-n1", "pgedge-n2"}, retryInterval)
+	if err == nil {
+		t.Fatal("expected timeout error for missing cluster")
+	}
+	if !strings.Contains(err.Error(), "missing: pgedge-n2") {
+		t.Errorf("error should name the missing cluster, got %v", err)
+	}
+}
+
+func TestWaitForAllIgnoresStaleCluster(t *testing.T) {
+	// A leftover cluster from a removed node must not block readiness.
+	stale := withReady(newCluster("pgedge-n9", "default", "Failed"), "False", "Failed", "gone")
+	client := newFakeClient(newReadyCluster("pgedge-n1"), stale)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := waitForAll(ctx, client, "default", "pgedge", []string{"pgedge-n1"}, retryInterval); err != nil {
+		t.Fatalf("waitForAll: %v", err)
+	}
+}
+
+func TestWaitForAllNoExpectedClusters(t *testing.T) {
+	client := newFakeClient()
+	if err := waitForAll(context.Background(), client, "default", "pgedge", nil, retryInterval); err != nil {
+		t.Fatalf("waitForAll: %v", err)
+	}
+}
+
+func TestWaitForAllFollowsWatch(t *testing.T) {
+	notReady := withReady(newCluster("pgedge-n1", "default", "Setting up primary"),
+		"False", "ClusterIsNotReady", "Cluster Is Not Ready")
+	client := newFakeClient(notReady)
+
+	watching := make(chan struct{})
+	client.PrependWatchReactor("clusters", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		// Register the watcher before signalling so the update below
+		// cannot race ahead of it.
+		w, err := client.Tracker().Watch(cnpgGVR, action.GetNamespace())
+		close(watching)
+		return true, w, err
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- waitForAll(ctx, client, "default", "pgedge", []string{"pgedge-n1"}, retryInterval)
+	}()
+
+	select {
+	case <-watching:
+	case <-ctx.Done():
+		t.Fatal("waitForAll never started watching")
+	}
+
+	_, err := client.Resource(cnpgGVR).Namespace("default").Update(ctx,
+		newReadyCluster("pgedge-n1"), metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("update cluster: %v", err)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("waitForAll: %v", err)
+	}
+}
+
+func withPrimary(obj *unstructured.Unstructured, primary string) *unstructured.Unstructured {
+	_ = unstructured.SetNestedField(obj.Object, primary, "status", "currentPrimary")
+	return obj
+}
+
+func TestWatchPrimariesReportsFailover(t *testing.T) {
+	client := newFakeClient(
+		withPrimary(newReadyCluster("pgedge-n1"), "pgedge-n1-1"),
+		withPrimary(newReadyCluster("pgedge-n2"), "pgedge-n2-1"),
+	)
+
+	watching := make(chan struct{})
+	client.PrependWatchReactor("clusters", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		w, err := client.Tracker().Watch(cnpgGVR, action.GetNamespace())
+		close(watching)
+		return true, w, err
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	type change struct{ node, primary string }
+	changes := make(chan change, 4)
+	cfg := &config.Config{
+		AppName:   "pgedge",
+		Namespace: "default",
+		Nodes:     []config.Node{{Name: "n1"}, {Name: "n2"}},
+	}
+	go watchPrimaries(ctx, client, cfg, func(node, primary string) {
+		changes <- change{node, primary}
+	})
+
+	select {
+	case <-watching:
+	case <-ctx.Done():
+		t.Fatal("watchPrimaries never started watching")
+	}
+
+	_, err := client.Resource(cnpgGVR).Namespace("default").Update(ctx,
+		withPrimary(newReadyCluster("pgedge-n2"), "pgedge-n2-2"), metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("update cluster: %v", err)
+	}
+
+	select {
+	case c := <-changes:
+		if c.node != "n2" || c.primary != "pgedge-n2-2" {
+			t.Errorf("expected n2 → pgedge-n2-2, got %+v", c)
+		}
+	case <-ctx.Done():
+		t.Fatal("no primary change reported")
+	}
+
+	select {
+	case c := <-changes:
+		t.Errorf("unexpected extra change %+v", c)
+	default:
+	}
+}
+
+func TestRemoteClustersDefaults(t *testing.T) {
+	cfg := &config.Config{
+		AppName:   "pgedge",
+		Namespace: "east",
+		Nodes: []config.Node{
+			{Name: "n1"},
+			{Name: "n2", External: true},
+			{Name: "n3", External: true, Remote: &config.RemoteCluster{Kubeconfig: "/kubeconfigs/n3/kubeconfig"}},
+			{Name: "n4", External: true, Remote: &config.RemoteCluster{
+				Kubeconfig: "/kubeconfigs/n4/kubeconfig", Context: "west", Namespace: "db", Name: "other-n4",
+			}},
+		},
+	}
+
+	remotes := remoteClusters(cfg)
+	if len(remotes) != 2 {
+		t.Fatalf("expected 2 remote clusters, got %+v", remotes)
+	}
+	if remotes[0].namespace != "east" || remotes[0].name != "pgedge-n3" {
+		t.Errorf("expected defaults east/pgedge-n3, got %+v", remotes[0])
+	}
+	if remotes[1].String() != "west:db/other-n4" {
+		t.Errorf("unexpected remote %s", remotes[1])
+	}
+}
+
+func TestWaitForRemote(t *testing.T) {
+	client := newFakeClient(newReadyCluster("pgedge-n3"))
+	var gotKubeconfig, gotContext string
+	newClient := func(kubeconfig, context string) (dynamic.Interface, error) {
+		gotKubeconfig, gotContext = kubeconfig, context
+		return client, nil
+	}
+	remote := remoteCluster{node: "n3", kubeconfig: "/kubeconfigs/n3/kubeconfig", context: "west",
+		namespace: "default", name: "pgedge-n3"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := waitForRemote(ctx, remote, newClient, retryInterval); err != nil {
+		t.Fatalf("waitForRemote: %v", err)
+	}
+	if gotKubeconfig != "/kubeconfigs/n3/kubeconfig" || gotContext != "west" {
+		t.Errorf("client built from %q/%q", gotKubeconfig, gotContext)
+	}
+}
+
+func TestWaitForRemoteReportsRemoteProblem(t *testing.T) {
+	client := newFakeClient(withReady(newCluster("pgedge-n3", "default", "Setting up primary"),
+		"False", "ClusterIsNotReady", "Cluster Is Not Ready"))
+	newClient := func(string, string) (dynamic.Interface, error) { return client, nil }
+	remote := remoteCluster{node: "n3", kubeconfig: "/kubeconfigs/n3/kubeconfig", namespace: "default", name: "pgedge-n3"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := waitForRemote(ctx, remote, newClient, retryInterval)
 	if err == nil {
-		t.Error("expected timeout error for unhealthy cluster")
+		t.Fatal("expected error for unhealthy remote cluster")
+	}
+	for _, want := range []string{"external node n3", "default/pgedge-n3", "ClusterIsNotReady"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error should mention %q, got %v", want, err)
+		}
 	}
 }
@@ -0,0 +1,89 @@
+// internal/cluster/primary.go
+package cluster
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/pgEdge/pgedge-helm/internal/config"
+)
+
+// PrimaryChangeFunc is called with the node name and new primary instance
+// when a CNPG Cluster reports a different status.currentPrimary.
+type PrimaryChangeFunc func(nodeName, primary string)
+
+// primaryTracker remembers the last seen currentPrimary per cluster and
+// reports changes. The first observation of a cluster is not a change.
+type primaryTracker struct {
+	nodes    map[string]string // cluster name → node name
+	primary  map[string]string // cluster name → currentPrimary
+	onChange PrimaryChangeFunc
+}
+
+func (p *primaryTracker) observe(obj *unstructured.Unstructured) {
+	node, ok := p.nodes[obj.GetName()]
+	if !ok {
+		return
+	}
+	current, _, _ := unstructured.NestedString(obj.Object, "status", "currentPrimary")
+	if current == "" {
+		return
+	}
+	previous, seen := p.primary[obj.GetName()]
+	p.primary[obj.GetName()] = current
+	if seen && previous != current {
+		slog.Warn("CNPG primary changed", "node", node, "cluster", obj.GetName(),
+			"previous", previous, "current", current)
+		p.onChange(node, current)
+	}
+}
+
+func (p *primaryTracker) reset(items []unstructured.Unstructured) bool {
+	for i := range items {
+		p.observe(&items[i])
+	}
+	return false
+}
+
+func (p *primaryTracker) apply(eventType watch.EventType, obj *unstructured.Unstructured) bool {
+	if eventType != watch.Deleted {
+		p.observe(obj)
+	}
+	return false
+}
+
+// watchPrimaries follows the locally managed CNPG Clusters and calls
+// onChange whenever one of them fails over or switches over. It runs
+// until ctx is done.
+func watchPrimaries(ctx context.Context, client dynamic.Interface, cfg *config.Config, onChange PrimaryChangeFunc) {
+	tracker := &primaryTracker{
+		nodes:    map[string]string{},
+		primary:  map[string]string{},
+		onChange: onChange,
+	}
+	for _, node := range cfg.LocalNodes() {
+		tracker.nodes[ClusterName(cfg.AppName, node.Name)] = node.Name
+	}
+	if len(tracker.nodes) == 0 {
+		return
+	}
+
+	res := client.Resource(cnpgGVR).Namespace(cfg.Namespace)
+	err := listAndWatch(ctx, res, metav1.ListOptions{LabelSelector: appSelector(cfg.AppName)}, tracker, retryInterval)
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("stopped watching CNPG primaries", "error", err)
+	}
+}
+
+// WatchPrimaries starts watching the CNPG Clusters of locally managed
+// nodes for primary changes in the background. Callers use onChange to
+// drop connections to a former primary.
+func WatchPrimaries(ctx context.Context, client dynamic.Interface, cfg *config.Config, onChange PrimaryChangeFunc) {
+	go watchPrimaries(ctx, client, cfg, onChange)
+}
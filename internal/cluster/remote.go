@@ -0,0 +1,86 @@
+// internal/cluster/remote.go
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/pgEdge/pgedge-helm/internal/config"
+	"github.com/pgEdge/pgedge-helm/internal/kube"
+)
+
+// remoteCluster is the CNPG Cluster of an external node in another
+// Kubernetes cluster.
+type remoteCluster struct {
+	node       string
+	kubeconfig string
+	context    string
+	namespace  string
+	name       string
+}
+
+func (r remoteCluster) String() string {
+	where := r.namespace + "/" + r.name
+	if r.context != "" {
+		where = r.context + ":" + where
+	}
+	return where
+}
+
+// remoteClusters returns the external nodes with a remote cluster
+// reference, with defaults applied: the release's namespace and the name
+// the chart would give the node's Cluster.
+func remoteClusters(cfg *config.Config) []remoteCluster {
+	var remotes []remoteCluster
+	for _, node := range cfg.Nodes {
+		if !node.External || node.Remote == nil || node.Remote.Kubeconfig == "" {
+			continue
+		}
+		r := remoteCluster{
+			node:       node.Name,
+			kubeconfig: node.Remote.Kubeconfig,
+			context:    node.Remote.Context,
+			namespace:  node.Remote.Namespace,
+			name:       node.Remote.Name,
+		}
+		if r.namespace == "" {
+			r.namespace = cfg.Namespace
+		}
+		if r.name == "" {
+			r.name = ClusterName(cfg.AppName, node.Name)
+		}
+		remotes = append(remotes, r)
+	}
+	return remotes
+}
+
+type remoteClientFunc func(kubeconfig, context string) (dynamic.Interface, error)
+
+func newRemoteClient(kubeconfig, context string) (dynamic.Interface, error) {
+	restCfg, err := kube.RestConfig(kubeconfig, context)
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewForConfig(restCfg)
+}
+
+// waitForRemote waits until an external node's CNPG Cluster in another
+// Kubernetes cluster reports Ready=True.
+func waitForRemote(ctx context.Context, r remoteCluster, newClient remoteClientFunc, retry time.Duration) error {
+	client, err := newClient(r.kubeconfig, r.context)
+	if err != nil {
+		return fmt.Errorf("remote cluster of external node %s: %w", r.node, err)
+	}
+	slog.Info("waiting for remote CNPG cluster", "node", r.node, "cluster", r.String())
+	opts := metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("metadata.name", r.name).String()}
+	if err := waitFor(ctx, client, r.namespace, opts, []string{r.name}, retry); err != nil {
+		return fmt.Errorf("remote cluster %s of external node %s: %w", r, r.node, err)
+	}
+	return nil
+}
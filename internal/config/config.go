@@ -3,7 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,16 +14,66 @@ import (
 type NodeBootstrap struct {
 	Mode       string `yaml:"mode"`
 	SourceNode string `yaml:"sourceNode"`
+	// Structure selects how the schema reaches the new node: StructureSpock
+	// (or empty) lets the subscription's synchronize_structure copy it,
+	// StructureDump applies a schema-only pg_dump of the source first.
+	Structure string `yaml:"structure"`
+	// SchemaFilter restricts the StructureDump copy, one entry per line in
+	// pg_dump filter syntax, e.g. "exclude schema audit".
+	SchemaFilter []string `yaml:"schemaFilter"`
 }
 
+// Bootstrap structure values.
+const (
+	StructureSpock = "spock"
+	StructureDump  = "dump"
+)
+
 // Node represents a pgEdge Spock node from the Helm config.
 type Node struct {
 	Name             string        `yaml:"name"`
 	Hostname         string        `yaml:"hostname"`
 	InternalHostname string        `yaml:"internalHostname"`
 	Bootstrap        NodeBootstrap `yaml:"bootstrap"`
+	// External is set by the chart for nodes listed under externalNodes.
+	// Their CNPG Clusters live outside this release and are not waited on
+	// unless Remote locates them.
+	External bool           `yaml:"external"`
+	Remote   *RemoteCluster `yaml:"remote"`
 }
 
+// RemoteCluster locates an external node's CNPG Cluster in another
+// Kubernetes cluster.
+type RemoteCluster struct {
+	// Kubeconfig is the path of the mounted kubeconfig for that cluster.
+	Kubeconfig string `yaml:"kubeconfig"`
+	Context    string `yaml:"context"`
+	Namespace  string `yaml:"namespace"`
+	// Name is the CNPG Cluster name; it defaults to <appName>-<node name>.
+	Name string `yaml:"name"`
+}
+
+// Lock modes: what a run does when another run holds the mesh lock.
+const (
+	LockModeWait = "wait"
+	LockModeExit = "exit"
+)
+
+// Data verification modes: what a run does when a newly added node's data
+// differs from its source node's.
+const (
+	VerifyDataOff  = "off"
+	VerifyDataWarn = "warn"
+	VerifyDataFail = "fail"
+)
+
+// SQL trace levels: the log level of SQL statement traces.
+const (
+	SQLTraceOff   = "off"
+	SQLTraceDebug = "debug"
+	SQLTraceInfo  = "info"
+)
+
 // Config holds all configuration for the init-spock job.
 type Config struct {
 	AppName    string
@@ -29,8 +81,77 @@ type Config struct {
 	Namespace  string
 	AdminUser  string
 	PgEdgeUser string
-	ResetSpock bool
-	Nodes      []Node
+	ResetSpock bool     // reset every node
+	ResetNodes []string // reset only these nodes when ResetSpock is false
+	LockMode   string   // LockModeWait or LockModeExit
+	Lease      bool     // also serialize runs with a coordination.k8s.io Lease
+	// SnapshotConfigMap also mirrors repset snapshots taken during a reset
+	// to a ConfigMap.
+	SnapshotConfigMap bool
+	// VerifyData compares newly added nodes with their source node after
+	// populate: VerifyDataOff, VerifyDataWarn or VerifyDataFail.
+	VerifyData string
+	// Degraded continues with the nodes that are ready when others are not
+	// ready within the node readiness wait.
+	Degraded bool
+	// SQLTrace logs every SQL statement with its duration: SQLTraceOff,
+	// SQLTraceDebug or SQLTraceInfo.
+	SQLTrace string
+	// SQLTraceArgs logs statement arguments in SQL traces instead of
+	// redacting them.
+	SQLTraceArgs bool
+	Waits        Waits
+	Nodes        []Node
+	// Excluded names the nodes left out of Nodes for this run, e.g. those
+	// that were not reachable in degraded mode. Their Spock nodes, slots
+	// and subscriptions are never treated as orphans.
+	Excluded []string
+}
+
+// LocalNodes returns the nodes whose CNPG Clusters are managed by this release.
+func (c *Config) LocalNodes() []Node {
+	var local []Node
+	for _, n := range c.Nodes {
+		if !n.External {
+			local = append(local, n)
+		}
+	}
+	return local
+}
+
+// WithoutNodes returns a copy of the configuration with the named nodes
+// moved from Nodes to Excluded.
+func (c *Config) WithoutNodes(names []string) *Config {
+	out := *c
+	out.Nodes = nil
+	out.Excluded = append([]string{}, c.Excluded...)
+	for _, n := range c.Nodes {
+		if slices.Contains(names, n.Name) {
+			out.Excluded = append(out.Excluded, n.Name)
+		} else {
+			out.Nodes = append(out.Nodes, n)
+		}
+	}
+	return &out
+}
+
+// OverrideEndpoints replaces the address init-spock connects to for the
+// named nodes, e.g. {"n1": "localhost:15432"} for a port-forward. The
+// hostname other nodes use in their Spock DSNs is left unchanged.
+func (c *Config) OverrideEndpoints(endpoints map[string]string) error {
+	for name, endpoint := range endpoints {
+		found := false
+		for i := range c.Nodes {
+			if c.Nodes[i].Name == name {
+				c.Nodes[i].InternalHostname = endpoint
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("endpoint override for unknown node %q", name)
+		}
+	}
+	return nil
 }
 
 // LoadNodes reads node definitions from a YAML file.
@@ -64,18 +185,90 @@ func Load(nodesPath string) (*Config, error) {
 	if adminUser == "" {
 		adminUser = "admin"
 	}
-	resetSpock, _ := strconv.ParseBool(os.Getenv("RESET_SPOCK"))
+	lockMode := os.Getenv("LOCK_MODE")
+	switch lockMode {
+	case "":
+		lockMode = LockModeWait
+	case LockModeWait, LockModeExit:
+	default:
+		return nil, fmt.Errorf("LOCK_MODE must be %q or %q, got %q", LockModeWait, LockModeExit, lockMode)
+	}
+	verifyData := os.Getenv("VERIFY_DATA")
+	switch verifyData {
+	case "":
+		verifyData = VerifyDataOff
+	case VerifyDataOff, VerifyDataWarn, VerifyDataFail:
+	default:
+		return nil, fmt.Errorf("VERIFY_DATA must be %q, %q or %q, got %q", VerifyDataOff, VerifyDataWarn, VerifyDataFail, verifyData)
+	}
+	sqlTrace := os.Getenv("SQL_TRACE")
+	switch sqlTrace {
+	case "":
+		sqlTrace = SQLTraceOff
+	case SQLTraceOff, SQLTraceDebug, SQLTraceInfo:
+	default:
+		return nil, fmt.Errorf("SQL_TRACE must be %q, %q or %q, got %q", SQLTraceOff, SQLTraceDebug, SQLTraceInfo, sqlTrace)
+	}
+	sqlTraceArgs, _ := strconv.ParseBool(os.Getenv("SQL_TRACE_ARGS"))
+	lease, _ := strconv.ParseBool(os.Getenv("LOCK_LEASE"))
+	snapshotConfigMap, _ := strconv.ParseBool(os.Getenv("REPSET_SNAPSHOT_CONFIGMAP"))
+	degraded, _ := strconv.ParseBool(os.Getenv("DEGRADED_MODE"))
+	waits, err := loadWaits()
+	if err != nil {
+		return nil, err
+	}
 	nodes, err := LoadNodes(nodesPath)
 	if err != nil {
 		return nil, err
 	}
+	resetSpock, resetNodes, err := parseResetSpock(os.Getenv("RESET_SPOCK"), nodes)
+	if err != nil {
+		return nil, err
+	}
 	return &Config{
-		AppName:    appName,
-		DBName:     dbName,
-		Namespace:  namespace,
-		AdminUser:  adminUser,
-		PgEdgeUser: "pgedge",
-		ResetSpock: resetSpock,
-		Nodes:      nodes,
+		AppName:           appName,
+		DBName:            dbName,
+		Namespace:         namespace,
+		AdminUser:         adminUser,
+		PgEdgeUser:        "pgedge",
+		ResetSpock:        resetSpock,
+		ResetNodes:        resetNodes,
+		LockMode:          lockMode,
+		Lease:             lease,
+		SnapshotConfigMap: snapshotConfigMap,
+		VerifyData:        verifyData,
+		Degraded:          degraded,
+		SQLTrace:          sqlTrace,
+		SQLTraceArgs:      sqlTraceArgs,
+		Waits:             waits,
+		Nodes:             nodes,
 	}, nil
 }
+
+// parseResetSpock interprets RESET_SPOCK: a boolean resets every node or
+// none, anything else is a comma-separated list of the nodes to reset.
+func parseResetSpock(value string, nodes []Node) (bool, []string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false, nil, nil
+	}
+	if all, err := strconv.ParseBool(value); err == nil {
+		return all, nil, nil
+	}
+	known := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		known[n.Name] = true
+	}
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			return false, nil, fmt.Errorf("RESET_SPOCK names unknown node %q", name)
+		}
+		names = append(names, name)
+	}
+	return false, names, nil
+}
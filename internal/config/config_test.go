@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoadNodes(t *testing.T) {
@@ -160,6 +161,194 @@ func TestLoadConfigResetSpockDefault(t *testing.T) {
 	}
 }
 
+func TestLoadConfigResetNodes(t *testing.T) {
+	path := writeTemp(t, "- name: n1\n  hostname: pgedge-n1-rw\n- name: n2\n  hostname: pgedge-n2-rw\n")
+	t.Setenv("APP_NAME", "pgedge")
+	t.Setenv("DB_NAME", "app")
+	t.Setenv("RESET_SPOCK", "n2, n1")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.ResetSpock {
+		t.Error("expected ResetSpock=false for a node list")
+	}
+	if len(cfg.ResetNodes) != 2 || cfg.ResetNodes[0] != "n2" || cfg.ResetNodes[1] != "n1" {
+		t.Errorf("expected ResetNodes=[n2 n1], got %v", cfg.ResetNodes)
+	}
+
+	t.Setenv("RESET_SPOCK", "n3")
+	if _, err := Load(path); err == nil {
+		t.Error("expected error for unknown node in RESET_SPOCK")
+	}
+}
+
+func TestLoadConfigLockMode(t *testing.T) {
+	path := writeTemp(t, "- name: n1\n  hostname: pgedge-n1-rw\n")
+	t.Setenv("APP_NAME", "pgedge")
+	t.Setenv("DB_NAME", "app")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.LockMode != LockModeWait || cfg.Lease {
+		t.Errorf("expected defaults LockMode=wait Lease=false, got %q %v", cfg.LockMode, cfg.Lease)
+	}
+
+	t.Setenv("LOCK_MODE", "exit")
+	t.Setenv("LOCK_LEASE", "true")
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.LockMode != LockModeExit || !cfg.Lease {
+		t.Errorf("expected LockMode=exit Lease=true, got %q %v", cfg.LockMode, cfg.Lease)
+	}
+
+	t.Setenv("LOCK_MODE", "skip")
+	if _, err := Load(path); err == nil {
+		t.Error("expected error for invalid LOCK_MODE")
+	}
+}
+
+func TestLoadConfigVerifyDataAndDegraded(t *testing.T) {
+	path := writeTemp(t, "- name: n1\n  hostname: pgedge-n1-rw\n")
+	t.Setenv("APP_NAME", "pgedge")
+	t.Setenv("DB_NAME", "app")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.VerifyData != VerifyDataOff {
+		t.Errorf("expected default VerifyData=off, got %q", cfg.VerifyData)
+	}
+
+	t.Setenv("VERIFY_DATA", "fail")
+	if cfg, err = Load(path); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.VerifyData != VerifyDataFail {
+		t.Errorf("expected VerifyData=fail, got %q", cfg.VerifyData)
+	}
+
+	t.Setenv("DEGRADED_MODE", "true")
+	if cfg, err = Load(path); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !cfg.Degraded {
+		t.Error("expected Degraded with DEGRADED_MODE=true")
+	}
+
+	t.Setenv("VERIFY_DATA", "strict")
+	if _, err := Load(path); err == nil {
+		t.Error("expected error for invalid VERIFY_DATA")
+	}
+}
+
+func TestLoadConfigSQLTrace(t *testing.T) {
+	path := writeTemp(t, "- name: n1\n  hostname: pgedge-n1-rw\n")
+	t.Setenv("APP_NAME", "pgedge")
+	t.Setenv("DB_NAME", "app")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.SQLTrace != SQLTraceOff || cfg.SQLTraceArgs {
+		t.Errorf("expected SQL tracing off without args by default, got %q, %v", cfg.SQLTrace, cfg.SQLTraceArgs)
+	}
+
+	t.Setenv("SQL_TRACE", "debug")
+	t.Setenv("SQL_TRACE_ARGS", "true")
+	if cfg, err = Load(path); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.SQLTrace != SQLTraceDebug || !cfg.SQLTraceArgs {
+		t.Errorf("expected SQLTrace=debug with args, got %q, %v", cfg.SQLTrace, cfg.SQLTraceArgs)
+	}
+
+	t.Setenv("SQL_TRACE", "verbose")
+	if _, err := Load(path); err == nil {
+		t.Error("expected error for invalid SQL_TRACE")
+	}
+}
+
+func TestLoadConfigWaits(t *testing.T) {
+	path := writeTemp(t, "- name: n1\n  hostname: pgedge-n1-rw\n")
+	t.Setenv("APP_NAME", "pgedge")
+	t.Setenv("DB_NAME", "app")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Waits != DefaultWaits() {
+		t.Errorf("expected default waits, got %+v", cfg.Waits)
+	}
+
+	t.Setenv("WAIT_PEER_CATCHUP_TIMEOUT", "0")
+	t.Setenv("WAIT_SYNC_EVENT_TIMEOUT", "90m")
+	t.Setenv("WAIT_SYNC_EVENT_INTERVAL", "30s")
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Waits.PeerCatchup.Timeout != 0 || cfg.Waits.PeerCatchup.Interval != 500*time.Millisecond {
+		t.Errorf("expected unbounded peer catchup at the default interval, got %+v", cfg.Waits.PeerCatchup)
+	}
+	if cfg.Waits.SyncEvent != (Wait{Timeout: 90 * time.Minute, Interval: 30 * time.Second}) {
+		t.Errorf("expected sync event wait 90m/30s, got %+v", cfg.Waits.SyncEvent)
+	}
+
+	for name, value := range map[string]string{
+		"WAIT_NODE_READY_INTERVAL": "0",
+		"WAIT_CLUSTERS_TIMEOUT":    "soon",
+		"WAIT_SYNC_EVENT_INTERVAL": "500ms",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := Load(path); err == nil {
+				t.Errorf("expected error for %s=%s", name, value)
+			}
+		})
+	}
+}
+
+func TestOverrideEndpoints(t *testing.T) {
+	cfg := &Config{Nodes: []Node{
+		{Name: "n1", Hostname: "pgedge-n1-rw"},
+		{Name: "n2", Hostname: "pgedge-n2-rw", InternalHostname: "pgedge-n2-rw.ns"},
+	}}
+	if err := cfg.OverrideEndpoints(map[string]string{"n2": "localhost:15433"}); err != nil {
+		t.Fatalf("OverrideEndpoints: %v", err)
+	}
+	if cfg.Nodes[1].InternalHostname != "localhost:15433" || cfg.Nodes[1].Hostname != "pgedge-n2-rw" {
+		t.Errorf("expected only the connect address to change, got %+v", cfg.Nodes[1])
+	}
+	if cfg.Nodes[0].InternalHostname != "" {
+		t.Errorf("n1 should be unchanged, got %+v", cfg.Nodes[0])
+	}
+	if err := cfg.OverrideEndpoints(map[string]string{"n9": "localhost:1"}); err == nil {
+		t.Error("expected error for unknown node")
+	}
+}
+
+func TestWithoutNodes(t *testing.T) {
+	cfg := &Config{Nodes: []Node{{Name: "n1"}, {Name: "n2"}, {Name: "n3"}}}
+	out := cfg.WithoutNodes([]string{"n2"})
+	if len(out.Nodes) != 2 || out.Nodes[0].Name != "n1" || out.Nodes[1].Name != "n3" {
+		t.Errorf("expected nodes n1 and n3, got %+v", out.Nodes)
+	}
+	if len(out.Excluded) != 1 || out.Excluded[0] != "n2" {
+		t.Errorf("expected n2 excluded, got %v", out.Excluded)
+	}
+	if len(cfg.Nodes) != 3 || cfg.Excluded != nil {
+		t.Errorf("original configuration changed: %+v", cfg)
+	}
+}
+
 func writeTemp(t *testing.T, content string) string {
 	t.Helper()
 	dir := t.TempDir()
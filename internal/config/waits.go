@@ -0,0 +1,91 @@
+// internal/config/waits.go
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Wait bounds one step that waits on the cluster: how long the step may
+// take and how often it checks again.
+type Wait struct {
+	// Timeout is the step's own deadline; zero leaves it bounded only by
+	// the run's deadline.
+	Timeout time.Duration
+	// Interval is the pause between checks.
+	Interval time.Duration
+}
+
+// Context returns ctx bounded by the wait's timeout, if it has one.
+func (w Wait) Context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if w.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, w.Timeout)
+}
+
+// Waits configures each wait step of a run. Every step reads
+// WAIT_<STEP>_TIMEOUT and WAIT_<STEP>_INTERVAL as Go durations, e.g.
+// WAIT_PEER_CATCHUP_TIMEOUT=1h.
+type Waits struct {
+	Clusters    Wait // CNPG Clusters becoming Ready; Interval is the pause after a failed list or watch
+	NodeReady   Wait // all nodes accepting connections, waited for concurrently
+	SyncEvent   Wait // a subscriber receiving a sync event; Interval is passed to spock.wait_for_sync_event
+	PeerCatchup Wait // the source applying a peer's changes up to a sync event
+}
+
+// DefaultWaits leaves the wait steps bounded only by the run's deadline,
+// except node readiness and peer catchup: a node that does not come up
+// should be reported along with the state of the others, and a stalled
+// catchup should fail with its position, rather than use up the Job's
+// deadline.
+func DefaultWaits() Waits {
+	return Waits{
+		Clusters:    Wait{Interval: 5 * time.Second},
+		NodeReady:   Wait{Timeout: 10 * time.Minute, Interval: 3 * time.Second},
+		SyncEvent:   Wait{Interval: 10 * time.Second},
+		PeerCatchup: Wait{Timeout: 30 * time.Minute, Interval: 500 * time.Millisecond},
+	}
+}
+
+// loadWaits applies the WAIT_* environment variables to DefaultWaits.
+func loadWaits() (Waits, error) {
+	waits := DefaultWaits()
+	for step, w := range map[string]*Wait{
+		"CLUSTERS":     &waits.Clusters,
+		"NODE_READY":   &waits.NodeReady,
+		"SYNC_EVENT":   &waits.SyncEvent,
+		"PEER_CATCHUP": &waits.PeerCatchup,
+	} {
+		if err := parseDuration("WAIT_"+step+"_TIMEOUT", &w.Timeout, true); err != nil {
+			return Waits{}, err
+		}
+		if err := parseDuration("WAIT_"+step+"_INTERVAL", &w.Interval, false); err != nil {
+			return Waits{}, err
+		}
+	}
+	if waits.SyncEvent.Interval < time.Second {
+		return Waits{}, fmt.Errorf("WAIT_SYNC_EVENT_INTERVAL must be at least 1s, got %s", waits.SyncEvent.Interval)
+	}
+	return waits, nil
+}
+
+// parseDuration sets *d from the environment variable name if it is set.
+// Zero is accepted only when allowZero is set.
+func parseDuration(name string, d *time.Duration, allowZero bool) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if parsed < 0 || (parsed == 0 && !allowZero) {
+		return fmt.Errorf("%s must be positive, got %s", name, value)
+	}
+	*d = parsed
+	return nil
+}
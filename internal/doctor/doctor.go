@@ -0,0 +1,336 @@
+// internal/doctor/doctor.go
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/pgEdge/pgedge-helm/internal/config"
+	"github.com/pgEdge/pgedge-helm/internal/pg"
+)
+
+// Severity ranks a finding. Only SeverityError findings stop a run.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// minSpockVersion is the oldest Spock release init-spock supports; it
+// relies on spock.sub_show_status(), spock.sync_event() and
+// spock.lag_tracker as they exist in Spock 5.
+const minSpockVersion = "5.0"
+
+// Finding is the result of one check on one node.
+type Finding struct {
+	Node     string   `json:"node"`
+	Check    string   `json:"check"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	Hint     string   `json:"hint,omitempty"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Node, f.Check, f.Message)
+}
+
+// settings is what the checks read from one node.
+type settings struct {
+	params       map[string]string
+	spockVersion string // empty if the extension is not installed
+	pgedgeUser   *role  // nil if the role does not exist
+	adminIsSuper bool
+}
+
+type role struct {
+	login, replication, superuser bool
+}
+
+// Check runs the preflight checks on every node of cfg. A node that cannot
+// be inspected yields a single error finding. peers is the number of peers
+// each node replicates with in the full mesh, which sizes the capacity
+// checks; a degraded run checks fewer nodes than that.
+func Check(ctx context.Context, cfg *config.Config, conns map[string]*pgxpool.Pool, peers int) []Finding {
+	findings := checkBootstrap(cfg)
+	for _, node := range cfg.Nodes {
+		s, err := readSettings(ctx, conns[node.Name], cfg.PgEdgeUser)
+		if err != nil {
+			findings = append(findings, Finding{
+				Node:     node.Name,
+				Check:    "connection",
+				Severity: SeverityError,
+				Message:  err.Error(),
+				Hint:     "check that the node is running and reachable with the admin client certificate",
+			})
+			continue
+		}
+		findings = append(findings, evaluate(node.Name, s, peers, cfg.PgEdgeUser)...)
+	}
+	return findings
+}
+
+// checkBootstrap checks that every node being added with Spock populates
+// from a node that already has its data. Several nodes may be added in
+// one run, but none of them can be the source of another.
+func checkBootstrap(cfg *config.Config) []Finding {
+	adding := map[string]bool{}
+	for _, node := range cfg.Nodes {
+		if node.Bootstrap.Mode == "spock" && node.Bootstrap.SourceNode != "" {
+			adding[node.Name] = true
+		}
+	}
+	known := map[string]bool{}
+	for _, node := range cfg.Nodes {
+		known[node.Name] = true
+	}
+
+	var findings []Finding
+	for _, node := range cfg.Nodes {
+		if !adding[node.Name] {
+			continue
+		}
+		source := node.Bootstrap.SourceNode
+		var msg string
+		switch {
+		case source == node.Name:
+			msg = "bootstrap source node is the node itself"
+		case !known[source]:
+			msg = fmt.Sprintf("bootstrap source node %s is not in the node list", source)
+		case adding[source]:
+			msg = fmt.Sprintf("bootstrap source node %s is itself being added", source)
+		default:
+			continue
+		}
+		findings = append(findings, Finding{
+			Node:     node.Name,
+			Check:    "bootstrap",
+			Severity: SeverityError,
+			Message:  msg,
+			Hint:     "set bootstrap.sourceNode to a node that is already part of the cluster",
+		})
+	}
+	for _, node := range cfg.Nodes {
+		if !adding[node.Name] {
+			continue
+		}
+		var msg string
+		switch node.Bootstrap.Structure {
+		case "", config.StructureSpock:
+			if len(node.Bootstrap.SchemaFilter) > 0 {
+				msg = "bootstrap.schemaFilter only applies with bootstrap.structure dump"
+			}
+		case config.StructureDump:
+			if _, err := pg.DumpFilterArgs(node.Bootstrap.SchemaFilter); err != nil {
+				msg = err.Error()
+			}
+		default:
+			msg = fmt.Sprintf("unknown bootstrap structure %q", node.Bootstrap.Structure)
+		}
+		if msg == "" {
+			continue
+		}
+		findings = append(findings, Finding{
+			Node:     node.Name,
+			Check:    "bootstrap",
+			Severity: SeverityError,
+			Message:  msg,
+			Hint:     `set bootstrap.structure to "spock" or "dump", with schemaFilter entries like "exclude schema audit"`,
+		})
+	}
+	return findings
+}
+
+// HasErrors reports whether any finding has SeverityError.
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns an error listing the SeverityError findings, or nil if
+// there are none.
+func Err(findings []Finding) error {
+	var failed []string
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			failed = append(failed, f.String())
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("preflight checks failed: %s", strings.Join(failed, "; "))
+}
+
+// Log writes the findings that are not informational to the default logger.
+func Log(findings []Finding) {
+	for _, f := range findings {
+		level := slog.LevelWarn
+		switch f.Severity {
+		case SeverityError:
+			level = slog.LevelError
+		case SeverityInfo:
+			continue
+		}
+		slog.Log(context.Background(), level, "preflight check", "node", f.Node, "check", f.Check,
+			"message", f.Message, "hint", f.Hint)
+	}
+}
+
+func readSettings(ctx context.Context, conn *pgxpool.Pool, pgedgeUser string) (settings, error) {
+	s := settings{params: map[string]string{}}
+	if conn == nil {
+		return s, errors.New("not connected")
+	}
+
+	rows, err := conn.Query(ctx, `
+		SELECT name, setting FROM pg_settings
+		WHERE name IN ('wal_level', 'track_commit_timestamp', 'shared_preload_libraries',
+		               'max_replication_slots', 'max_wal_senders', 'max_worker_processes')`)
+	if err != nil {
+		return s, fmt.Errorf("read settings: %w", err)
+	}
+	for rows.Next() {
+		var name, setting string
+		if err := rows.Scan(&name, &setting); err != nil {
+			rows.Close()
+			return s, fmt.Errorf("read settings: %w", err)
+		}
+		s.params[name] = setting
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return s, fmt.Errorf("read settings: %w", err)
+	}
+
+	err = conn.QueryRow(ctx, "SELECT extversion FROM pg_extension WHERE extname = 'spock'").Scan(&s.spockVersion)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return s, fmt.Errorf("read spock version: %w", err)
+	}
+
+	var r role
+	err = conn.QueryRow(ctx,
+		"SELECT rolcanlogin, rolreplication, rolsuper FROM pg_roles WHERE rolname = $1", pgedgeUser,
+	).Scan(&r.login, &r.replication, &r.superuser)
+	switch {
+	case err == nil:
+		s.pgedgeUser = &r
+	case !errors.Is(err, pgx.ErrNoRows):
+		return s, fmt.Errorf("read %s role: %w", pgedgeUser, err)
+	}
+
+	if err := conn.QueryRow(ctx, "SELECT rolsuper FROM pg_roles WHERE rolname = current_user").Scan(&s.adminIsSuper); err != nil {
+		return s, fmt.Errorf("read admin role: %w", err)
+	}
+	return s, nil
+}
+
+// evaluate turns a node's settings into findings. peers is the number of
+// other nodes in the mesh.
+func evaluate(node string, s settings, peers int, pgedgeUser string) []Finding {
+	var findings []Finding
+	add := func(check string, severity Severity, message, hint string) {
+		findings = append(findings, Finding{Node: node, Check: check, Severity: severity, Message: message, Hint: hint})
+	}
+
+	if v := s.params["wal_level"]; v != "logical" {
+		add("wal_level", SeverityError, fmt.Sprintf("wal_level is %q, Spock needs \"logical\"", v),
+			"set wal_level: logical under postgresql.parameters and restart the node")
+	}
+	if v := s.params["track_commit_timestamp"]; v != "on" {
+		add("track_commit_timestamp", SeverityError, fmt.Sprintf("track_commit_timestamp is %q, Spock needs \"on\"", v),
+			"set track_commit_timestamp: \"on\" under postgresql.parameters and restart the node")
+	}
+
+	libs := map[string]bool{}
+	for _, lib := range strings.Split(s.params["shared_preload_libraries"], ",") {
+		libs[strings.TrimSpace(lib)] = true
+	}
+	for _, lib := range []string{"spock", "snowflake"} {
+		if !libs[lib] {
+			add("shared_preload_libraries", SeverityError, lib+" is not in shared_preload_libraries",
+				"add "+lib+" to postgresql.shared_preload_libraries and restart the node")
+		}
+	}
+
+	switch {
+	case s.spockVersion == "":
+		add("spock_extension", SeverityError, "the spock extension is not installed",
+			"run CREATE EXTENSION spock in the application database; the chart does this in bootstrap.initdb.postInitApplicationSQL")
+	case compareVersions(s.spockVersion, minSpockVersion) < 0:
+		add("spock_extension", SeverityError,
+			fmt.Sprintf("spock %s is installed, init-spock needs %s or later", s.spockVersion, minSpockVersion),
+			"upgrade the image and run ALTER EXTENSION spock UPDATE")
+	default:
+		add("spock_extension", SeverityInfo, "spock "+s.spockVersion+" is installed", "")
+	}
+
+	// Each peer needs a slot and a WAL sender on this node for its
+	// subscription, and an apply worker here for this node's subscription
+	// to it. Initial syncs, CNPG standbys and the Spock manager take more.
+	checkCapacity := func(param string, needed, recommended int, why string) {
+		v, err := strconv.Atoi(s.params[param])
+		switch {
+		case err != nil:
+			add(param, SeverityWarning, fmt.Sprintf("%s is %q and could not be checked", param, s.params[param]), "")
+		case v < needed:
+			add(param, SeverityError, fmt.Sprintf("%s is %d, %d peers need at least %d for %s", param, v, peers, needed, why),
+				fmt.Sprintf("set %s to %d or more under postgresql.parameters and restart the node", param, recommended))
+		case v < recommended:
+			add(param, SeverityWarning, fmt.Sprintf("%s is %d, leaving little headroom beyond the %d %s of %d peers", param, v, needed, why, peers),
+				fmt.Sprintf("set %s to %d or more under postgresql.parameters and restart the node", param, recommended))
+		}
+	}
+	checkCapacity("max_replication_slots", peers, 2*peers+2, "replication slots")
+	checkCapacity("max_wal_senders", peers, 2*peers+2, "WAL senders")
+	checkCapacity("max_worker_processes", peers+1, 2*peers+2, "Spock workers")
+
+	if !s.adminIsSuper {
+		add("admin_privileges", SeverityError, "the admin user is not a superuser",
+			"init-spock connects as the admin user, which needs SUPERUSER to manage Spock")
+	}
+	switch u := s.pgedgeUser; {
+	case u == nil:
+		add("pgedge_user", SeverityInfo, fmt.Sprintf("role %s does not exist and will be created", pgedgeUser), "")
+	case !u.login || !u.replication || !u.superuser:
+		add("pgedge_user", SeverityError,
+			fmt.Sprintf("role %s lacks LOGIN, REPLICATION or SUPERUSER (login=%t, replication=%t, superuser=%t)",
+				pgedgeUser, u.login, u.replication, u.superuser),
+			fmt.Sprintf("run ALTER ROLE %s WITH LOGIN REPLICATION SUPERUSER", pgedgeUser))
+	}
+	return findings
+}
+
+// compareVersions compares dotted numeric versions such as "5.0.1";
+// missing components count as zero.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,161 @@
+// internal/doctor/doctor_test.go
+package doctor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pgEdge/pgedge-helm/internal/config"
+)
+
+func healthySettings() settings {
+	return settings{
+		params: map[string]string{
+			"wal_level":                "logical",
+			"track_commit_timestamp":   "on",
+			"shared_preload_libraries": "pg_stat_statements,snowflake,spock",
+			"max_replication_slots":    "10",
+			"max_wal_senders":          "10",
+			"max_worker_processes":     "8",
+		},
+		spockVersion: "5.0.4",
+		pgedgeUser:   &role{login: true, replication: true, superuser: true},
+		adminIsSuper: true,
+	}
+}
+
+func findingsFor(findings []Finding, check string) []Finding {
+	var matched []Finding
+	for _, f := range findings {
+		if f.Check == check {
+			matched = append(matched, f)
+		}
+	}
+	return matched
+}
+
+func TestEvaluateHealthy(t *testing.T) {
+	findings := evaluate("n1", healthySettings(), 2, "pgedge")
+	if HasErrors(findings) {
+		t.Fatalf("expected no errors, got %v", findings)
+	}
+	for _, f := range findings {
+		if f.Severity != SeverityInfo {
+			t.Errorf("unexpected finding: %+v", f)
+		}
+	}
+	if v := findingsFor(findings, "spock_extension"); len(v) != 1 || !strings.Contains(v[0].Message, "5.0.4") {
+		t.Errorf("expected the installed spock version to be reported, got %v", v)
+	}
+}
+
+func TestEvaluateMisconfigured(t *testing.T) {
+	s := healthySettings()
+	s.params["wal_level"] = "replica"
+	s.params["track_commit_timestamp"] = "off"
+	s.params["shared_preload_libraries"] = "pg_stat_statements"
+	s.spockVersion = "4.0.1"
+	s.pgedgeUser = &role{login: true}
+	s.adminIsSuper = false
+
+	findings := evaluate("n1", s, 2, "pgedge")
+	for _, check := range []string{"wal_level", "track_commit_timestamp", "spock_extension", "pgedge_user", "admin_privileges"} {
+		v := findingsFor(findings, check)
+		if len(v) != 1 || v[0].Severity != SeverityError || v[0].Hint == "" {
+			t.Errorf("expected one error with a hint for %s, got %v", check, v)
+		}
+	}
+	if v := findingsFor(findings, "shared_preload_libraries"); len(v) != 2 {
+		t.Errorf("expected spock and snowflake to be reported missing, got %v", v)
+	}
+	if err := Err(findings); err == nil || !strings.Contains(err.Error(), "n1: wal_level") {
+		t.Errorf("expected Err to list the failed checks, got %v", err)
+	}
+}
+
+func TestEvaluateCapacity(t *testing.T) {
+	s := healthySettings()
+	s.params["max_replication_slots"] = "3"
+	s.params["max_wal_senders"] = "6"
+
+	findings := evaluate("n1", s, 4, "pgedge")
+	if v := findingsFor(findings, "max_replication_slots"); len(v) != 1 || v[0].Severity != SeverityError {
+		t.Errorf("expected 3 slots for 4 peers to be an error, got %v", v)
+	}
+	if v := findingsFor(findings, "max_wal_senders"); len(v) != 1 || v[0].Severity != SeverityWarning {
+		t.Errorf("expected 6 senders for 4 peers to be a warning, got %v", v)
+	}
+	if v := findingsFor(findings, "max_worker_processes"); len(v) != 1 || v[0].Severity != SeverityWarning {
+		t.Errorf("expected 8 workers for 4 peers to be a warning, got %v", v)
+	}
+}
+
+func TestEvaluateMissingPgEdgeUser(t *testing.T) {
+	s := healthySettings()
+	s.pgedgeUser = nil
+	if v := findingsFor(evaluate("n1", s, 1, "pgedge"), "pgedge_user"); len(v) != 1 || v[0].Severity != SeverityInfo {
+		t.Errorf("a missing pgedge user is created by init-spock, got %v", v)
+	}
+}
+
+func TestCheckBootstrap(t *testing.T) {
+	spock := func(source string) config.NodeBootstrap {
+		return config.NodeBootstrap{Mode: "spock", SourceNode: source}
+	}
+	cfg := &config.Config{Nodes: []config.Node{
+		{Name: "n1"},
+		{Name: "n2", Bootstrap: spock("n1")},
+		{Name: "n3", Bootstrap: spock("n1")},
+		{Name: "n4", Bootstrap: spock("n3")},
+		{Name: "n5", Bootstrap: spock("n9")},
+	}}
+	findings := checkBootstrap(cfg)
+	var nodes []string
+	for _, f := range findings {
+		if f.Severity != SeverityError {
+			t.Errorf("expected an error, got %v", f)
+		}
+		nodes = append(nodes, f.Node)
+	}
+	if strings.Join(nodes, ",") != "n4,n5" {
+		t.Errorf("expected findings for n4 and n5, got %v", findings)
+	}
+}
+
+func TestCheckBootstrapStructure(t *testing.T) {
+	dump := func(filters ...string) config.NodeBootstrap {
+		return config.NodeBootstrap{Mode: "spock", SourceNode: "n1", Structure: config.StructureDump, SchemaFilter: filters}
+	}
+	cfg := &config.Config{Nodes: []config.Node{
+		{Name: "n1"},
+		{Name: "n2", Bootstrap: dump("exclude schema audit", "include table public.orders")},
+		{Name: "n3", Bootstrap: dump("exclude function f")},
+		{Name: "n4", Bootstrap: config.NodeBootstrap{Mode: "spock", SourceNode: "n1", Structure: "copy"}},
+		{Name: "n5", Bootstrap: config.NodeBootstrap{Mode: "spock", SourceNode: "n1", SchemaFilter: []string{"exclude schema audit"}}},
+	}}
+	var nodes []string
+	for _, f := range checkBootstrap(cfg) {
+		nodes = append(nodes, f.Node)
+	}
+	if strings.Join(nodes, ",") != "n3,n4,n5" {
+		t.Errorf("expected findings for n3, n4 and n5, got %v", nodes)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"5.0", "5.0", 0},
+		{"5.0.1", "5.0", 1},
+		{"4.1", "5.0", -1},
+		{"5", "5.0.0", 0},
+		{"10.0", "9.9", 1},
+	}
+	for _, c := range cases {
+		if got := compareVersions(c.a, c.b); got != c.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// internal/kube/kube.go
+package kube
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Clients are the Kubernetes API clients init-spock uses: the dynamic
+// client for CNPG Clusters and the typed client for Events, ConfigMaps
+// and Leases.
+type Clients struct {
+	Dynamic    dynamic.Interface
+	Kubernetes kubernetes.Interface
+}
+
+// RestConfig returns the API server configuration. With no kubeconfig or
+// context it uses the pod's service account, falling back to the default
+// kubeconfig loading rules ($KUBECONFIG, ~/.kube/config) outside a pod.
+func RestConfig(kubeconfig, context string) (*rest.Config, error) {
+	if kubeconfig == "" && context == "" {
+		if cfg, err := rest.InClusterConfig(); err == nil {
+			return cfg, nil
+		}
+	}
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("k8s client config: %w", err)
+	}
+	return cfg, nil
+}
+
+// NewClients creates the clients for the given kubeconfig and context; see
+// RestConfig.
+func NewClients(kubeconfig, context string) (*Clients, error) {
+	restCfg, err := RestConfig(kubeconfig, context)
+	if err != nil {
+		return nil, err
+	}
+	dyn, err := dynamic.NewForConfig(restCfg)
+	if err != nil {
+		return nil, fmt.Errorf("k8s dynamic client: %w", err)
+	}
+	kube, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, fmt.Errorf("k8s client: %w", err)
+	}
+	return &Clients{Dynamic: dyn, Kubernetes: kube}, nil
+}
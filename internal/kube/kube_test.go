@@ -0,0 +1,93 @@
+// internal/kube/kube_test.go
+package kube
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+  - name: east
+    cluster:
+      server: https://east.example.com
+  - name: west
+    cluster:
+      server: https://west.example.com
+users:
+  - name: admin
+    user:
+      token: secret
+contexts:
+  - name: east
+    context: {cluster: east, user: admin}
+  - name: west
+    context: {cluster: west, user: admin}
+current-context: east
+`
+
+func TestRestConfigFromKubeconfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(path, []byte(testKubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := RestConfig(path, "")
+	if err != nil {
+		t.Fatalf("RestConfig: %v", err)
+	}
+	if cfg.Host != "https://east.example.com" {
+		t.Errorf("expected current context east, got %q", cfg.Host)
+	}
+
+	cfg, err = RestConfig(path, "west")
+	if err != nil {
+		t.Fatalf("RestConfig: %v", err)
+	}
+	if cfg.Host != "https://west.example.com" {
+		t.Errorf("expected context west, got %q", cfg.Host)
+	}
+
+	if _, err := RestConfig(path, "north"); err == nil {
+		t.Error("expected error for unknown context")
+	}
+}
+
+func TestSnapshotConfigMap(t *testing.T) {
+	ctx := context.Background()
+	client := kubefake.NewSimpleClientset()
+	store := NewSnapshotConfigMap(client, "default", "pgedge")
+
+	if data, err := store.Load(ctx, "n1"); err != nil || data != nil {
+		t.Fatalf("expected no snapshot before save, got %q (%v)", data, err)
+	}
+	if err := store.Save(ctx, "n1", []byte(`{"sets":[]}`)); err != nil {
+		t.Fatalf("save n1: %v", err)
+	}
+	if err := store.Save(ctx, "n2", []byte(`{"tables":[]}`)); err != nil {
+		t.Fatalf("save n2: %v", err)
+	}
+	if data, err := store.Load(ctx, "n1"); err != nil || string(data) != `{"sets":[]}` {
+		t.Fatalf("unexpected n1 snapshot %q (%v)", data, err)
+	}
+
+	if err := store.Clear(ctx, "n1"); err != nil {
+		t.Fatalf("clear n1: %v", err)
+	}
+	cm, err := client.CoreV1().ConfigMaps("default").Get(ctx, "pgedge-repset-snapshot", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cm.Data["n1.json"]; ok || cm.Data["n2.json"] == "" {
+		t.Errorf("expected only n2's snapshot to remain, got %v", cm.Data)
+	}
+	if err := store.Clear(ctx, "n3"); err != nil {
+		t.Errorf("clearing a missing snapshot should succeed: %v", err)
+	}
+}
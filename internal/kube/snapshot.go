@@ -0,0 +1,92 @@
+// internal/kube/snapshot.go
+package kube
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// SnapshotConfigMapName returns the name of the ConfigMap mirroring the
+// repset snapshots of nodes being reset.
+func SnapshotConfigMapName(appName string) string {
+	return appName + "-repset-snapshot"
+}
+
+// SnapshotConfigMap stores one repset snapshot per node under the key
+// "<node>.json" of a ConfigMap. It implements spock.SnapshotMirror.
+type SnapshotConfigMap struct {
+	client    kubernetes.Interface
+	namespace string
+	appName   string
+}
+
+func NewSnapshotConfigMap(client kubernetes.Interface, namespace, appName string) *SnapshotConfigMap {
+	return &SnapshotConfigMap{client: client, namespace: namespace, appName: appName}
+}
+
+func snapshotKey(node string) string { return node + ".json" }
+
+func (s *SnapshotConfigMap) Save(ctx context.Context, node string, data []byte) error {
+	cms := s.client.CoreV1().ConfigMaps(s.namespace)
+	name := SnapshotConfigMapName(s.appName)
+	existing, err := cms.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = cms.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: s.namespace,
+				Labels:    map[string]string{"pgedge.com/app-name": s.appName},
+			},
+			Data: map[string]string{snapshotKey(node): string(data)},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("get configmap %s: %w", name, err)
+	}
+	if existing.Data == nil {
+		existing.Data = map[string]string{}
+	}
+	existing.Data[snapshotKey(node)] = string(data)
+	_, err = cms.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+func (s *SnapshotConfigMap) Load(ctx context.Context, node string) ([]byte, error) {
+	name := SnapshotConfigMapName(s.appName)
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get configmap %s: %w", name, err)
+	}
+	data, ok := cm.Data[snapshotKey(node)]
+	if !ok {
+		return nil, nil
+	}
+	return []byte(data), nil
+}
+
+func (s *SnapshotConfigMap) Clear(ctx context.Context, node string) error {
+	cms := s.client.CoreV1().ConfigMaps(s.namespace)
+	name := SnapshotConfigMapName(s.appName)
+	existing, err := cms.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get configmap %s: %w", name, err)
+	}
+	if _, ok := existing.Data[snapshotKey(node)]; !ok {
+		return nil
+	}
+	delete(existing.Data, snapshotKey(node))
+	_, err = cms.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,239 @@
+// internal/lock/advisory.go
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrHeld is returned when another init-spock run holds a lock and the
+// caller asked not to wait for it.
+var ErrHeld = errors.New("another init-spock run is in progress")
+
+// ErrLost is the cause of a context from MeshLock.Hold canceled because a
+// node's lock session ended, e.g. when its primary failed over, or from
+// Lease.Hold canceled because another run took the Lease.
+var ErrLost = errors.New("lost the mesh lock")
+
+// meshLockKey is the session-level advisory lock init-spock holds on every
+// node while it plans and executes. Any fixed value works as long as all
+// releases agree on it; this one spells "pgEdgeSp" in ASCII.
+const meshLockKey int64 = 0x7067456467655370
+
+// pollInterval is how often a waiting run retries a contended lock, and
+// how often a held mesh lock is checked.
+const pollInterval = 5 * time.Second
+
+// checkTimeout bounds one check of the held mesh lock. A check that times
+// out closes its connection, which releases the lock on that node.
+const checkTimeout = 10 * time.Second
+
+// MeshLock holds the advisory lock on every node of the mesh.
+type MeshLock struct {
+	held []heldLock
+
+	// Set by Hold: stop ends the checks, done is closed once they have.
+	stop   context.CancelFunc
+	done   chan struct{}
+	cancel context.CancelCauseFunc
+}
+
+type heldLock struct {
+	node string
+	conn *pgx.Conn
+}
+
+// AcquireMesh takes the mesh advisory lock on every node in pools, in
+// sorted node order so that concurrent runs cannot deadlock. Each lock is
+// held on a dedicated connection taken out of its pool, so pool resets
+// after a failover do not release it. identity is set as the connection's
+// application_name so a waiting run can report who holds the lock.
+//
+// When wait is false and a node's lock is held elsewhere, the locks
+// already taken are released and an error wrapping ErrHeld is returned.
+func AcquireMesh(ctx context.Context, pools map[string]*pgxpool.Pool, identity string, wait bool) (*MeshLock, error) {
+	names := make([]string, 0, len(pools))
+	for name := range pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	l := &MeshLock{}
+	for _, name := range names {
+		conn, err := dedicatedConn(ctx, pools[name], identity)
+		if err != nil {
+			l.Release(ctx)
+			return nil, fmt.Errorf("mesh lock on %s: %w", name, err)
+		}
+		l.held = append(l.held, heldLock{node: name, conn: conn})
+
+		if err := lockNode(ctx, conn, name, wait); err != nil {
+			l.Release(ctx)
+			return nil, err
+		}
+	}
+	slog.Info("acquired mesh lock", "nodes", len(names))
+	return l, nil
+}
+
+func dedicatedConn(ctx context.Context, pool *pgxpool.Pool, identity string) (*pgx.Conn, error) {
+	pc, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn := pc.Hijack()
+	if _, err := conn.Exec(ctx, "SELECT set_config('application_name', $1, false)", identity); err != nil {
+		conn.Close(ctx)
+		return nil, fmt.Errorf("set application_name: %w", err)
+	}
+	return conn, nil
+}
+
+// lockNode takes the advisory lock on one node, polling while it is held
+// elsewhere if wait is set.
+func lockNode(ctx context.Context, conn *pgx.Conn, node string, wait bool) error {
+	lastHolder := ""
+	for {
+		var ok bool
+		if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", meshLockKey).Scan(&ok); err != nil {
+			return fmt.Errorf("mesh lock on %s: %w", node, err)
+		}
+		if ok {
+			return nil
+		}
+
+		holder := lockHolder(ctx, conn)
+		if !wait {
+			return fmt.Errorf("%w: mesh lock on %s is held by %s", ErrHeld, node, holder)
+		}
+		if holder != lastHolder {
+			lastHolder = holder
+			slog.Info("waiting for mesh lock", "node", node, "holder", holder)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for mesh lock on %s (held by %s): %w", node, holder, ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// lockHolder describes the session holding the mesh lock on conn's server.
+func lockHolder(ctx context.Context, conn *pgx.Conn) string {
+	var appName, clientAddr string
+	var since time.Time
+	err := conn.QueryRow(ctx, `
+		SELECT coalesce(a.application_name, ''), coalesce(host(a.client_addr), 'local'), a.backend_start
+		FROM pg_locks l JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.granted
+		  AND l.classid = $1 AND l.objid = $2 AND l.objsubid = 1`,
+		lockKeyHigh(meshLockKey), lockKeyLow(meshLockKey),
+	).Scan(&appName, &clientAddr, &since)
+	if err != nil {
+		return "an unknown session"
+	}
+	if appName == "" {
+		appName = "unnamed session"
+	}
+	return fmt.Sprintf("%s from %s since %s", appName, clientAddr, since.UTC().Format(time.RFC3339))
+}
+
+// lockKeyHigh and lockKeyLow split a bigint advisory key the way pg_locks
+// reports it: classid holds the high 32 bits, objid the low 32 bits.
+func lockKeyHigh(key int64) uint32 { return uint32(uint64(key) >> 32) }
+func lockKeyLow(key int64) uint32  { return uint32(uint64(key)) }
+
+// Hold returns a context that is canceled, with a cause wrapping ErrLost,
+// as soon as the lock is no longer held on some node. The locks are
+// session locks: a failover or a dropped connection releases them without
+// the run noticing, and another run could then start. Each node's lock is
+// checked every pollInterval, and on every node at once whenever recheck
+// receives, e.g. when a primary changes. Release stops the checks.
+func (l *MeshLock) Hold(ctx context.Context, recheck <-chan struct{}) context.Context {
+	return l.hold(ctx, recheck, l.verify)
+}
+
+func (l *MeshLock) hold(ctx context.Context, recheck <-chan struct{}, verify func(context.Context) error) context.Context {
+	ctx, l.cancel = context.WithCancelCause(ctx)
+	watchCtx, stop := context.WithCancel(ctx)
+	l.stop, l.done = stop, make(chan struct{})
+	go l.watch(watchCtx, recheck, verify)
+	return ctx
+}
+
+func (l *MeshLock) watch(ctx context.Context, recheck <-chan struct{}, verify func(context.Context) error) {
+	defer close(l.done)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-recheck:
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		err := verify(checkCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return // released during the check
+			}
+			slog.Error("lost mesh lock, stopping", "error", err)
+			l.cancel(err)
+			return
+		}
+	}
+}
+
+// verify checks that every held connection is alive and still holds the
+// lock.
+func (l *MeshLock) verify(ctx context.Context) error {
+	for _, h := range l.held {
+		var held bool
+		err := h.conn.QueryRow(ctx, `
+			SELECT EXISTS(
+				SELECT 1 FROM pg_locks
+				WHERE locktype = 'advisory' AND granted AND pid = pg_backend_pid()
+				  AND classid = $1 AND objid = $2 AND objsubid = 1)`,
+			lockKeyHigh(meshLockKey), lockKeyLow(meshLockKey),
+		).Scan(&held)
+		if err != nil {
+			return fmt.Errorf("%w on %s: %v", ErrLost, h.node, err)
+		}
+		if !held {
+			return fmt.Errorf("%w on %s: the session no longer holds it", ErrLost, h.node)
+		}
+	}
+	return nil
+}
+
+// Release stops the checks started by Hold, unlocks every node in reverse
+// order and closes the dedicated connections. Closing a connection
+// releases its session locks even if the unlock fails.
+func (l *MeshLock) Release(ctx context.Context) {
+	if l == nil {
+		return
+	}
+	if l.stop != nil {
+		l.stop()
+		<-l.done
+		l.cancel(nil)
+		l.stop = nil
+	}
+	for i := len(l.held) - 1; i >= 0; i-- {
+		h := l.held[i]
+		if _, err := h.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", meshLockKey); err != nil {
+			slog.Warn("release mesh lock", "node", h.node, "error", err)
+		}
+		h.conn.Close(ctx)
+	}
+	l.held = nil
+}
@@ -0,0 +1,225 @@
+// internal/lock/lease.go
+package lock
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// leaseDuration is how long a Lease stays valid without renewal. A run
+	// that dies without releasing it blocks others for at most this long.
+	leaseDuration = 60 * time.Second
+
+	// leaseRenewInterval is how often the holder renews its Lease.
+	leaseRenewInterval = 20 * time.Second
+)
+
+// LeaseName returns the name of the Lease serializing init-spock runs.
+func LeaseName(appName string) string {
+	return appName + "-init-spock"
+}
+
+// Lease is a held coordination.k8s.io Lease, renewed in the background
+// until Release.
+type Lease struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	identity  string
+	stop      context.CancelFunc
+	done      chan struct{}
+
+	// lost is closed, with err set, once renewal finds the Lease taken.
+	lost chan struct{}
+	err  error
+	// Set by Hold.
+	cancel context.CancelCauseFunc
+}
+
+// AcquireLease takes the named Lease for identity. When wait is false and
+// another holder's Lease has not expired, it returns an error wrapping
+// ErrHeld.
+func AcquireLease(ctx context.Context, client kubernetes.Interface, namespace, name, identity string, wait bool) (*Lease, error) {
+	lastHolder := ""
+	for {
+		holder, err := tryAcquireLease(ctx, client, namespace, name, identity)
+		if err != nil {
+			return nil, fmt.Errorf("acquire lease %s: %w", name, err)
+		}
+		if holder == "" {
+			break
+		}
+		if !wait {
+			return nil, fmt.Errorf("%w: lease %s is held by %s", ErrHeld, name, holder)
+		}
+		if holder != lastHolder {
+			lastHolder = holder
+			slog.Info("waiting for lease", "lease", name, "holder", holder)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for lease %s (held by %s): %w", name, holder, ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+	slog.Info("acquired lease", "lease", name, "identity", identity)
+
+	l := &Lease{client: client, namespace: namespace, name: name, identity: identity}
+	l.start(leaseRenewInterval)
+	return l, nil
+}
+
+// start renews the Lease every interval until Release.
+func (l *Lease) start(interval time.Duration) {
+	renewCtx, stop := context.WithCancel(context.Background())
+	l.stop, l.done, l.lost = stop, make(chan struct{}), make(chan struct{})
+	go l.renew(renewCtx, interval)
+}
+
+// tryAcquireLease creates or takes over the Lease if it is free, expired
+// or already ours. It returns the current holder when someone else has it.
+func tryAcquireLease(ctx context.Context, client kubernetes.Interface, namespace, name, identity string) (string, error) {
+	leases := client.CoordinationV1().Leases(namespace)
+	now := metav1.NewMicroTime(time.Now())
+	seconds := int32(leaseDuration.Seconds())
+
+	lease, err := leases.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = leases.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &identity,
+				LeaseDurationSeconds: &seconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			return "another run", nil
+		}
+		return "", err
+	}
+	if err != nil {
+		return "", err
+	}
+
+	holder := leaseHolder(lease, now.Time)
+	if holder != "" && holder != identity {
+		return holder, nil
+	}
+	if holder != identity {
+		lease.Spec.AcquireTime = &now
+	}
+	lease.Spec.HolderIdentity = &identity
+	lease.Spec.LeaseDurationSeconds = &seconds
+	lease.Spec.RenewTime = &now
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	if apierrors.IsConflict(err) {
+		// Someone else took it between our Get and Update.
+		return "another run", nil
+	}
+	return "", err
+}
+
+// leaseHolder returns the Lease's holder, or "" if it is free or expired.
+func leaseHolder(lease *coordinationv1.Lease, now time.Time) string {
+	spec := lease.Spec
+	if spec.HolderIdentity == nil || *spec.HolderIdentity == "" || spec.RenewTime == nil {
+		return ""
+	}
+	duration := leaseDuration
+	if spec.LeaseDurationSeconds != nil {
+		duration = time.Duration(*spec.LeaseDurationSeconds) * time.Second
+	}
+	if now.After(spec.RenewTime.Add(duration)) {
+		return ""
+	}
+	return *spec.HolderIdentity
+}
+
+// renew keeps the Lease until ctx is canceled. It gives up once another
+// holder has it, or once it has gone unrenewed for leaseDuration and so
+// may have been taken.
+func (l *Lease) renew(ctx context.Context, interval time.Duration) {
+	defer close(l.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		holder, err := tryAcquireLease(ctx, l.client, l.namespace, l.name, l.identity)
+		switch {
+		case ctx.Err() != nil:
+			return // released during the renewal
+		case err == nil && holder == "":
+			renewed = time.Now()
+			continue
+		case err == nil:
+			l.err = fmt.Errorf("%w: lease %s is held by %s", ErrLost, l.name, holder)
+		case time.Since(renewed) >= leaseDuration:
+			l.err = fmt.Errorf("%w: lease %s not renewed for %s: %v", ErrLost, l.name, leaseDuration, err)
+		default:
+			slog.Warn("renew lease", "lease", l.name, "error", err)
+			continue
+		}
+		slog.Error("lost lease, stopping", "lease", l.name, "error", l.err)
+		close(l.lost)
+		return
+	}
+}
+
+// Hold returns a context that is canceled, with a cause wrapping ErrLost,
+// as soon as renewal finds the Lease lost, so that the run stops rather
+// than go on alongside the new holder. Release ends the context.
+func (l *Lease) Hold(ctx context.Context) context.Context {
+	ctx, l.cancel = context.WithCancelCause(ctx)
+	go func() {
+		select {
+		case <-l.lost:
+			l.cancel(l.err)
+		case <-ctx.Done():
+		}
+	}()
+	return ctx
+}
+
+// Release stops renewing the Lease and clears its holder so the next run
+// does not have to wait for it to expire.
+func (l *Lease) Release(ctx context.Context) {
+	if l == nil {
+		return
+	}
+	l.stop()
+	<-l.done
+	if l.cancel != nil {
+		l.cancel(nil)
+	}
+
+	leases := l.client.CoordinationV1().Leases(l.namespace)
+	lease, err := leases.Get(ctx, l.name, metav1.GetOptions{})
+	if err != nil {
+		slog.Warn("release lease", "lease", l.name, "error", err)
+		return
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != l.identity {
+		return
+	}
+	lease.Spec.HolderIdentity = nil
+	lease.Spec.AcquireTime = nil
+	lease.Spec.RenewTime = nil
+	if _, err := leases.Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+		slog.Warn("release lease", "lease", l.name, "error", err)
+	}
+}
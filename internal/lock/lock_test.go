@@ -0,0 +1,194 @@
+// internal/lock/lock_test.go
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLockKeySplit(t *testing.T) {
+	if got := uint64(lockKeyHigh(meshLockKey))<<32 | uint64(lockKeyLow(meshLockKey)); got != uint64(meshLockKey) {
+		t.Errorf("split key does not round-trip: %x != %x", got, meshLockKey)
+	}
+	if lockKeyHigh(-1) != 0xffffffff || lockKeyLow(-1) != 0xffffffff {
+		t.Error("negative keys should split into all-ones halves")
+	}
+}
+
+func getLease(t *testing.T, client *kubefake.Clientset) *coordinationv1.Lease {
+	t.Helper()
+	lease, err := client.CoordinationV1().Leases("default").Get(context.Background(), "pgedge-init-spock", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get lease: %v", err)
+	}
+	return lease
+}
+
+func TestLeaseExclusive(t *testing.T) {
+	ctx := context.Background()
+	client := kubefake.NewSimpleClientset()
+
+	first, err := AcquireLease(ctx, client, "default", "pgedge-init-spock", "job-a", false)
+	if err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+	if holder := getLease(t, client).Spec.HolderIdentity; holder == nil || *holder != "job-a" {
+		t.Fatalf("expected holder job-a, got %v", holder)
+	}
+
+	_, err = AcquireLease(ctx, client, "default", "pgedge-init-spock", "job-b", false)
+	if !errors.Is(err, ErrHeld) {
+		t.Fatalf("expected ErrHeld, got %v", err)
+	}
+
+	first.Release(ctx)
+	if holder := getLease(t, client).Spec.HolderIdentity; holder != nil {
+		t.Fatalf("expected released lease, got holder %q", *holder)
+	}
+
+	second, err := AcquireLease(ctx, client, "default", "pgedge-init-spock", "job-b", false)
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	second.Release(ctx)
+}
+
+func TestLeaseTakesOverExpired(t *testing.T) {
+	ctx := context.Background()
+	holder := "crashed-job"
+	seconds := int32(60)
+	stale := metav1.NewMicroTime(time.Now().Add(-2 * time.Minute))
+	client := kubefake.NewSimpleClientset(&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: "pgedge-init-spock", Namespace: "default"},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &seconds,
+			RenewTime:            &stale,
+		},
+	})
+
+	l, err := AcquireLease(ctx, client, "default", "pgedge-init-spock", "job-a", false)
+	if err != nil {
+		t.Fatalf("expected to take over expired lease: %v", err)
+	}
+	defer l.Release(ctx)
+	if got := getLease(t, client).Spec.HolderIdentity; got == nil || *got != "job-a" {
+		t.Errorf("expected holder job-a, got %v", got)
+	}
+}
+
+func TestLeaseWaitHonorsContext(t *testing.T) {
+	client := kubefake.NewSimpleClientset()
+	first, err := AcquireLease(context.Background(), client, "default", "pgedge-init-spock", "job-a", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Release(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = AcquireLease(ctx, client, "default", "pgedge-init-spock", "job-b", true)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded while waiting, got %v", err)
+	}
+}
+
+func TestLeaseHoldCancelsOnLoss(t *testing.T) {
+	ctx := context.Background()
+	client := kubefake.NewSimpleClientset()
+	if holder, err := tryAcquireLease(ctx, client, "default", "pgedge-init-spock", "job-a"); err != nil || holder != "" {
+		t.Fatalf("acquire: %q, %v", holder, err)
+	}
+	l := &Lease{client: client, namespace: "default", name: "pgedge-init-spock", identity: "job-a"}
+	l.start(10 * time.Millisecond)
+	defer l.Release(ctx)
+	held := l.Hold(ctx)
+
+	// Another run takes the Lease, e.g. after ours expired during an API
+	// server outage.
+	lease := getLease(t, client)
+	other := "job-b"
+	now := metav1.NewMicroTime(time.Now())
+	lease.Spec.HolderIdentity, lease.Spec.RenewTime = &other, &now
+	if _, err := client.CoordinationV1().Leases("default").Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-held.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected losing the lease to cancel the context")
+	}
+	if cause := context.Cause(held); !errors.Is(cause, ErrLost) {
+		t.Errorf("expected ErrLost as the cause, got %v", cause)
+	}
+
+	l.Release(ctx)
+	if holder := getLease(t, client).Spec.HolderIdentity; holder == nil || *holder != "job-b" {
+		t.Errorf("expected Release to leave job-b's lease alone, got %v", holder)
+	}
+}
+
+func TestLeaseReleaseEndsHold(t *testing.T) {
+	ctx := context.Background()
+	l, err := AcquireLease(ctx, kubefake.NewSimpleClientset(), "default", "pgedge-init-spock", "job-a", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	held := l.Hold(ctx)
+	l.Release(ctx)
+	if held.Err() == nil || errors.Is(context.Cause(held), ErrLost) {
+		t.Errorf("expected Release to end the context without ErrLost, got %v", context.Cause(held))
+	}
+}
+
+func TestMeshLockHoldCancelsOnLoss(t *testing.T) {
+	l := &MeshLock{}
+	recheck := make(chan struct{}, 1)
+	lost := fmt.Errorf("%w on n2: conn closed", ErrLost)
+	checks := make(chan struct{}, 1)
+	ctx := l.hold(context.Background(), recheck, func(context.Context) error {
+		checks <- struct{}{}
+		return lost
+	})
+	defer l.Release(context.Background())
+
+	if ctx.Err() != nil {
+		t.Fatal("expected the context to stay live until a check fails")
+	}
+	recheck <- struct{}{}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected a recheck to cancel the context at once")
+	}
+	if cause := context.Cause(ctx); !errors.Is(cause, ErrLost) || cause.Error() != "lost the mesh lock on n2: conn closed" {
+		t.Errorf("expected ErrLost as the cause, got %v", cause)
+	}
+	if len(checks) != 1 {
+		t.Errorf("expected one check, got %d", len(checks))
+	}
+}
+
+func TestMeshLockReleaseStopsChecks(t *testing.T) {
+	l := &MeshLock{}
+	recheck := make(chan struct{})
+	ctx := l.hold(context.Background(), recheck, func(context.Context) error { return nil })
+	recheck <- struct{}{}
+
+	l.Release(context.Background())
+	if ctx.Err() == nil || errors.Is(context.Cause(ctx), ErrLost) {
+		t.Errorf("expected Release to end the context without ErrLost, got %v", context.Cause(ctx))
+	}
+	select {
+	case recheck <- struct{}{}:
+		t.Error("expected no checks after Release")
+	default:
+	}
+}
@@ -0,0 +1,103 @@
+// internal/pg/dump.go
+package pg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// pgDumpEnv names the environment variable overriding the pg_dump binary.
+const pgDumpEnv = "PG_DUMP"
+
+// alwaysExcludedSchemas are never part of a schema pre-copy: Spock creates
+// its own schema on the new node, and init-spock keeps its bookkeeping in
+// pgedge_init_spock.
+var alwaysExcludedSchemas = []string{"spock", "pgedge_init_spock"}
+
+// dumpFilterFlags maps the object types of a dump filter to the pg_dump
+// flags that include and exclude them.
+var dumpFilterFlags = map[string][2]string{
+	"schema":    {"--schema", "--exclude-schema"},
+	"table":     {"--table", "--exclude-table"},
+	"extension": {"--extension", "--exclude-extension"},
+}
+
+// DumpFilterArgs turns a filter list into pg_dump arguments. Each entry has
+// the form of a pg_dump --filter line, "include|exclude schema|table|extension
+// PATTERN", e.g. "exclude schema audit" or "include table public.orders".
+func DumpFilterArgs(filters []string) ([]string, error) {
+	var args []string
+	for _, filter := range filters {
+		fields := strings.Fields(filter)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid dump filter %q: want \"include|exclude <object type> <pattern>\"", filter)
+		}
+		flags, ok := dumpFilterFlags[fields[1]]
+		if !ok {
+			return nil, fmt.Errorf("invalid dump filter %q: object type must be schema, table or extension", filter)
+		}
+		switch fields[0] {
+		case "include":
+			args = append(args, flags[0]+"="+fields[2])
+		case "exclude":
+			args = append(args, flags[1]+"="+fields[2])
+		default:
+			return nil, fmt.Errorf("invalid dump filter %q: must start with include or exclude", filter)
+		}
+	}
+	return args, nil
+}
+
+// DumpSchema runs pg_dump --schema-only against the node and returns the
+// SQL, without ownership, privileges, publications or subscriptions, and
+// without psql meta-commands so it can be executed over a connection.
+// pg_dump must be at least as new as the node's PostgreSQL; the PG_DUMP
+// environment variable overrides its path.
+func DumpSchema(ctx context.Context, hostname, internalHostname string, opts Options, filters []string) (string, error) {
+	filterArgs, err := DumpFilterArgs(filters)
+	if err != nil {
+		return "", err
+	}
+	certPath, keyPath := opts.certPaths()
+	host, port := splitHostPort(connectHost(hostname, internalHostname))
+	connStr := fmt.Sprintf("host=%s port=%s dbname=%s user=%s sslmode=require sslcert=%s sslkey=%s connect_timeout=%d",
+		host, port, opts.DBName, opts.User, certPath, keyPath, int(connectTimeout.Seconds()))
+
+	args := []string{"--schema-only", "--no-owner", "--no-privileges", "--no-publications",
+		"--no-subscriptions", "--dbname=" + connStr}
+	for _, schema := range alwaysExcludedSchemas {
+		args = append(args, "--exclude-schema="+schema)
+	}
+	args = append(args, "--exclude-extension=spock")
+	args = append(args, filterArgs...)
+
+	bin := os.Getenv(pgDumpEnv)
+	if bin == "" {
+		bin = "pg_dump"
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("pg_dump of %s: %w: %s", hostname, err, strings.TrimSpace(stderr.String()))
+	}
+	return stripMetaCommands(stdout.String()), nil
+}
+
+// stripMetaCommands drops psql meta-command lines, such as the \restrict
+// lines recent pg_dump versions emit, which the server cannot execute.
+func stripMetaCommands(script string) string {
+	lines := strings.Split(script, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if strings.HasPrefix(line, "\\") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
@@ -4,14 +4,59 @@ package pg
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrNotPrimary is returned when a new pool connection lands on a server
+// that is in recovery, e.g. while a CNPG failover is still propagating
+// to the -rw service.
+var ErrNotPrimary = errors.New("connected server is in recovery, not the primary")
+
+// ErrClientCert is returned when the client certificate cannot be loaded.
+var ErrClientCert = errors.New("load TLS client cert")
+
+// Connection states of a node, from ConnectState.
+const (
+	StateReady       = "ready"
+	StateUnreachable = "unreachable"
+	StateTLS         = "tls"
+	StateAuth        = "auth"
+)
+
+// ConnectState classifies the outcome of connecting to a node: StateReady
+// for a nil error, StateTLS for certificate and handshake failures,
+// StateAuth when the server rejects the role, and StateUnreachable for
+// anything else, such as refused connections, DNS failures and timeouts.
+func ConnectState(err error) string {
+	if err == nil {
+		return StateReady
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && strings.HasPrefix(pgErr.Code, "28") {
+		// Class 28 — invalid authorization specification.
+		return StateAuth
+	}
+	var alertErr tls.AlertError
+	var verifyErr *tls.CertificateVerificationError
+	var headerErr tls.RecordHeaderError
+	if errors.Is(err, ErrClientCert) || errors.As(err, &alertErr) || errors.As(err, &verifyErr) ||
+		errors.As(err, &headerErr) || strings.Contains(err.Error(), "server refused TLS connection") {
+		return StateTLS
+	}
+	return StateUnreachable
+}
+
 const (
 	defaultPort    = 5432
 	connectTimeout = 3 * time.Second
@@ -19,8 +64,39 @@ const (
 	defaultKeyPath  = "/certificates/admin/tls.key"
 )
 
+// Options selects the database, role and client certificate used to
+// connect to a node. Empty certificate paths default to the admin client
+// certificate mounted into the init-spock pod.
+type Options struct {
+	DBName   string
+	User     string
+	CertPath string
+	KeyPath  string
+	Trace    Trace
+	node     string // node named in SQL traces
+}
+
+// ForNode returns o for connecting to the named node, so SQL traces of
+// its pool name it.
+func (o Options) ForNode(name string) Options {
+	o.node = name
+	return o
+}
+
+func (o Options) certPaths() (string, string) {
+	certPath, keyPath := o.CertPath, o.KeyPath
+	if certPath == "" {
+		certPath = defaultCertPath
+	}
+	if keyPath == "" {
+		keyPath = defaultKeyPath
+	}
+	return certPath, keyPath
+}
+
 // connectHost returns the host to use for connectivity checks.
 // Uses internalHostname if set, otherwise falls back to hostname.
+// Either may carry a port as "host:port".
 func connectHost(hostname, internalHostname string) string {
 	if internalHostname != "" {
 		return internalHostname
@@ -28,16 +104,26 @@ func connectHost(hostname, internalHostname string) string {
 	return hostname
 }
 
+// splitHostPort splits an optional ":port" suffix off host, defaulting to
+// the PostgreSQL port.
+func splitHostPort(host string) (string, string) {
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		return h, port
+	}
+	return host, strconv.Itoa(defaultPort)
+}
+
 // buildConnConfig creates a pgx connection config with TLS client certificates.
 func buildConnConfig(host, dbName, user, certPath, keyPath string) (*pgx.ConnConfig, error) {
 	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
 	if err != nil {
-		return nil, fmt.Errorf("load TLS client cert: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrClientCert, err)
 	}
 
+	host, port := splitHostPort(host)
 	connStr := fmt.Sprintf(
-		"host=%s port=%d dbname=%s user=%s sslmode=require connect_timeout=%d",
-		host, defaultPort, dbName, user, int(connectTimeout.Seconds()),
+		"host=%s port=%s dbname=%s user=%s sslmode=require connect_timeout=%d",
+		host, port, dbName, user, int(connectTimeout.Seconds()),
 	)
 	cfg, err := pgx.ParseConfig(connStr)
 	if err != nil {
@@ -54,8 +140,9 @@ func buildConnConfig(host, dbName, user, certPath, keyPath string) (*pgx.ConnCon
 }
 
 // Connect creates a new pgx connection to the given host.
-func Connect(ctx context.Context, host, dbName, user string) (*pgx.Conn, error) {
-	cfg, err := buildConnConfig(host, dbName, user, defaultCertPath, defaultKeyPath)
+func Connect(ctx context.Context, host string, opts Options) (*pgx.Conn, error) {
+	certPath, keyPath := opts.certPaths()
+	cfg, err := buildConnConfig(host, opts.DBName, opts.User, certPath, keyPath)
 	if err != nil {
 		return nil, err
 	}
@@ -74,36 +161,119 @@ func buildPoolConfig(hostname, internalHostname, dbName, user, certPath, keyPath
 		return nil, fmt.Errorf("parse pool config: %w", err)
 	}
 	poolCfg.ConnConfig = connCfg
+	poolCfg.AfterConnect = requirePrimary
 	return poolCfg, nil
 }
 
+// requirePrimary rejects connections to a server in recovery so a pool
+// never hands out a connection to a standby or a demoted former primary.
+// A live connection cannot be demoted in place — CNPG restarts the
+// instance — so checking once per connection is enough.
+func requirePrimary(ctx context.Context, conn *pgx.Conn) error {
+	var inRecovery bool
+	if err := conn.QueryRow(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+		return fmt.Errorf("check recovery state on %s: %w", conn.Config().Host, err)
+	}
+	if inRecovery {
+		return fmt.Errorf("%s: %w", conn.Config().Host, ErrNotPrimary)
+	}
+	return nil
+}
+
+// IsRetryable reports whether err is a transient failure that is expected
+// to clear once a failover completes: lost or refused connections, server
+// shutdowns, writes rejected by a read-only server, and ErrNotPrimary. Lock
+// timeouts are retryable too: the lock holder is expected to finish.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrNotPrimary) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "25006", // read_only_sql_transaction
+			"55P03", // lock_not_available, e.g. lock_timeout
+			"57P01", // admin_shutdown
+			"57P02", // crash_shutdown
+			"57P03": // cannot_connect_now
+			return true
+		}
+		// Class 08 — connection exception.
+		return strings.HasPrefix(pgErr.Code, "08")
+	}
+
+	// A failed connect is retryable even when it timed out: connect_timeout
+	// surfaces as a deadline inside the ConnectError. Callers stop retrying
+	// once their own context is done.
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	return pgconn.SafeToRetry(err)
+}
+
 // ConnectPool creates a new pgxpool connection pool to the node.
 // Uses internalHostname if set, otherwise falls back to hostname.
 // The pool is safe for concurrent use from multiple goroutines.
-func ConnectPool(ctx context.Context, hostname, internalHostname, dbName, user string) (*pgxpool.Pool, error) {
-	poolCfg, err := buildPoolConfig(hostname, internalHostname, dbName, user, defaultCertPath, defaultKeyPath)
+func ConnectPool(ctx context.Context, hostname, internalHostname string, opts Options) (*pgxpool.Pool, error) {
+	certPath, keyPath := opts.certPaths()
+	poolCfg, err := buildPoolConfig(hostname, internalHostname, opts.DBName, opts.User, certPath, keyPath)
 	if err != nil {
 		return nil, err
 	}
+	if opts.Trace.Enabled {
+		node := opts.node
+		if node == "" {
+			node = hostname
+		}
+		poolCfg.ConnConfig.Tracer = &sqlTracer{node: node, trace: opts.Trace}
+	}
 	return pgxpool.NewWithConfig(ctx, poolCfg)
 }
 
-// WaitReady polls until PostgreSQL accepts connections on the node.
-// Uses internalHostname for the check, falls back to hostname.
-func WaitReady(ctx context.Context, hostname, internalHostname, dbName, user string) error {
+// WaitReady polls every interval until PostgreSQL accepts connections on
+// the node, for at most timeout if it is positive. Uses internalHostname
+// for the check, falls back to hostname.
+func WaitReady(ctx context.Context, hostname, internalHostname string, opts Options, timeout, interval time.Duration) error {
 	host := connectHost(hostname, internalHostname)
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	start := time.Now()
 	for {
-		conn, err := Connect(ctx, host, dbName, user)
+		conn, err := Connect(waitCtx, host, opts)
 		if err == nil {
-			conn.Close(ctx)
+			conn.Close(waitCtx)
 			slog.Info("node accepting connections", "hostname", hostname)
 			return nil
 		}
 		slog.Info("waiting for node", "hostname", hostname, "error", err)
 		select {
-		case <-ctx.Done():
-			return fmt.Errorf("timed out waiting for %s: %w", hostname, ctx.Err())
-		case <-time.After(3 * time.Second):
+		case <-waitCtx.Done():
+			if ctx.Err() == nil {
+				return fmt.Errorf("node %s did not accept connections within %s, last error: %w: %w",
+					hostname, timeout, err, waitCtx.Err())
+			}
+			return fmt.Errorf("timed out waiting for %s after %s, last error: %w: %w",
+				hostname, time.Since(start).Round(time.Second), err, ctx.Err())
+		case <-time.After(interval):
 		}
 	}
 }
@@ -2,16 +2,30 @@
 package pg
 
 import (
+	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"math/big"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/pgEdge/pgedge-helm/internal/resource"
 )
 
 func TestBuildConnConfig(t *testing.T) {
@@ -38,6 +52,29 @@ func TestBuildConnConfig(t *testing.T) {
 	}
 }
 
+func TestBuildConnConfigWithPort(t *testing.T) {
+	certPath, keyPath := generateTempCerts(t)
+
+	cfg, err := buildConnConfig("localhost:15432", "app", "admin", certPath, keyPath)
+	if err != nil {
+		t.Fatalf("buildConnConfig: %v", err)
+	}
+	if cfg.Host != "localhost" || cfg.Port != 15432 {
+		t.Errorf("expected localhost:15432, got %s:%d", cfg.Host, cfg.Port)
+	}
+}
+
+func TestOptionsDefaultCertPaths(t *testing.T) {
+	certPath, keyPath := Options{}.certPaths()
+	if certPath != defaultCertPath || keyPath != defaultKeyPath {
+		t.Errorf("expected in-pod defaults, got %q %q", certPath, keyPath)
+	}
+	certPath, keyPath = Options{CertPath: "/tmp/tls.crt", KeyPath: "/tmp/tls.key"}.certPaths()
+	if certPath != "/tmp/tls.crt" || keyPath != "/tmp/tls.key" {
+		t.Errorf("expected explicit paths, got %q %q", certPath, keyPath)
+	}
+}
+
 func TestBuildConnConfigMissingCerts(t *testing.T) {
 	_, err := buildConnConfig("host", "db", "user",
 		"/nonexistent/tls.crt", "/nonexistent/tls.key")
@@ -117,3 +154,141 @@ func generateTempCerts(t *testing.T) (certPath, keyPath string) {
 	}
 	return certPath, keyPath
 }
+
+func TestBuildPoolConfigRequiresPrimary(t *testing.T) {
+	certPath, keyPath := generateTempCerts(t)
+
+	cfg, err := buildPoolConfig("pgedge-n1-rw", "", "app", "admin", certPath, keyPath)
+	if err != nil {
+		t.Fatalf("buildPoolConfig: %v", err)
+	}
+	if cfg.AfterConnect == nil {
+		t.Error("expected AfterConnect to reject standby connections")
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"not primary", fmt.Errorf("acquire: %w", ErrNotPrimary), true},
+		{"read only", &pgconn.PgError{Code: "25006"}, true},
+		{"admin shutdown", &pgconn.PgError{Code: "57P01"}, true},
+		{"lock timeout", fmt.Errorf("drop: %w", &pgconn.PgError{Code: "55P03"}), true},
+		{"statement timeout", &pgconn.PgError{Code: "57014"}, false},
+		{"connection failure", &pgconn.PgError{Code: "08006"}, true},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"syntax error", fmt.Errorf("create: %w", &pgconn.PgError{Code: "42601"}), false},
+		{"unexpected eof", fmt.Errorf("query: %w", io.ErrUnexpectedEOF), true},
+		{"net error", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"canceled", context.Canceled, false},
+		{"plain", errors.New("boom"), false},
+	}
+	for _, tc := range cases {
+		if got := IsRetryable(tc.err); got != tc.want {
+			t.Errorf("%s: IsRetryable = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDumpFilterArgs(t *testing.T) {
+	args, err := DumpFilterArgs([]string{"exclude schema audit", "include table public.orders", "exclude  extension  postgis"})
+	if err != nil {
+		t.Fatalf("DumpFilterArgs: %v", err)
+	}
+	want := []string{"--exclude-schema=audit", "--table=public.orders", "--exclude-extension=postgis"}
+	if fmt.Sprint(args) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", args, want)
+	}
+
+	for _, filter := range []string{"exclude schema", "drop schema audit", "exclude function f", "exclude schema a b"} {
+		if _, err := DumpFilterArgs([]string{filter}); err == nil {
+			t.Errorf("expected an error for %q", filter)
+		}
+	}
+}
+
+func TestStripMetaCommands(t *testing.T) {
+	script := "\\restrict abc\nSET statement_timeout = 0;\nCREATE TABLE t (id int);\n\\unrestrict abc\n"
+	want := "SET statement_timeout = 0;\nCREATE TABLE t (id int);\n"
+	if got := stripMetaCommands(script); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestConnectState(t *testing.T) {
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{nil, StateReady},
+		{&pgconn.PgError{Code: "28000"}, StateAuth},
+		{fmt.Errorf("connect: %w", &pgconn.PgError{Code: "28P01"}), StateAuth},
+		{fmt.Errorf("%w: open tls.key: no such file", ErrClientCert), StateTLS},
+		{fmt.Errorf("handshake: %w", tls.AlertError(42)), StateTLS},
+		{errors.New("server refused TLS connection"), StateTLS},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, StateUnreachable},
+		{context.DeadlineExceeded, StateUnreachable},
+		{&pgconn.PgError{Code: "57P03"}, StateUnreachable},
+	}
+	for _, c := range cases {
+		if got := ConnectState(c.err); got != c.want {
+			t.Errorf("ConnectState(%v) = %q, want %q", c.err, got, c.want)
+		}
+	}
+}
+
+func TestWaitReadyKeepsLastError(t *testing.T) {
+	opts := Options{DBName: "app", User: "admin", CertPath: "/nonexistent/tls.crt", KeyPath: "/nonexistent/tls.key"}
+	err := WaitReady(context.Background(), "pgedge-n1-rw", "", opts, 50*time.Millisecond, 10*time.Millisecond)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if ConnectState(err) != StateTLS {
+		t.Errorf("expected the client certificate error to be kept, got %v", err)
+	}
+}
+
+func TestSQLTracer(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	tracer := &sqlTracer{node: "n1", trace: Trace{Enabled: true, Level: slog.LevelDebug}}
+	ctx := resource.WithIdentifier(context.Background(), resource.Identifier{Type: "spock.subscription", ID: "sub_n1_n2"})
+	ctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{
+		SQL:  "SELECT spock.sub_drop($1)\n\t\t  WHERE true",
+		Args: []any{"sub_n1_n2"},
+	})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: &pgconn.PgError{Code: "55P03", Message: "canceling statement due to lock timeout"}})
+
+	out := buf.String()
+	for _, want := range []string{
+		"level=DEBUG", "msg=\"sql start\"", "msg=\"sql end\"", "node=n1", "resource=spock.subscription/sub_n1_n2",
+		"sql=\"SELECT spock.sub_drop($1) WHERE true\"", "args=1", "sqlstate=55P03", "duration=",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("trace missing %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "args=[sub_n1_n2]") {
+		t.Errorf("arguments not redacted:\n%s", out)
+	}
+
+	buf.Reset()
+	tracer.trace.Args = true
+	tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT $1", Args: []any{"sub_n1_n2"}})
+	if out := buf.String(); !strings.Contains(out, "args=[sub_n1_n2]") || strings.Contains(out, "resource=") {
+		t.Errorf("expected logged arguments and no resource:\n%s", out)
+	}
+}
+
+func TestTraceSQLTruncates(t *testing.T) {
+	sql := traceSQL(strings.Repeat("x", traceSQLMax+10))
+	if len(sql) != traceSQLMax+3 || !strings.HasSuffix(sql, "...") {
+		t.Errorf("expected %d bytes ending in ..., got %d: %q", traceSQLMax+3, len(sql), sql)
+	}
+}
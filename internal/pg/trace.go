@@ -0,0 +1,104 @@
+// internal/pg/trace.go
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/pgEdge/pgedge-helm/internal/resource"
+)
+
+// traceSQLMax bounds the length of a statement in traces; schema copies
+// run whole dumps as one statement.
+const traceSQLMax = 500
+
+// Trace configures the SQL tracing of pools from ConnectPool.
+type Trace struct {
+	Enabled bool
+	Level   slog.Level
+	// Args logs statement arguments. They are redacted by default: they
+	// can carry DSNs and role settings.
+	Args bool
+}
+
+// sqlTracer is a pgx.QueryTracer that logs each statement when it starts
+// and when it ends, with the node, the resource it runs for, its duration
+// and, on failure, its SQLSTATE. A statement that hangs, e.g. in
+// spock.get_lsn_from_commit_ts or a slot advance, shows up as a start
+// without an end.
+type sqlTracer struct {
+	node  string
+	trace Trace
+}
+
+type traceKey struct{}
+
+// traceQuery is the state of a statement between its start and end.
+type traceQuery struct {
+	start time.Time
+	sql   string
+}
+
+func (t *sqlTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	q := &traceQuery{start: time.Now(), sql: traceSQL(data.SQL)}
+	attrs := append(t.attrs(ctx, q), t.args(data.Args))
+	slog.Default().LogAttrs(ctx, t.trace.Level, "sql start", attrs...)
+	return context.WithValue(ctx, traceKey{}, q)
+}
+
+func (t *sqlTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	q, ok := ctx.Value(traceKey{}).(*traceQuery)
+	if !ok {
+		return
+	}
+	attrs := append(t.attrs(ctx, q), slog.Duration("duration", time.Since(q.start)))
+	if data.Err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(data.Err, &pgErr) {
+			attrs = append(attrs, slog.String("sqlstate", pgErr.Code))
+		}
+		attrs = append(attrs, slog.Any("error", data.Err))
+	} else {
+		attrs = append(attrs, slog.String("tag", data.CommandTag.String()))
+	}
+	slog.Default().LogAttrs(ctx, t.trace.Level, "sql end", attrs...)
+}
+
+// attrs returns the attributes common to both log lines of a statement.
+func (t *sqlTracer) attrs(ctx context.Context, q *traceQuery) []slog.Attr {
+	attrs := []slog.Attr{slog.String("node", t.node)}
+	if id, ok := resource.IdentifierFrom(ctx); ok {
+		attrs = append(attrs, slog.String("resource", id.Type+"/"+id.ID))
+	}
+	return append(attrs, slog.String("sql", q.sql))
+}
+
+// args returns the statement's arguments, or only their number unless
+// t.trace.Args is set.
+func (t *sqlTracer) args(args []any) slog.Attr {
+	if !t.trace.Args {
+		return slog.Int("args", len(args))
+	}
+	values := make([]string, len(args))
+	for i, arg := range args {
+		values[i] = fmt.Sprint(arg)
+	}
+	return slog.Any("args", values)
+}
+
+// traceSQL collapses the whitespace of a statement onto one line and
+// truncates it to traceSQLMax bytes.
+func traceSQL(sql string) string {
+	sql = strings.Join(strings.Fields(sql), " ")
+	if len(sql) > traceSQLMax {
+		return sql[:traceSQLMax] + "..."
+	}
+	return sql
+}
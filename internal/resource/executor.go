@@ -5,40 +5,25 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 )
 
 // Execute runs plan phases sequentially, parallelizing events within each phase.
-func Execute(ctx context.Context, phases [][]Event) error {
+func Execute(ctx context.Context, phases [][]Event, opts ...Option) error {
+	o := buildOptions(opts)
+	o.planned(phases)
 	for i, phase := range phases {
 		slog.Info("executing phase", "phase", i, "events", len(phase))
 
 		g, ctx := errgroup.WithContext(ctx)
 		for _, event := range phase {
 			g.Go(func() error {
-				id := event.Resource.Identifier()
-				switch event.Action {
-				case ActionCreate:
-					slog.Info("creating resource", "type", id.Type, "id", id.ID)
-					if err := event.Resource.Create(ctx); err != nil {
-						return fmt.Errorf("create %s/%s: %w", id.Type, id.ID, err)
-					}
-				case ActionUpdate:
-					slog.Info("updating resource", "type", id.Type, "id", id.ID)
-					if err := event.Resource.Update(ctx); err != nil {
-						return fmt.Errorf("update %s/%s: %w", id.Type, id.ID, err)
-					}
-				case ActionDelete:
-					slog.Info("deleting resource", "type", id.Type, "id", id.ID)
-					if err := event.Resource.Delete(ctx); err != nil {
-						return fmt.Errorf("delete %s/%s: %w", id.Type, id.ID, err)
-					}
-				default:
-					slog.Error("unsupported action", "action", event.Action, "type", id.Type, "id", id.ID)
-					return fmt.Errorf("unsupported action %d for %s/%s", event.Action, id.Type, id.ID)
-				}
-				return nil
+				start := time.Now()
+				err := executeEvent(o.withProgress(ctx, event), event, o)
+				o.finished(event, time.Since(start), err)
+				return err
 			})
 		}
 		if err := g.Wait(); err != nil {
@@ -47,3 +32,30 @@ func Execute(ctx context.Context, phases [][]Event) error {
 	}
 	return nil
 }
+
+// executeEvent applies a single event, retrying transient failures per o.
+func executeEvent(ctx context.Context, event Event, o options) error {
+	id := event.Resource.Identifier()
+	ctx = WithIdentifier(ctx, id)
+	switch event.Action {
+	case ActionCreate:
+		slog.Info("creating resource", "type", id.Type, "id", id.ID)
+		if err := o.retry.do(ctx, "create "+id.Type+"/"+id.ID, event.Resource.Create); err != nil {
+			return fmt.Errorf("create %s/%s: %w", id.Type, id.ID, err)
+		}
+	case ActionUpdate:
+		slog.Info("updating resource", "type", id.Type, "id", id.ID)
+		if err := o.retry.do(ctx, "update "+id.Type+"/"+id.ID, event.Resource.Update); err != nil {
+			return fmt.Errorf("update %s/%s: %w", id.Type, id.ID, err)
+		}
+	case ActionDelete:
+		slog.Info("deleting resource", "type", id.Type, "id", id.ID)
+		if err := o.retry.do(ctx, "delete "+id.Type+"/"+id.ID, event.Resource.Delete); err != nil {
+			return fmt.Errorf("delete %s/%s: %w", id.Type, id.ID, err)
+		}
+	default:
+		slog.Error("unsupported action", "action", event.Action, "type", id.Type, "id", id.ID)
+		return fmt.Errorf("unsupported action %d for %s/%s", event.Action, id.Type, id.ID)
+	}
+	return nil
+}
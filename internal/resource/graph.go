@@ -0,0 +1,226 @@
+// internal/resource/graph.go
+package resource
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Graph output formats.
+const (
+	GraphDOT     = "dot"
+	GraphMermaid = "mermaid"
+)
+
+// Resource states shown in a graph. Without a refresh the state is unknown
+// and nodes are left uncolored.
+const (
+	stateUnknown   = ""
+	stateExists    = "exists"
+	stateMissing   = "missing"
+	stateUpdate    = "update"
+	stateRecreate  = "recreate"
+	stateUnhealthy = "unhealthy"
+	stateOrphan    = "orphan"
+)
+
+var stateColors = map[string]string{
+	stateUnknown:   "#ffffff",
+	stateExists:    "#c8e6c9",
+	stateMissing:   "#bbdefb",
+	stateUpdate:    "#fff59d",
+	stateRecreate:  "#ffcc80",
+	stateUnhealthy: "#ce93d8",
+	stateOrphan:    "#ef9a9a",
+}
+
+// graphNode is one resource in a rendered graph.
+type graphNode struct {
+	id    Identifier
+	deps  []Identifier
+	state string
+	steps []string // e.g. "1:delete", "4:create"
+	phase int      // first phase the resource acts in, 0 for none
+}
+
+func (n graphNode) label() []string {
+	lines := []string{n.id.Type, n.id.ID}
+	if n.state != stateUnknown {
+		lines = append(lines, n.state)
+	}
+	if len(n.steps) > 0 {
+		lines = append(lines, "phase "+strings.Join(n.steps, ", "))
+	}
+	return lines
+}
+
+// WriteGraph renders the dependency graph of the desired resources, and
+// of orphans in actual, in the given format. Edges point from a
+// dependency to its dependent. Resources are grouped by the phase of
+// plan they first act in and labeled with every step they take. With a
+// nil actual map, resources are not colored by state.
+func WriteGraph(w io.Writer, format string, desired, actual map[Identifier]Resource, plan [][]Event) error {
+	nodes := graphNodes(desired, actual, plan)
+	switch format {
+	case GraphDOT:
+		return writeDOT(w, nodes, len(plan))
+	case GraphMermaid:
+		return writeMermaid(w, nodes, len(plan))
+	}
+	return fmt.Errorf("unknown graph format %q", format)
+}
+
+func graphNodes(desired, actual map[Identifier]Resource, plan [][]Event) []graphNode {
+	byID := make(map[Identifier]*graphNode)
+	add := func(r Resource, state string) {
+		byID[r.Identifier()] = &graphNode{id: r.Identifier(), deps: r.Dependencies(), state: state}
+	}
+	for id, r := range desired {
+		state := stateUnknown
+		if actual != nil {
+			state = stateMissing
+			if _, ok := actual[id]; ok {
+				state = stateExists
+				if s := r.Status(); s.NeedsRecreate {
+					state = stateRecreate
+				} else if s.NeedsUpdate {
+					state = stateUpdate
+				} else if s.Unhealthy {
+					state = stateUnhealthy
+				}
+			}
+		}
+		add(r, state)
+	}
+	for id, r := range actual {
+		if _, ok := desired[id]; !ok {
+			add(r, stateOrphan)
+		}
+	}
+
+	for i, phase := range plan {
+		for _, e := range phase {
+			n, ok := byID[e.Resource.Identifier()]
+			if !ok {
+				continue
+			}
+			if n.phase == 0 {
+				n.phase = i + 1
+			}
+			n.steps = append(n.steps, fmt.Sprintf("%d:%s", i+1, e.Action))
+		}
+	}
+
+	nodes := make([]graphNode, 0, len(byID))
+	for _, n := range byID {
+		nodes = append(nodes, *n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].id.Type != nodes[j].id.Type {
+			return nodes[i].id.Type < nodes[j].id.Type
+		}
+		return nodes[i].id.ID < nodes[j].id.ID
+	})
+	return nodes
+}
+
+// edges returns the dependency edges between nodes of the graph as index
+// pairs (dependency, dependent).
+func edges(nodes []graphNode) [][2]int {
+	index := make(map[Identifier]int, len(nodes))
+	for i, n := range nodes {
+		index[n.id] = i
+	}
+	var out [][2]int
+	for i, n := range nodes {
+		for _, dep := range n.deps {
+			if j, ok := index[dep]; ok {
+				out = append(out, [2]int{j, i})
+			}
+		}
+	}
+	return out
+}
+
+func writeDOT(w io.Writer, nodes []graphNode, phases int) error {
+	var b strings.Builder
+	quote := func(s string) string {
+		return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+	}
+	node := func(indent string, i int, n graphNode) {
+		fmt.Fprintf(&b, "%sn%d [label=%s, fillcolor=%s];\n",
+			indent, i, quote(strings.Join(n.label(), `\n`)), quote(stateColors[n.state]))
+	}
+
+	b.WriteString("digraph resources {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+	for p := 1; p <= phases; p++ {
+		fmt.Fprintf(&b, "  subgraph cluster_phase%d {\n    label=\"Phase %d\";\n", p, p)
+		for i, n := range nodes {
+			if n.phase == p {
+				node("    ", i, n)
+			}
+		}
+		b.WriteString("  }\n")
+	}
+	for i, n := range nodes {
+		if n.phase == 0 {
+			node("  ", i, n)
+		}
+	}
+	for _, e := range edges(nodes) {
+		fmt.Fprintf(&b, "  n%d -> n%d;\n", e[0], e[1])
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMermaid(w io.Writer, nodes []graphNode, phases int) error {
+	var b strings.Builder
+	node := func(indent string, i int, n graphNode) {
+		label := strings.ReplaceAll(strings.Join(n.label(), "<br/>"), `"`, "#quot;")
+		fmt.Fprintf(&b, "%sn%d[\"%s\"]\n", indent, i, label)
+	}
+
+	b.WriteString("flowchart LR\n")
+	for p := 1; p <= phases; p++ {
+		fmt.Fprintf(&b, "  subgraph phase%d[\"Phase %d\"]\n", p, p)
+		for i, n := range nodes {
+			if n.phase == p {
+				node("    ", i, n)
+			}
+		}
+		b.WriteString("  end\n")
+	}
+	for i, n := range nodes {
+		if n.phase == 0 {
+			node("  ", i, n)
+		}
+	}
+	for _, e := range edges(nodes) {
+		fmt.Fprintf(&b, "  n%d --> n%d\n", e[0], e[1])
+	}
+
+	// Color by state; skip classes for states that do not occur.
+	byState := map[string][]string{}
+	for i, n := range nodes {
+		if n.state != stateUnknown {
+			byState[n.state] = append(byState[n.state], fmt.Sprintf("n%d", i))
+		}
+	}
+	states := make([]string, 0, len(byState))
+	for state := range byState {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	for _, state := range states {
+		fmt.Fprintf(&b, "  classDef %s fill:%s\n", state, stateColors[state])
+		fmt.Fprintf(&b, "  class %s %s\n", strings.Join(byState[state], ","), state)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,66 @@
+// internal/resource/observer.go
+package resource
+
+import (
+	"context"
+	"time"
+)
+
+// Observer is notified as a reconciliation progresses. Finished may be
+// called concurrently for events in the same phase.
+type Observer interface {
+	// Planned is called once with the phases about to be executed.
+	Planned(phases [][]Event)
+	// Finished is called after each event, with its error if it failed.
+	Finished(event Event, elapsed time.Duration, err error)
+}
+
+// Progress describes how far a long-running action has come.
+type Progress struct {
+	Message string
+	Done    int           // units of work completed, e.g. tables
+	Total   int           // units of work in total, 0 if unknown
+	Bytes   int64         // bytes processed so far
+	ETA     time.Duration // estimated time remaining, 0 if unknown
+}
+
+// ProgressObserver is an Observer that is also told about the progress
+// long-running actions report with ReportProgress.
+type ProgressObserver interface {
+	Observer
+	Progress(event Event, p Progress)
+}
+
+type progressKey struct{}
+
+// ReportProgress passes the progress of the action running with ctx to the
+// ProgressObservers of its reconciliation. It does nothing outside Execute.
+func ReportProgress(ctx context.Context, p Progress) {
+	if report, ok := ctx.Value(progressKey{}).(func(Progress)); ok {
+		report(p)
+	}
+}
+
+// withProgress returns ctx with progress reports for event routed to o's
+// ProgressObservers.
+func (o options) withProgress(ctx context.Context, event Event) context.Context {
+	return context.WithValue(ctx, progressKey{}, func(p Progress) {
+		for _, obs := range o.observers {
+			if po, ok := obs.(ProgressObserver); ok {
+				po.Progress(event, p)
+			}
+		}
+	})
+}
+
+func (o options) planned(phases [][]Event) {
+	for _, obs := range o.observers {
+		obs.Planned(phases)
+	}
+}
+
+func (o options) finished(event Event, elapsed time.Duration, err error) {
+	for _, obs := range o.observers {
+		obs.Finished(event, elapsed, err)
+	}
+}
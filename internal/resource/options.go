@@ -0,0 +1,35 @@
+// internal/resource/options.go
+package resource
+
+import "time"
+
+// Option configures Reconcile and Execute.
+type Option func(*options)
+
+type options struct {
+	retry     RetryPolicy
+	observers []Observer
+}
+
+// WithRetry retries actions whose error satisfies retryable, up to attempts
+// tries in total, starting at backoff and doubling between tries.
+func WithRetry(retryable func(error) bool, attempts int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.retry = RetryPolicy{Retryable: retryable, Attempts: attempts, Backoff: backoff}
+	}
+}
+
+// WithObserver registers an Observer for the plan and each executed event.
+func WithObserver(obs Observer) Option {
+	return func(o *options) {
+		o.observers = append(o.observers, obs)
+	}
+}
+
+func buildOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
@@ -1,15 +1,28 @@
 // internal/resource/plan.go
 package resource
 
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrCycle is returned by Plan when the events of a plan depend on each
+// other in a cycle, so no order satisfies every dependency.
+var ErrCycle = errors.New("dependency cycle")
+
 // Plan computes the diff between actual and desired resource maps.
 // Returns topologically-sorted phases of events.
-// Creates are ordered so dependencies come first.
+// Creates and updates are ordered together so dependencies come first: an
+// update waits for the creation of what it depends on.
 // Deletes are ordered so dependents are deleted before their dependencies.
 // Recreates (NeedsRecreate) produce a Delete phase followed by a Create phase.
-func Plan(actual, desired map[Identifier]Resource) [][]Event {
+// A dependency cycle among the deletes, or among the creates and updates,
+// is an ErrCycle.
+func Plan(actual, desired map[Identifier]Resource) ([][]Event, error) {
 	var deletes []Event
 	var creates []Event
-	var updates []Event
 
 	// Resources in actual but not desired → delete
 	for id, r := range actual {
@@ -26,7 +39,7 @@ func Plan(actual, desired map[Identifier]Resource) [][]Event {
 				deletes = append(deletes, Event{Action: ActionDelete, Resource: r})
 				creates = append(creates, Event{Action: ActionCreate, Resource: r})
 			} else if s.Exists && s.NeedsUpdate {
-				updates = append(updates, Event{Action: ActionUpdate, Resource: r})
+				creates = append(creates, Event{Action: ActionUpdate, Resource: r})
 			}
 			// Exists and healthy → no-op
 			continue
@@ -39,28 +52,30 @@ func Plan(actual, desired map[Identifier]Resource) [][]Event {
 
 	// Delete phases (reverse dependency order — dependents first)
 	if len(deletes) > 0 {
-		deletePhases := topoSort(deletes, true)
+		deletePhases, err := topoSort(deletes, true)
+		if err != nil {
+			return nil, fmt.Errorf("plan deletes: %w", err)
+		}
 		phases = append(phases, deletePhases...)
 	}
 
-	// Update phases (dependency order — dependencies first)
-	if len(updates) > 0 {
-		updatePhases := topoSort(updates, false)
-		phases = append(phases, updatePhases...)
-	}
-
-	// Create phases (dependency order — dependencies first)
+	// Create and update phases (dependency order — dependencies first)
 	if len(creates) > 0 {
-		createPhases := topoSort(creates, false)
+		createPhases, err := topoSort(creates, false)
+		if err != nil {
+			return nil, fmt.Errorf("plan creates and updates: %w", err)
+		}
 		phases = append(phases, createPhases...)
 	}
 
-	return phases
+	return phases, nil
 }
 
 // topoSort orders events into phases respecting dependencies.
 // If reverse=true, dependents come before dependencies (for deletes).
-func topoSort(events []Event, reverse bool) [][]Event {
+// It fails with ErrCycle, naming the events left unordered, if the
+// dependencies form a cycle.
+func topoSort(events []Event, reverse bool) ([][]Event, error) {
 	eventSet := make(map[Identifier]Event)
 	for _, e := range events {
 		eventSet[e.Resource.Identifier()] = e
@@ -103,13 +118,13 @@ func topoSort(events []Event, reverse bool) [][]Event {
 			}
 		}
 		if len(ready) == 0 {
-			// Cycle detected — emit remaining as a single phase
-			var remaining []Event
+			// Every remaining event is in a cycle or depends on one.
+			remaining := make([]string, 0, len(inDegree))
 			for id := range inDegree {
-				remaining = append(remaining, eventSet[id])
+				remaining = append(remaining, id.Type+"/"+id.ID)
 			}
-			phases = append(phases, remaining)
-			break
+			slices.Sort(remaining)
+			return nil, fmt.Errorf("%w among %s", ErrCycle, strings.Join(remaining, ", "))
 		}
 
 		phase := make([]Event, 0, len(ready))
@@ -123,5 +138,5 @@ func topoSort(events []Event, reverse bool) [][]Event {
 		phases = append(phases, phase)
 	}
 
-	return phases
+	return phases, nil
 }
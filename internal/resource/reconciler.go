@@ -1,7 +1,10 @@
 // internal/resource/reconciler.go
 package resource
 
-import "context"
+import (
+	"context"
+	"log/slog"
+)
 
 // Reconciler provides the desired and actual resource state for a domain.
 type Reconciler interface {
@@ -11,12 +14,26 @@ type Reconciler interface {
 
 // Reconcile drives a full reconciliation cycle: compute desired state,
 // refresh actual state, plan the diff, and execute it.
-func Reconcile(ctx context.Context, r Reconciler) error {
+func Reconcile(ctx context.Context, r Reconciler, opts ...Option) error {
+	o := buildOptions(opts)
 	desired := r.ComputeDesired()
-	actual, err := r.RefreshActual(ctx, desired)
+	var actual map[Identifier]Resource
+	err := o.retry.do(ctx, "refresh", func(ctx context.Context) error {
+		var err error
+		actual, err = r.RefreshActual(ctx, desired)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for id, r := range desired {
+		if s := r.Status(); s.Unhealthy {
+			slog.Warn("resource unhealthy", "type", id.Type, "id", id.ID, "reason", s.Reason)
+		}
+	}
+	plan, err := Plan(actual, desired)
 	if err != nil {
 		return err
 	}
-	plan := Plan(actual, desired)
-	return Execute(ctx, plan)
+	return Execute(ctx, plan, opts...)
 }
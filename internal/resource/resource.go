@@ -1,7 +1,10 @@
 // internal/resource/resource.go
 package resource
 
-import "context"
+import (
+	"context"
+	"fmt"
+)
 
 // Identifier uniquely identifies a resource by type and ID.
 type Identifier struct {
@@ -9,12 +12,31 @@ type Identifier struct {
 	ID   string
 }
 
+type identifierKey struct{}
+
+// WithIdentifier returns ctx marked as running on behalf of the resource
+// id, so that lower layers such as the SQL tracer can attribute their work.
+func WithIdentifier(ctx context.Context, id Identifier) context.Context {
+	return context.WithValue(ctx, identifierKey{}, id)
+}
+
+// IdentifierFrom returns the resource identifier WithIdentifier stored in
+// ctx, if any.
+func IdentifierFrom(ctx context.Context) (Identifier, bool) {
+	id, ok := ctx.Value(identifierKey{}).(Identifier)
+	return id, ok
+}
+
 // Status represents the inspected state of a resource.
 type Status struct {
 	Exists        bool
 	NeedsRecreate bool
 	NeedsUpdate   bool
-	Reason        string
+	// Unhealthy marks a resource that exists but is failing in a way the
+	// plan does not fix, e.g. a subscription stopped by an apply error.
+	// It produces no event; Reconcile reports it.
+	Unhealthy bool
+	Reason    string
 }
 
 // Resource is the core abstraction for a managed object.
@@ -37,6 +59,18 @@ const (
 	ActionDelete
 )
 
+func (a Action) String() string {
+	switch a {
+	case ActionCreate:
+		return "create"
+	case ActionUpdate:
+		return "update"
+	case ActionDelete:
+		return "delete"
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
 // Event pairs an action with the resource it applies to.
 type Event struct {
 	Action   Action
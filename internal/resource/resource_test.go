@@ -3,9 +3,13 @@ package resource
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 // mockResource implements Resource for testing.
@@ -49,6 +53,15 @@ func id(typ, name string) Identifier {
 	return Identifier{Type: typ, ID: name}
 }
 
+func mustPlan(t *testing.T, actual, desired map[Identifier]Resource) [][]Event {
+	t.Helper()
+	plan, err := Plan(actual, desired)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	return plan
+}
+
 func TestPlanFreshInstall(t *testing.T) {
 	desired := map[Identifier]Resource{
 		id("user", "n1"):  &mockResource{id: id("user", "n1"), status: Status{Exists: false}},
@@ -59,7 +72,7 @@ func TestPlanFreshInstall(t *testing.T) {
 	}
 	actual := map[Identifier]Resource{}
 
-	events := Plan(actual, desired)
+	events := mustPlan(t, actual, desired)
 	if len(events) == 0 {
 		t.Fatal("expected events for fresh install, got none")
 	}
@@ -79,7 +92,7 @@ func TestPlanNoOp(t *testing.T) {
 	desired := map[Identifier]Resource{id("node", "n1"): r}
 	actual := map[Identifier]Resource{id("node", "n1"): r}
 
-	events := Plan(actual, desired)
+	events := mustPlan(t, actual, desired)
 	total := 0
 	for _, phase := range events {
 		total += len(phase)
@@ -94,7 +107,7 @@ func TestPlanDeleteRemovedResource(t *testing.T) {
 	desired := map[Identifier]Resource{}
 	actual := map[Identifier]Resource{id("node", "n3"): r}
 
-	events := Plan(actual, desired)
+	events := mustPlan(t, actual, desired)
 	total := 0
 	for _, phase := range events {
 		for _, e := range phase {
@@ -114,7 +127,7 @@ func TestPlanRecreate(t *testing.T) {
 	desired := map[Identifier]Resource{id("node", "n1"): r}
 	actual := map[Identifier]Resource{id("node", "n1"): r}
 
-	events := Plan(actual, desired)
+	events := mustPlan(t, actual, desired)
 	actions := []Action{}
 	for _, phase := range events {
 		for _, e := range phase {
@@ -137,7 +150,7 @@ func TestPlanDependencyOrder(t *testing.T) {
 	}
 	actual := map[Identifier]Resource{}
 
-	events := Plan(actual, desired)
+	events := mustPlan(t, actual, desired)
 	if len(events) < 2 {
 		t.Fatalf("expected at least 2 phases for dependency ordering, got %d", len(events))
 	}
@@ -209,7 +222,7 @@ func TestPlanOrphanNodesPerSurvivor(t *testing.T) {
 	}
 	desired := map[Identifier]Resource{}
 
-	phases := Plan(actual, desired)
+	phases := mustPlan(t, actual, desired)
 
 	var deleteCount int
 	for _, phase := range phases {
@@ -223,3 +236,480 @@ func TestPlanOrphanNodesPerSurvivor(t *testing.T) {
 		t.Errorf("expected 2 delete events for orphan n3 on both survivors, got %d", deleteCount)
 	}
 }
+
+// flakyResource fails Create with err for its first failures calls.
+type flakyResource struct {
+	mockResource
+	failures int
+	calls    int
+	err      error
+}
+
+func (f *flakyResource) Create(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	f.createCalled = true
+	return nil
+}
+
+var errTransient = errors.New("transient")
+
+func isTransient(err error) bool { return errors.Is(err, errTransient) }
+
+func TestExecuteRetriesTransientErrors(t *testing.T) {
+	r := &flakyResource{mockResource: mockResource{id: id("node", "n1")}, failures: 2, err: errTransient}
+	plan := [][]Event{{{Action: ActionCreate, Resource: r}}}
+
+	err := Execute(context.Background(), plan, WithRetry(isTransient, 3, time.Millisecond))
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if r.calls != 3 || !r.createCalled {
+		t.Errorf("expected success on third attempt, got %d calls", r.calls)
+	}
+}
+
+func TestExecuteRetryGivesUpAfterAttempts(t *testing.T) {
+	r := &flakyResource{mockResource: mockResource{id: id("node", "n1")}, failures: 5, err: errTransient}
+	plan := [][]Event{{{Action: ActionCreate, Resource: r}}}
+
+	err := Execute(context.Background(), plan, WithRetry(isTransient, 3, time.Millisecond))
+	if !errors.Is(err, errTransient) {
+		t.Fatalf("expected transient error after exhausting attempts, got %v", err)
+	}
+	if r.calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", r.calls)
+	}
+}
+
+func TestExecuteDoesNotRetryPermanentErrors(t *testing.T) {
+	r := &flakyResource{mockResource: mockResource{id: id("node", "n1")}, failures: 5, err: errors.New("permanent")}
+	plan := [][]Event{{{Action: ActionCreate, Resource: r}}}
+
+	if err := Execute(context.Background(), plan, WithRetry(isTransient, 3, time.Millisecond)); err == nil {
+		t.Fatal("expected error from Execute")
+	}
+	if r.calls != 1 {
+		t.Errorf("expected a single attempt for a permanent error, got %d", r.calls)
+	}
+}
+
+type progressResource struct {
+	mockResource
+}
+
+func (p *progressResource) Create(ctx context.Context) error {
+	ReportProgress(ctx, Progress{Message: "copying", Done: 1, Total: 2})
+	return nil
+}
+
+type progressObserver struct {
+	progress []Progress
+}
+
+func (o *progressObserver) Planned([][]Event)                    {}
+func (o *progressObserver) Finished(Event, time.Duration, error) {}
+func (o *progressObserver) Progress(_ Event, p Progress)         { o.progress = append(o.progress, p) }
+
+func TestExecuteReportsProgress(t *testing.T) {
+	r := &progressResource{mockResource{id: id("node", "n1")}}
+	obs := &progressObserver{}
+	plan := [][]Event{{{Action: ActionCreate, Resource: r}}}
+
+	if err := Execute(context.Background(), plan, WithObserver(obs)); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if len(obs.progress) != 1 || obs.progress[0].Done != 1 || obs.progress[0].Total != 2 {
+		t.Errorf("expected one progress report of 1/2, got %+v", obs.progress)
+	}
+	// Outside Execute, reports go nowhere.
+	ReportProgress(context.Background(), Progress{})
+}
+
+type identifierResource struct {
+	mockResource
+	got Identifier
+}
+
+func (r *identifierResource) Create(ctx context.Context) error {
+	r.got, _ = IdentifierFrom(ctx)
+	return nil
+}
+
+func TestExecuteSetsIdentifier(t *testing.T) {
+	r := &identifierResource{mockResource: mockResource{id: id("node", "n1")}}
+	plan := [][]Event{{{Action: ActionCreate, Resource: r}}}
+
+	if err := Execute(context.Background(), plan); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if r.got != id("node", "n1") {
+		t.Errorf("expected the context to carry node/n1, got %+v", r.got)
+	}
+	if _, ok := IdentifierFrom(context.Background()); ok {
+		t.Error("expected no identifier outside Execute")
+	}
+}
+
+func TestWriteGraph(t *testing.T) {
+	user := &mockResource{id: id("user", "n1"), status: Status{Exists: true}}
+	node := &mockResource{id: id("node", "n1"), deps: []Identifier{id("user", "n1")}, status: Status{Exists: true, NeedsRecreate: true}}
+	sub := &mockResource{id: id("sub", "n1n2"), deps: []Identifier{id("node", "n1")}}
+	orphan := &mockResource{id: id("sub", "n1n3"), status: Status{Exists: true}}
+	failing := &mockResource{id: id("sub", "n2n1"), status: Status{Exists: true, Unhealthy: true}}
+	desired := map[Identifier]Resource{user.id: user, node.id: node, sub.id: sub, failing.id: failing}
+	actual := map[Identifier]Resource{user.id: user, node.id: node, orphan.id: orphan, failing.id: failing}
+	plan := mustPlan(t, actual, desired)
+
+	var dot strings.Builder
+	if err := WriteGraph(&dot, GraphDOT, desired, actual, plan); err != nil {
+		t.Fatal(err)
+	}
+	out := dot.String()
+	for _, want := range []string{
+		"digraph resources {",
+		`subgraph cluster_phase1 {`,
+		`label="Phase 1";`,
+		`node\nn1\nrecreate\nphase 1:delete, 2:create`,
+		`sub\nn1n3\norphan\nphase 1:delete`,
+		`sub\nn1n2\nmissing\nphase 3:create`,
+		`user\nn1\nexists"`,
+		`sub\nn2n1\nunhealthy"`,
+		"n0 -> n1;", // node/n1 → sub/n1n2
+		"n4 -> n0;", // user/n1 → node/n1
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("DOT output missing %q:\n%s", want, out)
+		}
+	}
+
+	var mermaid strings.Builder
+	if err := WriteGraph(&mermaid, GraphMermaid, desired, nil, mustPlan(t, nil, desired)); err != nil {
+		t.Fatal(err)
+	}
+	out = mermaid.String()
+	for _, want := range []string{
+		"flowchart LR",
+		`subgraph phase1["Phase 1"]`,
+		`n3["user<br/>n1<br/>phase 1:create"]`,
+		"n3 --> n0",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Mermaid output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "classDef") {
+		t.Errorf("expected no state colors without a refresh:\n%s", out)
+	}
+
+	if err := WriteGraph(&mermaid, "svg", desired, nil, nil); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+// randomCases is how many graphs the randomized tests generate. Each case
+// is a subtest named after its seed, e.g. -run 'TestPlanRandomGraphs/seed=17'
+// reproduces a failure.
+const randomCases = 300
+
+// randomState is the state a generated resource is in.
+type randomState int
+
+const (
+	randomMissing  randomState = iota // desired only: created
+	randomExists                      // up to date: no event
+	randomUpdate                      // NeedsUpdate: updated
+	randomRecreate                    // NeedsRecreate: deleted, then created
+	randomOrphan                      // actual only: deleted
+)
+
+// randomResource is a generated resource. Its actions record themselves
+// in ran and fail if fail is set.
+type randomResource struct {
+	mockResource
+	state randomState
+	fail  bool
+	ran   *eventLog
+}
+
+func (r *randomResource) Create(context.Context) error { return r.ran.record(ActionCreate, r) }
+func (r *randomResource) Update(context.Context) error { return r.ran.record(ActionUpdate, r) }
+func (r *randomResource) Delete(context.Context) error { return r.ran.record(ActionDelete, r) }
+
+type eventKey struct {
+	action Action
+	id     Identifier
+}
+
+func (k eventKey) String() string { return k.action.String() + " " + k.id.ID }
+
+// eventLog records the actions Execute ran and which of them failed.
+type eventLog struct {
+	mu     sync.Mutex
+	ran    map[eventKey]int
+	failed map[eventKey]bool
+}
+
+func (l *eventLog) record(action Action, r *randomResource) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	k := eventKey{action, r.id}
+	l.ran[k]++
+	if r.fail {
+		l.failed[k] = true
+		return errors.New("injected failure")
+	}
+	return nil
+}
+
+// randomGraph generates up to 15 resources in random states. Each depends
+// on a random subset of the resources generated before it, so the graph
+// is acyclic.
+func randomGraph(rng *rand.Rand) (resources []*randomResource, actual, desired map[Identifier]Resource) {
+	actual, desired = map[Identifier]Resource{}, map[Identifier]Resource{}
+	for i := range 1 + rng.IntN(15) {
+		r := &randomResource{
+			mockResource: mockResource{id: id(fmt.Sprintf("t%d", i%3), fmt.Sprintf("r%d", i))},
+			state:        randomState(rng.IntN(5)),
+		}
+		for _, dep := range resources {
+			if rng.IntN(3) == 0 {
+				r.deps = append(r.deps, dep.id)
+			}
+		}
+		switch r.state {
+		case randomExists, randomOrphan:
+			r.status = Status{Exists: true}
+		case randomUpdate:
+			r.status = Status{Exists: true, NeedsUpdate: true}
+		case randomRecreate:
+			r.status = Status{Exists: true, NeedsRecreate: true}
+		}
+		if r.state != randomOrphan {
+			desired[r.id] = r
+		}
+		if r.state != randomMissing {
+			actual[r.id] = r
+		}
+		resources = append(resources, r)
+	}
+	return resources, actual, desired
+}
+
+// wantEvents returns the actions a resource in state needs.
+func wantEvents(state randomState) []Action {
+	switch state {
+	case randomMissing:
+		return []Action{ActionCreate}
+	case randomUpdate:
+		return []Action{ActionUpdate}
+	case randomRecreate:
+		return []Action{ActionDelete, ActionCreate}
+	case randomOrphan:
+		return []Action{ActionDelete}
+	}
+	return nil
+}
+
+// mustPrecede reports whether event a of a dependency must finish before
+// event b of its dependent: deletes run dependents first, creates and
+// updates dependencies first.
+func mustPrecede(a, b Action) bool {
+	if a == ActionDelete || b == ActionDelete {
+		return false
+	}
+	return true
+}
+
+// phaseIndex maps each event of a plan to its phase, failing on an event
+// scheduled twice.
+func phaseIndex(t *testing.T, plan [][]Event) map[eventKey]int {
+	t.Helper()
+	phaseOf := map[eventKey]int{}
+	for i, phase := range plan {
+		for _, e := range phase {
+			k := eventKey{e.Action, e.Resource.Identifier()}
+			if _, dup := phaseOf[k]; dup {
+				t.Errorf("%v scheduled twice", k)
+			}
+			phaseOf[k] = i
+		}
+	}
+	return phaseOf
+}
+
+func TestPlanRandomGraphs(t *testing.T) {
+	for seed := range uint64(randomCases) {
+		t.Run(fmt.Sprintf("seed=%d", seed), func(t *testing.T) {
+			resources, actual, desired := randomGraph(rand.New(rand.NewPCG(seed, 0)))
+			plan, err := Plan(actual, desired)
+			if err != nil {
+				t.Fatalf("Plan of an acyclic graph: %v", err)
+			}
+			phaseOf := phaseIndex(t, plan)
+
+			// Exactly the events the states call for.
+			want := 0
+			for _, r := range resources {
+				for _, action := range wantEvents(r.state) {
+					want++
+					if _, ok := phaseOf[eventKey{action, r.id}]; !ok {
+						t.Errorf("%v missing from the plan", eventKey{action, r.id})
+					}
+				}
+			}
+			if len(phaseOf) != want {
+				t.Errorf("expected %d events, got %d", want, len(phaseOf))
+			}
+
+			// Every dependency runs in an earlier phase: for deletes, the
+			// dependent is deleted first.
+			for _, r := range resources {
+				for _, dep := range r.deps {
+					for _, action := range []Action{ActionCreate, ActionUpdate, ActionDelete} {
+						p, ok := phaseOf[eventKey{action, r.id}]
+						if !ok {
+							continue
+						}
+						for _, depAction := range []Action{ActionCreate, ActionUpdate, ActionDelete} {
+							dp, ok := phaseOf[eventKey{depAction, dep}]
+							if !ok {
+								continue
+							}
+							if action == ActionDelete && depAction == ActionDelete && p >= dp {
+								t.Errorf("%v in phase %d, not before %v in phase %d", eventKey{action, r.id}, p, eventKey{depAction, dep}, dp)
+							}
+							if mustPrecede(depAction, action) && dp >= p {
+								t.Errorf("%v in phase %d, not before %v in phase %d", eventKey{depAction, dep}, dp, eventKey{action, r.id}, p)
+							}
+						}
+					}
+				}
+			}
+
+			// Every delete, including a recreate's, comes before every
+			// create and update.
+			lastDelete, firstOther := -1, len(plan)
+			for k, p := range phaseOf {
+				if k.action == ActionDelete {
+					lastDelete = max(lastDelete, p)
+				} else {
+					firstOther = min(firstOther, p)
+				}
+			}
+			if lastDelete >= firstOther {
+				t.Errorf("delete in phase %d after a create or update in phase %d", lastDelete, firstOther)
+			}
+		})
+	}
+}
+
+func TestPlanReportsCycles(t *testing.T) {
+	for seed := range uint64(randomCases) {
+		t.Run(fmt.Sprintf("seed=%d", seed), func(t *testing.T) {
+			rng := rand.New(rand.NewPCG(seed, 0))
+			resources, actual, desired := randomGraph(rng)
+
+			// Close a ring among resources sorted together: ones being
+			// deleted, or ones being created or updated. Recreates are left
+			// out, they are in both sorts and the ring could close another
+			// cycle in the sort that fails first.
+			deleting := rng.IntN(2) == 0
+			var pool []*randomResource
+			for _, r := range resources {
+				if deleting && r.state == randomOrphan || !deleting && (r.state == randomMissing || r.state == randomUpdate) {
+					pool = append(pool, r)
+				}
+			}
+			if len(pool) < 2 {
+				t.Skip("not enough resources to form a cycle")
+			}
+			rng.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+			ring := pool[:2+rng.IntN(len(pool)-1)]
+			for i, r := range ring {
+				r.deps = append(r.deps, ring[(i+1)%len(ring)].id)
+			}
+
+			_, err := Plan(actual, desired)
+			if !errors.Is(err, ErrCycle) {
+				t.Fatalf("expected ErrCycle for a ring of %d, got %v", len(ring), err)
+			}
+			for _, r := range ring {
+				if !strings.Contains(err.Error(), r.id.Type+"/"+r.id.ID) {
+					t.Errorf("cycle error does not name %v: %v", r.id, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPlanIgnoresCyclesWithoutEvents(t *testing.T) {
+	a := &mockResource{id: id("node", "a"), deps: []Identifier{id("node", "b")}, status: Status{Exists: true}}
+	b := &mockResource{id: id("node", "b"), deps: []Identifier{id("node", "a")}, status: Status{Exists: true}}
+	resources := map[Identifier]Resource{a.id: a, b.id: b}
+
+	plan, err := Plan(resources, resources)
+	if err != nil || len(plan) != 0 {
+		t.Errorf("expected an empty plan for up-to-date resources, got %v, %v", plan, err)
+	}
+}
+
+func TestExecuteRandomFaults(t *testing.T) {
+	for seed := range uint64(randomCases) {
+		t.Run(fmt.Sprintf("seed=%d", seed), func(t *testing.T) {
+			rng := rand.New(rand.NewPCG(seed, 0))
+			resources, actual, desired := randomGraph(rng)
+			log := &eventLog{ran: map[eventKey]int{}, failed: map[eventKey]bool{}}
+			for _, r := range resources {
+				r.ran = log
+				r.fail = rng.IntN(6) == 0
+			}
+			plan, err := Plan(actual, desired)
+			if err != nil {
+				t.Fatalf("Plan: %v", err)
+			}
+			phaseOf := phaseIndex(t, plan)
+
+			err = Execute(context.Background(), plan)
+			if (err != nil) != (len(log.failed) > 0) {
+				t.Errorf("Execute returned %v with failed events %v", err, log.failed)
+			}
+
+			firstFailed := len(plan)
+			for k := range log.failed {
+				firstFailed = min(firstFailed, phaseOf[k])
+			}
+			for k, n := range log.ran {
+				if n != 1 {
+					t.Errorf("%v ran %d times", k, n)
+				}
+				if phaseOf[k] > firstFailed {
+					t.Errorf("%v in phase %d ran after a failure in phase %d", k, phaseOf[k], firstFailed)
+				}
+			}
+			if len(log.failed) == 0 && len(log.ran) != len(phaseOf) {
+				t.Errorf("expected all %d events to run, ran %d", len(phaseOf), len(log.ran))
+			}
+
+			// No dependent runs after its dependency failed.
+			for _, r := range resources {
+				for _, dep := range r.deps {
+					for depKey := range log.failed {
+						if depKey.id != dep {
+							continue
+						}
+						for k := range log.ran {
+							if k.id == r.id && mustPrecede(depKey.action, k.action) {
+								t.Errorf("%v ran although its dependency's %v failed", k, depKey)
+							}
+						}
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+// internal/resource/retry.go
+package resource
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// maxRetryBackoff caps the exponential backoff between retry attempts.
+const maxRetryBackoff = 30 * time.Second
+
+// RetryPolicy describes how transient failures are retried. Resources are
+// idempotent, so retrying a failed Create, Update, Delete or Refresh is
+// safe once the underlying cause (e.g. a failover) has cleared.
+type RetryPolicy struct {
+	// Retryable reports whether an error is transient.
+	Retryable func(error) bool
+	// Attempts is the total number of tries, including the first.
+	Attempts int
+	// Backoff is the delay before the first retry; it doubles each time.
+	Backoff time.Duration
+}
+
+// do runs fn, retrying per the policy. A zero policy runs fn once.
+func (p RetryPolicy) do(ctx context.Context, what string, fn func(context.Context) error) error {
+	backoff := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || p.Retryable == nil || attempt >= p.Attempts || !p.Retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		slog.Warn("retrying after transient error", "action", what,
+			"attempt", attempt, "max_attempts", p.Attempts, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
@@ -0,0 +1,203 @@
+// internal/spock/conflicts.go
+package spock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pgEdge/pgedge-helm/internal/config"
+)
+
+// Conflict is one resolved conflict from a node's spock.resolutions log.
+type Conflict struct {
+	Time        time.Time `json:"time"`
+	Table       string    `json:"table"`
+	Type        string    `json:"type"`
+	Resolution  string    `json:"resolution"`
+	Origin      string    `json:"origin"`
+	Receiver    string    `json:"receiver"`
+	LocalTuple  string    `json:"localTuple,omitempty"`
+	RemoteTuple string    `json:"remoteTuple,omitempty"`
+}
+
+// ConflictGroup counts the conflicts of one kind on one table between an
+// origin and a receiver.
+type ConflictGroup struct {
+	Table    string    `json:"table"`
+	Type     string    `json:"type"`
+	Origin   string    `json:"origin"`
+	Receiver string    `json:"receiver"`
+	Count    int       `json:"count"`
+	First    time.Time `json:"first"`
+	Last     time.Time `json:"last"`
+}
+
+// ConflictReport summarizes the conflicts resolved across the mesh since
+// a point in time.
+type ConflictReport struct {
+	Since  time.Time       `json:"since"`
+	Total  int             `json:"total"`
+	Groups []ConflictGroup `json:"groups"`
+	// Recent holds the latest conflicts, newest first.
+	Recent []Conflict `json:"recent"`
+	// Errors holds the nodes whose log could not be read.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// ReadConflicts reads the conflicts every node resolved since the given
+// time, aggregated by each node, and up to recent of the latest ones.
+func ReadConflicts(ctx context.Context, cfg *config.Config, conns map[string]DB, since time.Time, recent int) *ConflictReport {
+	origins := originNodes(cfg)
+	var groups []ConflictGroup
+	var latest []Conflict
+	errs := map[string]string{}
+	for _, node := range cfg.Nodes {
+		g, l, err := nodeConflicts(ctx, conns[node.Name], node.Name, origins, since, recent)
+		if err != nil {
+			errs[node.Name] = err.Error()
+			continue
+		}
+		groups = append(groups, g...)
+		latest = append(latest, l...)
+	}
+	report := summarizeConflicts(groups, latest, since, recent)
+	if len(errs) > 0 {
+		report.Errors = errs
+	}
+	return report
+}
+
+// originNodes maps the replication origins of the mesh's subscriptions,
+// which Spock names after their slots, to the provider node.
+func originNodes(cfg *config.Config) map[string]string {
+	origins := map[string]string{}
+	for _, src := range cfg.Nodes {
+		for _, dst := range cfg.Nodes {
+			if src.Name != dst.Name {
+				origins[spockSlotName(cfg.DBName, src.Name, dst.Name)] = src.Name
+			}
+		}
+	}
+	return origins
+}
+
+// nodeConflicts reads the conflicts node resolved since the given time,
+// counted per table, type and origin, and the latest recent of them. The
+// log can be large, so it is aggregated and limited by the node.
+func nodeConflicts(ctx context.Context, conn DB, node string, origins map[string]string, since time.Time, recent int) ([]ConflictGroup, []Conflict, error) {
+	if conn == nil {
+		return nil, nil, errors.New("not connected")
+	}
+	provider := func(origin string) string {
+		if p, ok := origins[origin]; ok {
+			return p
+		}
+		return origin
+	}
+
+	rows, err := conn.Query(ctx, `
+		SELECT coalesce(r.relname, ''), r.conflict_type::text,
+		       coalesce(o.roname, r.remote_origin::text, ''),
+		       count(*), min(r.log_time), max(r.log_time)
+		FROM spock.resolutions r
+		LEFT JOIN pg_replication_origin o ON o.roident = r.remote_origin
+		WHERE r.log_time >= $1
+		GROUP BY r.relname, r.conflict_type, r.remote_origin, o.roname`, since)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read spock.resolutions on %s: %w", node, err)
+	}
+	var groups []ConflictGroup
+	for rows.Next() {
+		g := ConflictGroup{Receiver: node}
+		if err := rows.Scan(&g.Table, &g.Type, &g.Origin, &g.Count, &g.First, &g.Last); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("read spock.resolutions on %s: %w", node, err)
+		}
+		g.Origin = provider(g.Origin)
+		groups = append(groups, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("read spock.resolutions on %s: %w", node, err)
+	}
+	if recent == 0 {
+		return groups, nil, nil
+	}
+
+	rows, err = conn.Query(ctx, `
+		SELECT r.log_time, coalesce(r.relname, ''), r.conflict_type::text, r.conflict_resolution::text,
+		       coalesce(o.roname, r.remote_origin::text, ''),
+		       coalesce(r.local_tuple::text, ''), coalesce(r.remote_tuple::text, '')
+		FROM spock.resolutions r
+		LEFT JOIN pg_replication_origin o ON o.roident = r.remote_origin
+		WHERE r.log_time >= $1
+		ORDER BY r.log_time DESC
+		LIMIT $2`, since, recent)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read spock.resolutions on %s: %w", node, err)
+	}
+	defer rows.Close()
+	var latest []Conflict
+	for rows.Next() {
+		c := Conflict{Receiver: node}
+		if err := rows.Scan(&c.Time, &c.Table, &c.Type, &c.Resolution, &c.Origin, &c.LocalTuple, &c.RemoteTuple); err != nil {
+			return nil, nil, fmt.Errorf("read spock.resolutions on %s: %w", node, err)
+		}
+		c.Origin = provider(c.Origin)
+		latest = append(latest, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("read spock.resolutions on %s: %w", node, err)
+	}
+	return groups, latest, nil
+}
+
+// summarizeConflicts merges the nodes' groups, most frequent first, and
+// keeps the latest recent of their latest conflicts.
+func summarizeConflicts(groups []ConflictGroup, latest []Conflict, since time.Time, recent int) *ConflictReport {
+	// Origins unknown to the config keep their name, so two of a node's
+	// groups can only meet if they map to the same provider.
+	type key struct{ table, typ, origin, receiver string }
+	merged := map[key]*ConflictGroup{}
+	report := &ConflictReport{Since: since, Groups: []ConflictGroup{}, Recent: []Conflict{}}
+	for _, g := range groups {
+		report.Total += g.Count
+		k := key{g.Table, g.Type, g.Origin, g.Receiver}
+		m, ok := merged[k]
+		if !ok {
+			m = &ConflictGroup{Table: g.Table, Type: g.Type, Origin: g.Origin, Receiver: g.Receiver, First: g.First, Last: g.Last}
+			merged[k] = m
+		}
+		m.Count += g.Count
+		if g.First.Before(m.First) {
+			m.First = g.First
+		}
+		if g.Last.After(m.Last) {
+			m.Last = g.Last
+		}
+	}
+	for _, g := range merged {
+		report.Groups = append(report.Groups, *g)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i], report.Groups[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if !a.Last.Equal(b.Last) {
+			return a.Last.After(b.Last)
+		}
+		return a.Table < b.Table
+	})
+
+	latest = append([]Conflict(nil), latest...)
+	sort.SliceStable(latest, func(i, j int) bool { return latest[i].Time.After(latest[j].Time) })
+	if len(latest) > recent {
+		latest = latest[:recent]
+	}
+	report.Recent = append(report.Recent, latest...)
+	return report
+}
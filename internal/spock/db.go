@@ -0,0 +1,34 @@
+// internal/spock/db.go
+package spock
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DB is the database access the Spock resources need. A *pgxpool.Pool
+// implements it; tests use a fake that models the Spock catalog instead.
+type DB interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Compile-time assertion: pgxpool.Pool implements DB.
+var _ DB = (*pgxpool.Pool)(nil)
+
+// PoolDBs returns the nodes' pools as DBs, for the functions that take
+// every node's connection.
+func PoolDBs(pools map[string]*pgxpool.Pool) map[string]DB {
+	dbs := make(map[string]DB, len(pools))
+	for name, pool := range pools {
+		if pool != nil {
+			dbs[name] = pool
+		}
+	}
+	return dbs
+}
@@ -4,8 +4,6 @@ package spock
 import (
 	"fmt"
 
-	"github.com/jackc/pgx/v5/pgxpool"
-
 	"github.com/pgEdge/pgedge-helm/internal/config"
 	"github.com/pgEdge/pgedge-helm/internal/resource"
 )
@@ -24,10 +22,14 @@ const (
 	ResourceTypeDisabledSubscription          = "spock.disabled_subscription"
 	ResourceTypeReplicationOriginAdvance      = "spock.replication_origin_advance"
 	ResourceTypePeerCatchup                   = "spock.peer_catchup"
+	ResourceTypeSchemaCopy                    = "spock.schema_copy"
+	ResourceTypeVerifyData                    = "spock.verify_data"
 )
 
-// ComputeDesired builds the full resource graph from node config.
-func ComputeDesired(cfg *config.Config, conns map[string]*pgxpool.Pool) map[resource.Identifier]resource.Resource {
+// ComputeDesired builds the full resource graph from node config. dump
+// produces the schema copied to new nodes bootstrapped with structure
+// "dump"; it may be nil when the graph is not executed.
+func ComputeDesired(cfg *config.Config, conns map[string]DB, dump SchemaDumper) map[resource.Identifier]resource.Resource {
 	resources := make(map[resource.Identifier]resource.Resource)
 
 	// Users + Nodes
@@ -39,7 +41,9 @@ func ComputeDesired(cfg *config.Config, conns map[string]*pgxpool.Pool) map[reso
 		resources[n.Identifier()] = n
 	}
 
-	// Identify new nodes needing populate
+	// Identify new nodes needing populate. Several may be added at once:
+	// each populates from its source and the existing nodes only, and new
+	// nodes subscribe to each other once both have finished populating.
 	newNodes := map[string]config.Node{}
 	for _, node := range cfg.Nodes {
 		if node.Bootstrap.Mode == "spock" && node.Bootstrap.SourceNode != "" {
@@ -50,7 +54,7 @@ func ComputeDesired(cfg *config.Config, conns map[string]*pgxpool.Pool) map[reso
 	// Emit populate resources and collect per-node peer deps
 	peerDeps := map[string][]resource.Identifier{}
 	for _, newNode := range newNodes {
-		deps := addPopulateResources(resources, cfg, newNode, conns)
+		deps := addPopulateResources(resources, cfg, newNode, newNodes, conns, dump)
 		peerDeps[newNode.Name] = deps
 	}
 
@@ -67,6 +71,9 @@ func ComputeDesired(cfg *config.Config, conns map[string]*pgxpool.Pool) map[reso
 			// Source→new subscription: created with sync=true, extra deps on peer waits
 			if newNode, isNewDst := newNodes[dst.Name]; isNewDst && src.Name == newNode.Bootstrap.SourceNode {
 				s := NewSubscription(src, dst, cfg.DBName, cfg.PgEdgeUser, true, conns[dst.Name], peerDeps[dst.Name]...)
+				if newNode.Bootstrap.Structure == config.StructureDump {
+					s.withoutStructureSync()
+				}
 				resources[s.Identifier()] = s
 				continue
 			}
@@ -74,7 +81,28 @@ func ComputeDesired(cfg *config.Config, conns map[string]*pgxpool.Pool) map[reso
 			// Compute extraDeps for other subscriptions involving new nodes
 			var extraDeps []resource.Identifier
 
-			if _, isNewDst := newNodes[dst.Name]; isNewDst {
+			newSrc, isNewSrc := newNodes[src.Name]
+			newDst, isNewDst := newNodes[dst.Name]
+			if isNewSrc && isNewDst {
+				// New→new: neither node has its data until it has populated,
+				// and neither is in the other's populate chain. Start
+				// replicating between them once both are done.
+				extraDeps = append(extraDeps,
+					resource.Identifier{
+						Type: ResourceTypeWaitForSyncEvent,
+						ID:   fmt.Sprintf("%s_%s", newSrc.Bootstrap.SourceNode, src.Name),
+					},
+					resource.Identifier{
+						Type: ResourceTypeWaitForSyncEvent,
+						ID:   fmt.Sprintf("%s_%s", newDst.Bootstrap.SourceNode, dst.Name),
+					},
+				)
+				s := NewSubscription(src, dst, cfg.DBName, cfg.PgEdgeUser, false, conns[dst.Name], extraDeps...)
+				resources[s.Identifier()] = s
+				continue
+			}
+
+			if isNewDst {
 				// Peer→new: wait for origin advance (which itself waits on slot
 				// advance — both must complete before enabling the subscription
 				// to keep slot LSN and origin LSN in lockstep).
@@ -84,36 +112,77 @@ func ComputeDesired(cfg *config.Config, conns map[string]*pgxpool.Pool) map[reso
 				})
 			}
 
-			if newNode, isNewSrc := newNodes[src.Name]; isNewSrc {
+			if isNewSrc {
 				// New→existing: wait for source sync completion
 				extraDeps = append(extraDeps, resource.Identifier{
 					Type: ResourceTypeWaitForSyncEvent,
-					ID:   fmt.Sprintf("%s_%s", newNode.Bootstrap.SourceNode, src.Name),
+					ID:   fmt.Sprintf("%s_%s", newSrc.Bootstrap.SourceNode, src.Name),
 				})
 			}
 
 			s := NewSubscription(src, dst, cfg.DBName, cfg.PgEdgeUser, false, conns[dst.Name], extraDeps...)
+			if isNewDst {
+				s.fromPopulate()
+			}
 			resources[s.Identifier()] = s
 		}
 	}
 
+	// Optionally compare each new node with its source once every
+	// subscription to it is enabled.
+	if cfg.VerifyData == config.VerifyDataWarn || cfg.VerifyData == config.VerifyDataFail {
+		for _, newNode := range newNodes {
+			var peers []string
+			var source config.Node
+			for _, node := range cfg.Nodes {
+				if node.Name == newNode.Name {
+					continue
+				}
+				peers = append(peers, node.Name)
+				if node.Name == newNode.Bootstrap.SourceNode {
+					source = node
+				}
+			}
+			if source.Name == "" {
+				continue
+			}
+			v := NewVerifyData(source, newNode, peers, cfg.VerifyData, cfg.Waits.SyncEvent, conns[source.Name], conns[newNode.Name])
+			resources[v.Identifier()] = v
+		}
+	}
+
 	return resources
 }
 
 // addPopulateResources emits the populate resource chain for a new node.
 // Returns the identifiers of peer-side gates (WaitForSyncEvent + PeerCatchup
-// per peer) that the source→new subscription must wait on before COPY.
+// per peer, and SchemaCopy for structure "dump") that the source→new
+// subscription must wait on before COPY.
+// Other new nodes are not peers here: they have no data to sync yet.
 func addPopulateResources(
 	resources map[resource.Identifier]resource.Resource,
 	cfg *config.Config,
 	newNode config.Node,
-	conns map[string]*pgxpool.Pool,
+	newNodes map[string]config.Node,
+	conns map[string]DB,
+	dump SchemaDumper,
 ) []resource.Identifier {
 	sourceNode := newNode.Bootstrap.SourceNode
 	var peerWaitForSync []resource.Identifier
 
+	if newNode.Bootstrap.Structure == config.StructureDump {
+		for _, source := range cfg.Nodes {
+			if source.Name != sourceNode {
+				continue
+			}
+			schemaCopy := NewSchemaCopy(source, newNode, cfg.DBName, dump, conns[newNode.Name])
+			resources[schemaCopy.Identifier()] = schemaCopy
+			peerWaitForSync = append(peerWaitForSync, schemaCopy.Identifier())
+		}
+	}
+
 	for _, peer := range cfg.Nodes {
-		if peer.Name == newNode.Name || peer.Name == sourceNode {
+		if _, isNew := newNodes[peer.Name]; isNew || peer.Name == sourceNode {
 			continue
 		}
 
@@ -131,14 +200,14 @@ func addPopulateResources(
 		)
 		resources[peerSyncEvt.Identifier()] = peerSyncEvt
 
-		peerWaitEvt := NewWaitForSyncEvent(peer.Name, sourceNode, peerSyncEvt, conns[sourceNode])
+		peerWaitEvt := NewWaitForSyncEvent(peer.Name, sourceNode, peerSyncEvt, cfg.Waits.SyncEvent, conns[sourceNode])
 		resources[peerWaitEvt.Identifier()] = peerWaitEvt
 		peerWaitForSync = append(peerWaitForSync, peerWaitEvt.Identifier())
 
 		// Belt-and-suspenders: also wait on apply progress via
 		// spock.progress.remote_lsn, which tracks actual commit application
 		// rather than WAL receipt. Gates the source→new COPY.
-		peerCatchup := NewPeerCatchup(peer.Name, sourceNode, peerSyncEvt, conns[sourceNode])
+		peerCatchup := NewPeerCatchup(peer.Name, sourceNode, peerSyncEvt, cfg.Waits.PeerCatchup, conns[sourceNode])
 		resources[peerCatchup.Identifier()] = peerCatchup
 		peerWaitForSync = append(peerWaitForSync, peerCatchup.Identifier())
 
@@ -162,7 +231,8 @@ func addPopulateResources(
 	srcSyncEvt := NewSyncEvent(sourceNode, newNode.Name, conns[sourceNode])
 	resources[srcSyncEvt.Identifier()] = srcSyncEvt
 
-	srcWaitEvt := NewWaitForSyncEvent(sourceNode, newNode.Name, srcSyncEvt, conns[newNode.Name])
+	srcWaitEvt := NewWaitForSyncEvent(sourceNode, newNode.Name, srcSyncEvt, cfg.Waits.SyncEvent, conns[newNode.Name]).
+		withSyncProgress(conns[sourceNode])
 	resources[srcWaitEvt.Identifier()] = srcWaitEvt
 
 	return peerWaitForSync
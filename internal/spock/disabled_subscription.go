@@ -8,7 +8,6 @@ import (
 	"log/slog"
 
 	"github.com/jackc/pgx/v5/pgconn"
-	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/pgEdge/pgedge-helm/internal/config"
 	"github.com/pgEdge/pgedge-helm/internal/resource"
@@ -24,11 +23,11 @@ type DisabledSubscription struct {
 	dst        config.Node
 	dbName     string
 	pgedgeUser string
-	conn       *pgxpool.Pool // dst node's connection
+	conn       DB // dst node's connection
 	status     resource.Status
 }
 
-func NewDisabledSubscription(src, dst config.Node, dbName, pgedgeUser string, conn *pgxpool.Pool) *DisabledSubscription {
+func NewDisabledSubscription(src, dst config.Node, dbName, pgedgeUser string, conn DB) *DisabledSubscription {
 	return &DisabledSubscription{
 		src:        src,
 		dst:        dst,
@@ -63,7 +62,7 @@ func (s *DisabledSubscription) Refresh(_ context.Context) error {
 func (s *DisabledSubscription) Status() resource.Status { return s.status }
 
 func (s *DisabledSubscription) Create(ctx context.Context) error {
-	tx, err := s.conn.Begin(ctx)
+	tx, err := beginOp(ctx, s.conn, opCatalog)
 	if err != nil {
 		return fmt.Errorf("begin tx for disabled subscription %s: %w", s.subName(), err)
 	}
@@ -81,7 +80,7 @@ func (s *DisabledSubscription) Create(ctx context.Context) error {
 		SELECT spock.sub_create(
 			subscription_name := $1,
 			provider_dsn := $2,
-			replication_sets := '{default, default_insert_only, ddl_sql}',
+			replication_sets := $3,
 			synchronize_structure := false,
 			synchronize_data := false,
 			forward_origins := '{}',
@@ -89,7 +88,7 @@ func (s *DisabledSubscription) Create(ctx context.Context) error {
 			enabled := 'false'
 		)
 		WHERE $1 NOT IN (SELECT sub_name FROM spock.subscription)
-	`, s.subName(), dsn)
+	`, s.subName(), dsn, subscriptionRepsets)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == pgCodeDuplicateObject {
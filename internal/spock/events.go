@@ -0,0 +1,135 @@
+// internal/spock/events.go
+package spock
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pgEdge/pgedge-helm/internal/resource"
+)
+
+// Notice is an operator-facing summary of an executed resource event,
+// attributed to the node whose CNPG Cluster it should be reported on.
+type Notice struct {
+	Node    string
+	Warning bool
+	Reason  string
+	Message string
+}
+
+// DescribeEvent summarizes the outcome of an executed event for operators.
+// Returns false for events not worth surfacing, such as the ephemeral
+// populate steps, which are covered by a single PopulateFinished notice.
+func DescribeEvent(event resource.Event, err error) (Notice, bool) {
+	id := event.Resource.Identifier()
+	if err != nil {
+		node, ok := eventNode(event.Resource)
+		if !ok {
+			return Notice{}, false
+		}
+		return Notice{
+			Node:    node,
+			Warning: true,
+			Reason:  actionName(event.Action) + "Failed",
+			Message: fmt.Sprintf("%s %s/%s failed: %v", strings.ToLower(actionName(event.Action)), id.Type, id.ID, err),
+		}, true
+	}
+
+	switch r := event.Resource.(type) {
+	case *SpockNode:
+		if event.Action == resource.ActionCreate {
+			return Notice{Node: r.node.Name, Reason: "NodeCreated",
+				Message: fmt.Sprintf("created spock node %s", r.node.Name)}, true
+		}
+		if event.Action == resource.ActionDelete && r.survivor != "" {
+			return Notice{Node: r.survivor, Reason: "OrphanDropped",
+				Message: fmt.Sprintf("dropped orphan spock node %s", r.node.Name)}, true
+		}
+	case *Subscription:
+		switch event.Action {
+		case resource.ActionCreate:
+			if s := r.Status(); s.NeedsRecreate {
+				return Notice{Node: r.dst.Name, Reason: "SubscriptionRecreated",
+					Message: fmt.Sprintf("recreated subscription %s: %s", r.subName(), s.Reason)}, true
+			}
+			if r.sync {
+				return Notice{Node: r.dst.Name, Reason: "SubscriptionCreated",
+					Message: fmt.Sprintf("created subscription %s with initial data sync from %s", r.subName(), r.src.Name)}, true
+			}
+			return Notice{Node: r.dst.Name, Reason: "SubscriptionCreated",
+				Message: fmt.Sprintf("created subscription %s", r.subName())}, true
+		case resource.ActionUpdate:
+			return Notice{Node: r.dst.Name, Reason: "SubscriptionEnabled",
+				Message: fmt.Sprintf("enabled subscription %s", r.subName())}, true
+		case resource.ActionDelete:
+			if r.Status().NeedsRecreate {
+				// Reported once by the paired create.
+				return Notice{}, false
+			}
+			return Notice{Node: r.dst.Name, Reason: "OrphanDropped",
+				Message: fmt.Sprintf("dropped orphan subscription %s", r.subName())}, true
+		}
+	case *ReplicationSlot:
+		if event.Action == resource.ActionDelete {
+			return Notice{Node: r.providerName, Reason: "OrphanDropped",
+				Message: fmt.Sprintf("dropped orphan replication slot %s", r.slotName())}, true
+		}
+	}
+	return Notice{}, false
+}
+
+// eventNode returns the node a resource executes on, when known.
+func eventNode(r resource.Resource) (string, bool) {
+	switch r := r.(type) {
+	case *PgEdgeUser:
+		return r.node.Name, true
+	case *SpockNode:
+		if r.survivor != "" {
+			return r.survivor, true
+		}
+		return r.node.Name, true
+	case *Subscription:
+		return r.dst.Name, true
+	case *DisabledSubscription:
+		return r.dst.Name, true
+	case *ReplicationSlot:
+		return r.providerName, true
+	case *ReplicationSlotCreate:
+		return r.providerName, true
+	case *SyncEvent:
+		return r.providerName, true
+	case *WaitForSyncEvent:
+		return r.subscriberName, true
+	case *LagTrackerCommitTimestamp:
+		return r.receiverName, true
+	case *ReplicationSlotAdvanceFromCTS:
+		return r.providerName, true
+	case *ReplicationOriginAdvance:
+		return r.subscriberName, true
+	case *PeerCatchup:
+		return r.sourceName, true
+	}
+	return "", false
+}
+
+// PopulateTarget returns the new node whose initial data copy an event
+// starts: the creation of the source→new subscription with sync enabled.
+func PopulateTarget(event resource.Event) (string, bool) {
+	s, ok := event.Resource.(*Subscription)
+	if !ok || !s.sync || event.Action != resource.ActionCreate {
+		return "", false
+	}
+	return s.dst.Name, true
+}
+
+func actionName(a resource.Action) string {
+	switch a {
+	case resource.ActionCreate:
+		return "Create"
+	case resource.ActionUpdate:
+		return "Update"
+	case resource.ActionDelete:
+		return "Delete"
+	}
+	return "Unknown"
+}
@@ -0,0 +1,229 @@
+// internal/spock/exceptions.go
+package spock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/pgEdge/pgedge-helm/internal/config"
+)
+
+// Exception is one failed apply operation recorded in a subscriber's
+// spock.exception_log.
+type Exception struct {
+	Subscription string    `json:"subscription"`
+	Origin       string    `json:"origin"`
+	Receiver     string    `json:"receiver"`
+	CommitTS     time.Time `json:"commitTimestamp"`
+	RemoteXID    int64     `json:"remoteXid"`
+	Table        string    `json:"table,omitempty"`
+	Operation    string    `json:"operation,omitempty"`
+	Error        string    `json:"error"`
+	LoggedAt     time.Time `json:"loggedAt"`
+}
+
+// ExceptionReport lists the logged exceptions across the mesh, oldest
+// transaction first.
+type ExceptionReport struct {
+	Exceptions []Exception `json:"exceptions"`
+	// Errors holds the nodes whose log could not be read.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// ReadExceptions reads spock.exception_log on every node.
+func ReadExceptions(ctx context.Context, cfg *config.Config, conns map[string]DB) *ExceptionReport {
+	origins := originNodes(cfg)
+	report := &ExceptionReport{Exceptions: []Exception{}}
+	for _, node := range cfg.Nodes {
+		exceptions, err := nodeExceptions(ctx, conns[node.Name], node.Name, origins)
+		if err != nil {
+			if report.Errors == nil {
+				report.Errors = map[string]string{}
+			}
+			report.Errors[node.Name] = err.Error()
+			continue
+		}
+		report.Exceptions = append(report.Exceptions, exceptions...)
+	}
+	return report
+}
+
+func nodeExceptions(ctx context.Context, conn DB, node string, origins map[string]string) ([]Exception, error) {
+	if conn == nil {
+		return nil, errors.New("not connected")
+	}
+	rows, err := conn.Query(ctx, `
+		SELECT coalesce(o.roname, e.remote_origin::text), e.remote_commit_ts, e.remote_xid,
+		       coalesce(e.table_schema || '.' || e.table_name, ''), coalesce(e.operation, ''),
+		       e.error_message, e.retry_errored_at
+		FROM spock.exception_log e
+		LEFT JOIN pg_replication_origin o ON o.roident = e.remote_origin
+		ORDER BY e.remote_commit_ts, e.command_counter`)
+	if err != nil {
+		return nil, fmt.Errorf("read spock.exception_log on %s: %w", node, err)
+	}
+	defer rows.Close()
+	var exceptions []Exception
+	for rows.Next() {
+		e := Exception{Receiver: node}
+		if err := rows.Scan(&e.Origin, &e.CommitTS, &e.RemoteXID, &e.Table, &e.Operation, &e.Error, &e.LoggedAt); err != nil {
+			return nil, fmt.Errorf("read spock.exception_log on %s: %w", node, err)
+		}
+		if provider, ok := origins[e.Origin]; ok {
+			e.Origin = provider
+			e.Subscription = spockSubName(provider, node)
+		}
+		exceptions = append(exceptions, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read spock.exception_log on %s: %w", node, err)
+	}
+	return exceptions, nil
+}
+
+// subscriptionExceptions returns how many operations of a subscription are
+// in the subscriber's exception log and the latest error. The log is keyed
+// by replication origin, which Spock names after the subscription's slot.
+func subscriptionExceptions(ctx context.Context, conn DB, slotName string) (int, string, error) {
+	var count int
+	var lastErr string
+	err := conn.QueryRow(ctx, `
+		SELECT count(*), coalesce((array_agg(e.error_message ORDER BY e.remote_commit_ts DESC))[1], '')
+		FROM spock.exception_log e
+		JOIN pg_replication_origin o ON o.roident = e.remote_origin
+		WHERE o.roname = $1`, slotName,
+	).Scan(&count, &lastErr)
+	return count, lastErr, err
+}
+
+// findPair returns the provider and subscriber of a subscription name.
+func findPair(cfg *config.Config, subName string) (config.Node, config.Node, error) {
+	for _, src := range cfg.Nodes {
+		for _, dst := range cfg.Nodes {
+			if src.Name != dst.Name && spockSubName(src.Name, dst.Name) == subName {
+				return src, dst, nil
+			}
+		}
+	}
+	return config.Node{}, config.Node{}, fmt.Errorf("unknown subscription %q", subName)
+}
+
+// SkipException skips the oldest failed transaction of a subscription:
+// it resolves the transaction's commit LSN on the provider, tells the
+// subscription to skip it with spock.sub_alter_skiplsn, clears its entries
+// from the exception log and restarts the subscription. It returns the
+// skipped transaction.
+func SkipException(ctx context.Context, cfg *config.Config, conns map[string]DB, subName string) (*Exception, error) {
+	src, dst, err := findPair(cfg, subName)
+	if err != nil {
+		return nil, err
+	}
+	provider, subscriber := conns[src.Name], conns[dst.Name]
+	if provider == nil || subscriber == nil {
+		return nil, fmt.Errorf("skip exception on %s: nodes %s and %s must both be connected", subName, src.Name, dst.Name)
+	}
+	slotName := spockSlotName(cfg.DBName, src.Name, dst.Name)
+
+	e := Exception{Subscription: subName, Origin: src.Name, Receiver: dst.Name}
+	err = subscriber.QueryRow(ctx, `
+		SELECT e.remote_commit_ts, e.remote_xid, coalesce(e.table_schema || '.' || e.table_name, ''),
+		       coalesce(e.operation, ''), e.error_message, e.retry_errored_at
+		FROM spock.exception_log e
+		JOIN pg_replication_origin o ON o.roident = e.remote_origin
+		WHERE o.roname = $1
+		ORDER BY e.remote_commit_ts, e.command_counter
+		LIMIT 1`, slotName,
+	).Scan(&e.CommitTS, &e.RemoteXID, &e.Table, &e.Operation, &e.Error, &e.LoggedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("no exceptions logged for %s", subName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read exception for %s: %w", subName, err)
+	}
+
+	var lsn string
+	err = provider.QueryRow(ctx, "SELECT spock.get_lsn_from_commit_ts($1, $2)::text", slotName, e.CommitTS).Scan(&lsn)
+	if err != nil {
+		return nil, fmt.Errorf("get commit LSN of xid %d for %s: %w", e.RemoteXID, subName, err)
+	}
+
+	err = restartSubscription(ctx, subscriber, subName, slotName, &e.CommitTS, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "SELECT spock.sub_alter_skiplsn($1, $2::pg_lsn)", subName, lsn)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("skipped failed transaction", "sub", subName, "xid", e.RemoteXID, "lsn", lsn)
+	return &e, nil
+}
+
+// RetryException clears a subscription's entries from the exception log
+// and restarts it, so its apply worker retries the failed transaction.
+// If the transaction fails again, Spock logs it again.
+func RetryException(ctx context.Context, cfg *config.Config, conns map[string]DB, subName string) error {
+	src, dst, err := findPair(cfg, subName)
+	if err != nil {
+		return err
+	}
+	subscriber := conns[dst.Name]
+	if subscriber == nil {
+		return fmt.Errorf("retry %s: node %s is not connected", subName, dst.Name)
+	}
+	err = restartSubscription(ctx, subscriber, subName, spockSlotName(cfg.DBName, src.Name, dst.Name), nil, nil)
+	if err != nil {
+		return err
+	}
+	slog.Info("restarted subscription to retry failed transactions", "sub", subName)
+	return nil
+}
+
+// restartSubscription runs before, if set, and clears the subscription's
+// exception log entries (only those of commitTS, if set) in one transaction
+// on the subscriber. Once that has committed, it disables and re-enables
+// the subscription with immediate := true, so the apply worker stops and
+// starts again with the skip LSN. Spock refuses immediate := true inside a
+// transaction block, so these two run outside one.
+func restartSubscription(ctx context.Context, conn DB, subName, slotName string, commitTS *time.Time, before func(pgx.Tx) error) error {
+	tx, err := beginOp(ctx, conn, opCatalog)
+	if err != nil {
+		return fmt.Errorf("begin tx for %s: %w", subName, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT spock.repair_mode('True')`); err != nil {
+		return fmt.Errorf("repair mode for %s: %w", subName, err)
+	}
+	if before != nil {
+		if err := before(tx); err != nil {
+			return fmt.Errorf("skip transaction on %s: %w", subName, err)
+		}
+	}
+	_, err = tx.Exec(ctx, `
+		DELETE FROM spock.exception_log e
+		USING pg_replication_origin o
+		WHERE o.roident = e.remote_origin AND o.roname = $1
+		  AND ($2::timestamptz IS NULL OR e.remote_commit_ts = $2)`, slotName, commitTS)
+	if err != nil {
+		return fmt.Errorf("clear exception log for %s: %w", subName, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit exception log changes for %s: %w", subName, err)
+	}
+
+	// The log is cleared from here on: if the restart fails, init-spock
+	// exceptions -retry restarts the subscription without skipping another
+	// transaction.
+	if _, err := conn.Exec(ctx, `SELECT spock.sub_disable($1, immediate := true)`, subName); err != nil {
+		return fmt.Errorf("disable subscription %s (restart it with exceptions -retry): %w", subName, err)
+	}
+	if _, err := conn.Exec(ctx, `SELECT spock.sub_enable($1, immediate := true)`, subName); err != nil {
+		return fmt.Errorf("enable subscription %s (restart it with exceptions -retry): %w", subName, err)
+	}
+	return nil
+}
@@ -0,0 +1,735 @@
+// internal/spock/fake_test.go
+package spock
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeMesh is a set of fake databases, one per node, that reach each other
+// by hostname the way Spock's DSNs do. One lock serializes every statement
+// in the mesh, since some of them, like sub_create, change two nodes.
+type fakeMesh struct {
+	mu     sync.Mutex
+	dbName string
+	dbs    map[string]*fakeDB // by hostname
+}
+
+func newFakeMesh(dbName string) *fakeMesh {
+	return &fakeMesh{dbName: dbName, dbs: map[string]*fakeDB{}}
+}
+
+// add creates the database of a node reachable at hostname.
+func (m *fakeMesh) add(name, hostname string) *fakeDB {
+	db := &fakeDB{mesh: m, name: name, cat: newFakeCatalog()}
+	m.dbs[hostname] = db
+	return db
+}
+
+// fakeCatalog models the parts of a node's Spock and replication catalogs
+// the resources use.
+type fakeCatalog struct {
+	roles      map[string]bool
+	local      string            // local Spock node, "" without one
+	nodes      map[string]string // Spock node name → DSN
+	subs       map[string]*fakeSub
+	slots      map[string]bool
+	origins    map[string]bool
+	exceptions map[string][]fakeException // by replication origin
+	lsn        uint64                     // last sync event LSN
+}
+
+type fakeSub struct {
+	provider string // provider's Spock node name
+	repsets  []string
+	enabled  bool
+	sync     bool
+	skipLSN  string // from sub_alter_skiplsn
+	restarts int    // immediate disables followed by an enable
+	stopped  bool   // disabled with immediate := true
+}
+
+// fakeException is a failed apply transaction in spock.exception_log.
+type fakeException struct {
+	commitTS time.Time
+	xid      int64
+	err      string
+}
+
+func newFakeCatalog() fakeCatalog {
+	return fakeCatalog{
+		roles:      map[string]bool{},
+		nodes:      map[string]string{},
+		subs:       map[string]*fakeSub{},
+		slots:      map[string]bool{},
+		origins:    map[string]bool{},
+		exceptions: map[string][]fakeException{},
+	}
+}
+
+func (c fakeCatalog) clone() fakeCatalog {
+	out := c
+	out.roles = cloneMap(c.roles)
+	out.nodes = cloneMap(c.nodes)
+	out.subs = make(map[string]*fakeSub, len(c.subs))
+	for name, sub := range c.subs {
+		s := *sub
+		out.subs[name] = &s
+	}
+	out.slots = cloneMap(c.slots)
+	out.origins = cloneMap(c.origins)
+	out.exceptions = cloneMap(c.exceptions)
+	return out
+}
+
+func cloneMap[V any](m map[string]V) map[string]V {
+	out := make(map[string]V, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// fakeHandler answers statements containing match. It returns the result
+// rows, or an error such as a *pgconn.PgError. db.inTx tells whether the
+// statement runs in a transaction block.
+type fakeHandler struct {
+	match string
+	fn    func(db *fakeDB, args []any) ([][]any, error)
+}
+
+// fakeDB is a DB backed by a fakeCatalog. Statements are matched by
+// substring against handlers scripted with on, then against the catalog
+// handlers in fakeCatalogHandlers; anything else fails, so a test notices
+// SQL the fake does not model. Transactions restore the node's catalog on
+// rollback; changes they made on other nodes, like the provider's slot
+// created by sub_create, stay, as they do with Spock.
+type fakeDB struct {
+	mesh     *fakeMesh
+	name     string
+	cat      fakeCatalog
+	handlers []fakeHandler
+	log      []fakeStmt
+	txs      int  // transactions begun
+	inTx     bool // the statement being run is in a transaction block
+}
+
+// fakeStmt is a statement in a fakeDB's log, whitespace collapsed. tx is
+// the number of the transaction it ran in, 0 outside one; COMMIT and
+// ROLLBACK are logged too.
+type fakeStmt struct {
+	sql string
+	tx  int
+}
+
+func (s fakeStmt) String() string {
+	if s.tx == 0 {
+		return s.sql
+	}
+	return fmt.Sprintf("[tx %d] %s", s.tx, s.sql)
+}
+
+// on scripts the answer to statements containing match, ahead of the
+// catalog model.
+func (db *fakeDB) on(match string, fn func(db *fakeDB, args []any) ([][]any, error)) {
+	db.mesh.mu.Lock()
+	defer db.mesh.mu.Unlock()
+	db.handlers = append(db.handlers, fakeHandler{match: match, fn: fn})
+}
+
+// ran returns the statements run since clearLog that contain match.
+func (db *fakeDB) ran(match string) []string {
+	db.mesh.mu.Lock()
+	defer db.mesh.mu.Unlock()
+	var out []string
+	for _, stmt := range db.log {
+		if strings.Contains(stmt.sql, match) {
+			out = append(out, stmt.sql)
+		}
+	}
+	return out
+}
+
+// ranIn returns the log since clearLog, as run, of the statements that
+// contain one of matches.
+func (db *fakeDB) ranIn(matches ...string) []fakeStmt {
+	db.mesh.mu.Lock()
+	defer db.mesh.mu.Unlock()
+	var out []fakeStmt
+	for _, stmt := range db.log {
+		if slices.ContainsFunc(matches, func(m string) bool { return strings.Contains(stmt.sql, m) }) {
+			out = append(out, stmt)
+		}
+	}
+	return out
+}
+
+func (db *fakeDB) clearLog() {
+	db.mesh.mu.Lock()
+	defer db.mesh.mu.Unlock()
+	db.log = nil
+}
+
+// run runs a statement in tx, or outside a transaction if tx is nil.
+func (db *fakeDB) run(tx *fakeTx, sql string, args []any) ([][]any, error) {
+	db.mesh.mu.Lock()
+	defer db.mesh.mu.Unlock()
+	stmt := strings.Join(strings.Fields(sql), " ")
+	logged := fakeStmt{sql: stmt}
+	if tx != nil {
+		if tx.done {
+			return nil, pgx.ErrTxClosed
+		}
+		logged.tx = tx.id
+	}
+	db.log = append(db.log, logged)
+	db.inTx = tx != nil
+	if n := placeholders(stmt); n != len(args) {
+		return nil, &pgconn.PgError{Code: "08P01", Message: fmt.Sprintf("bind message supplies %d parameters, but prepared statement requires %d", len(args), n)}
+	}
+	for _, h := range slices.Backward(db.handlers) {
+		if strings.Contains(stmt, h.match) {
+			return h.fn(db, args)
+		}
+	}
+	for _, h := range fakeCatalogHandlers {
+		if strings.Contains(stmt, h.match) {
+			return h.fn(db, args)
+		}
+	}
+	return nil, fmt.Errorf("fake %s: unexpected statement: %s", db.name, stmt)
+}
+
+var placeholderRE = regexp.MustCompile(`\$(\d+)`)
+
+// placeholders returns the highest $N placeholder in stmt, which Postgres
+// requires to match the number of arguments.
+func placeholders(stmt string) int {
+	n := 0
+	for _, m := range placeholderRE.FindAllStringSubmatch(stmt, -1) {
+		i, _ := strconv.Atoi(m[1])
+		n = max(n, i)
+	}
+	return n
+}
+
+// namedArg returns the value of the name := value argument in the last
+// statement run, resolving $N placeholders against args and parsing
+// booleans and quoted literals.
+func (db *fakeDB) namedArg(args []any, name string) (any, error) {
+	stmt := db.log[len(db.log)-1].sql
+	m := regexp.MustCompile(regexp.QuoteMeta(name) + ` := ('[^']*'|[^,)\s]+)`).FindStringSubmatch(stmt)
+	if m == nil {
+		return nil, fmt.Errorf("fake %s: no %s argument in: %s", db.name, name, stmt)
+	}
+	v := m[1]
+	if n, ok := strings.CutPrefix(v, "$"); ok {
+		i, _ := strconv.Atoi(n)
+		return args[i-1], nil
+	}
+	if b, err := strconv.ParseBool(strings.Trim(v, "'")); err == nil {
+		return b, nil
+	}
+	return strings.Trim(v, "'"), nil
+}
+
+func (db *fakeDB) Begin(_ context.Context) (pgx.Tx, error) {
+	db.mesh.mu.Lock()
+	defer db.mesh.mu.Unlock()
+	db.txs++
+	return &fakeTx{db: db, id: db.txs, saved: db.cat.clone()}, nil
+}
+
+func (db *fakeDB) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return db.exec(nil, sql, args)
+}
+
+func (db *fakeDB) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return db.query(nil, sql, args)
+}
+
+func (db *fakeDB) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	return db.queryRow(nil, sql, args)
+}
+
+func (db *fakeDB) exec(tx *fakeTx, sql string, args []any) (pgconn.CommandTag, error) {
+	if _, err := db.run(tx, sql, args); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return pgconn.NewCommandTag("SELECT 1"), nil
+}
+
+func (db *fakeDB) query(tx *fakeTx, sql string, args []any) (pgx.Rows, error) {
+	rows, err := db.run(tx, sql, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{rows: rows, i: -1}, nil
+}
+
+func (db *fakeDB) queryRow(tx *fakeTx, sql string, args []any) pgx.Row {
+	rows, err := db.run(tx, sql, args)
+	return &fakeRow{rows: rows, err: err}
+}
+
+// provider returns the database a DSN's host names.
+func (db *fakeDB) provider(dsn string) (*fakeDB, error) {
+	for _, field := range strings.Fields(dsn) {
+		if host, ok := strings.CutPrefix(field, "host="); ok {
+			if p, ok := db.mesh.dbs[host]; ok {
+				return p, nil
+			}
+			return nil, &pgconn.PgError{Code: "08001", Message: "could not connect to " + host}
+		}
+	}
+	return nil, fmt.Errorf("fake %s: no host in DSN %q", db.name, dsn)
+}
+
+// slotFor returns the provider-side slot name of a subscription, which
+// Spock also uses for the subscriber's replication origin.
+func (db *fakeDB) slotFor(provider, sub string) string {
+	return strings.ReplaceAll(fmt.Sprintf("spk_%s_%s_%s", db.mesh.dbName, provider, sub), "-", "_")
+}
+
+// fakeCatalogHandlers model the statements of the Spock resources. More
+// specific matches come first.
+var fakeCatalogHandlers = []fakeHandler{
+	{"set_config('lock_timeout'", func(_ *fakeDB, args []any) ([][]any, error) {
+		return [][]any{{args[0], args[1]}}, nil
+	}},
+	{"spock.repair_mode(", noRows},
+	{"FROM pg_roles WHERE rolname = $1", func(db *fakeDB, args []any) ([][]any, error) {
+		return exists(db.cat.roles[args[0].(string)]), nil
+	}},
+	{"CREATE ROLE ", func(db *fakeDB, _ []any) ([][]any, error) {
+		stmt := db.log[len(db.log)-1].sql
+		name := strings.Fields(stmt)[2]
+		if db.cat.roles[name] {
+			return nil, &pgconn.PgError{Code: pgCodeDuplicateObject, Message: fmt.Sprintf("role %q already exists", name)}
+		}
+		db.cat.roles[name] = true
+		return nil, nil
+	}},
+	{"JOIN spock.local_node", func(db *fakeDB, args []any) ([][]any, error) {
+		return exists(db.cat.local != "" && db.cat.local == args[0]), nil
+	}},
+	{"spock.node_create(", func(db *fakeDB, args []any) ([][]any, error) {
+		name := args[0].(string)
+		if _, ok := db.cat.nodes[name]; ok {
+			return nil, nil // WHERE $1 NOT IN (SELECT node_name FROM spock.node)
+		}
+		db.cat.nodes[name] = args[1].(string)
+		if db.cat.local == "" {
+			db.cat.local = name
+		}
+		return nil, nil
+	}},
+	{"spock.node_drop($1", func(db *fakeDB, args []any) ([][]any, error) {
+		name := args[0].(string)
+		delete(db.cat.nodes, name)
+		if db.cat.local == name {
+			db.cat.local = ""
+		}
+		return nil, nil
+	}},
+	{"SELECT node_name FROM spock.node WHERE node_name != ALL", func(db *fakeDB, args []any) ([][]any, error) {
+		var rows [][]any
+		for _, name := range sortedKeys(db.cat.nodes) {
+			if !slices.Contains(args[0].([]string), name) {
+				rows = append(rows, []any{name})
+			}
+		}
+		return rows, nil
+	}},
+	{"FROM spock.subscription WHERE sub_name = $1 AND NOT sub_enabled", func(db *fakeDB, args []any) ([][]any, error) {
+		sub, ok := db.cat.subs[args[0].(string)]
+		return exists(ok && !sub.enabled), nil
+	}},
+	{"FROM spock.subscription WHERE sub_name = $1", func(db *fakeDB, args []any) ([][]any, error) {
+		sub, ok := db.cat.subs[args[0].(string)]
+		if strings.HasPrefix(db.log[len(db.log)-1].sql, "SELECT sub_enabled") {
+			if !ok {
+				return nil, nil
+			}
+			return [][]any{{sub.enabled}}, nil
+		}
+		return exists(ok), nil
+	}},
+	{"DELETE FROM spock.exception_log", func(db *fakeDB, args []any) ([][]any, error) {
+		origin := args[0].(string)
+		commitTS, _ := args[1].(*time.Time)
+		db.cat.exceptions[origin] = slices.DeleteFunc(db.cat.exceptions[origin], func(e fakeException) bool {
+			return commitTS == nil || e.commitTS.Equal(*commitTS)
+		})
+		return nil, nil
+	}},
+	{"ORDER BY e.remote_commit_ts, e.command_counter LIMIT 1", func(db *fakeDB, args []any) ([][]any, error) {
+		exceptions := db.cat.exceptions[args[0].(string)]
+		if len(exceptions) == 0 {
+			return nil, nil
+		}
+		e := exceptions[0]
+		return [][]any{{e.commitTS, e.xid, "", "", e.err, e.commitTS}}, nil
+	}},
+	{"FROM spock.exception_log", func(db *fakeDB, args []any) ([][]any, error) {
+		exceptions := db.cat.exceptions[args[0].(string)]
+		latest := ""
+		if len(exceptions) > 0 {
+			latest = exceptions[len(exceptions)-1].err
+		}
+		return [][]any{{len(exceptions), latest}}, nil
+	}},
+	{"spock.get_lsn_from_commit_ts(", func(db *fakeDB, args []any) ([][]any, error) {
+		return [][]any{{fmt.Sprintf("0/%X", args[1].(time.Time).Unix())}}, nil
+	}},
+	{"spock.sub_alter_skiplsn(", func(db *fakeDB, args []any) ([][]any, error) {
+		sub, ok := db.cat.subs[args[0].(string)]
+		if !ok {
+			return nil, &pgconn.PgError{Code: "42704", Message: fmt.Sprintf("subscription %q not found", args[0])}
+		}
+		sub.skipLSN = args[1].(string)
+		return nil, nil
+	}},
+	{"spock.sub_create(", func(db *fakeDB, args []any) ([][]any, error) {
+		name := args[0].(string)
+		if _, ok := db.cat.subs[name]; ok {
+			return nil, nil // WHERE $1 NOT IN (SELECT sub_name FROM spock.subscription)
+		}
+		p, err := db.provider(args[1].(string))
+		if err != nil {
+			return nil, err
+		}
+		if p.cat.local == "" {
+			return nil, &pgconn.PgError{Code: "P0001", Message: "provider has no local spock node"}
+		}
+		// Subscription passes synchronize_data as a placeholder and
+		// DisabledSubscription as a literal.
+		sync, err := db.namedArg(args, "synchronize_data")
+		if err != nil {
+			return nil, err
+		}
+		enabled, err := db.namedArg(args, "enabled")
+		if err != nil {
+			return nil, err
+		}
+		repsets, err := db.namedArg(args, "replication_sets")
+		if err != nil {
+			return nil, err
+		}
+		db.cat.subs[name] = &fakeSub{provider: p.cat.local, repsets: repsets.([]string), enabled: enabled.(bool), sync: sync.(bool)}
+		if _, ok := db.cat.nodes[p.cat.local]; !ok {
+			db.cat.nodes[p.cat.local] = args[1].(string)
+		}
+		slot := db.slotFor(p.cat.local, name)
+		p.cat.slots[slot] = true
+		db.cat.origins[slot] = true
+		return nil, nil
+	}},
+	{"spock.sub_enable($1", func(db *fakeDB, args []any) ([][]any, error) {
+		return nil, db.setEnabled(args[0].(string), true)
+	}},
+	{"spock.sub_disable($1", func(db *fakeDB, args []any) ([][]any, error) {
+		return nil, db.setEnabled(args[0].(string), false)
+	}},
+	{"spock.sub_drop($1", func(db *fakeDB, args []any) ([][]any, error) {
+		name := args[0].(string)
+		sub, ok := db.cat.subs[name]
+		if !ok {
+			return nil, nil
+		}
+		delete(db.cat.subs, name)
+		slot := db.slotFor(sub.provider, name)
+		delete(db.cat.origins, slot)
+		for _, p := range db.mesh.dbs {
+			if p.cat.local == sub.provider {
+				delete(p.cat.slots, slot)
+			}
+		}
+		return nil, nil
+	}},
+	{"SELECT status FROM spock.sub_show_status()", func(db *fakeDB, args []any) ([][]any, error) {
+		sub, ok := db.cat.subs[args[0].(string)]
+		switch {
+		case !ok:
+			return nil, nil
+		case sub.enabled:
+			return [][]any{{"replicating"}}, nil
+		default:
+			return [][]any{{"disabled"}}, nil
+		}
+	}},
+	{"SELECT EXISTS(SELECT 1 FROM pg_replication_slots WHERE slot_name = $1)", func(db *fakeDB, args []any) ([][]any, error) {
+		return exists(db.cat.slots[args[0].(string)]), nil
+	}},
+	{"SELECT pg_create_logical_replication_slot($1, 'spock_output')", func(db *fakeDB, args []any) ([][]any, error) {
+		slot := args[0].(string)
+		if db.cat.slots[slot] {
+			return nil, &pgconn.PgError{Code: "42710", Message: fmt.Sprintf("replication slot %q already exists", slot)}
+		}
+		db.cat.slots[slot] = true
+		return nil, nil
+	}},
+	{"WHERE slot_name = $1 AND active_pid IS NOT NULL", func(db *fakeDB, args []any) ([][]any, error) {
+		// A slot is active while an enabled subscription streams from it.
+		for _, sub := range db.mesh.dbs {
+			for name, s := range sub.cat.subs {
+				if s.enabled && s.provider == db.cat.local && sub.slotFor(s.provider, name) == args[0] {
+					return exists(true), nil
+				}
+			}
+		}
+		return exists(false), nil
+	}},
+	{"SELECT restart_lsn::text FROM pg_replication_slots WHERE slot_name = $1", func(db *fakeDB, args []any) ([][]any, error) {
+		if !db.cat.slots[args[0].(string)] {
+			return nil, nil
+		}
+		return [][]any{{"0/0"}}, nil
+	}},
+	{"SELECT $1::pg_lsn <= $2::pg_lsn", func(_ *fakeDB, args []any) ([][]any, error) {
+		return [][]any{{parseLSN(args[0].(string)) <= parseLSN(args[1].(string))}}, nil
+	}},
+	{"pg_replication_slot_advance($1, $2::pg_lsn)", func(db *fakeDB, args []any) ([][]any, error) {
+		if !db.cat.slots[args[0].(string)] {
+			return nil, nil
+		}
+		return [][]any{{args[1]}}, nil
+	}},
+	{"SELECT pg_replication_origin_create($1)", func(db *fakeDB, args []any) ([][]any, error) {
+		db.cat.origins[args[0].(string)] = true
+		return nil, nil
+	}},
+	{"SELECT pg_replication_origin_advance($1, $2::pg_lsn)", func(db *fakeDB, args []any) ([][]any, error) {
+		if !db.cat.origins[args[0].(string)] {
+			return nil, &pgconn.PgError{Code: "42704", Message: fmt.Sprintf("replication origin %q does not exist", args[0])}
+		}
+		return nil, nil
+	}},
+	{"FROM spock.lag_tracker WHERE origin_name = $1 AND receiver_name = $2", func(db *fakeDB, args []any) ([][]any, error) {
+		// The receiver tracks a fixed last commit from each origin it has
+		// a subscription from, enabled or not.
+		if db.cat.local != args[1] {
+			return nil, nil
+		}
+		for _, sub := range db.cat.subs {
+			if sub.provider == args[0] {
+				return [][]any{{fakeCommitTS}}, nil
+			}
+		}
+		return nil, nil
+	}},
+	{"SELECT p.remote_lsn >= $1::pg_lsn FROM spock.progress p", func(db *fakeDB, args []any) ([][]any, error) {
+		lsn, err := db.remoteLSN(args[1].(string))
+		return [][]any{{err == nil && lsn >= parseLSN(args[0].(string))}}, nil
+	}},
+	{"SELECT p.remote_lsn::text FROM spock.progress p", func(db *fakeDB, args []any) ([][]any, error) {
+		lsn, err := db.remoteLSN(args[0].(string))
+		if err != nil {
+			return nil, nil
+		}
+		return [][]any{{fmt.Sprintf("%X/%X", lsn>>32, uint32(lsn))}}, nil
+	}},
+	{"SELECT pg_terminate_backend(active_pid)", noRows},
+	{"SELECT pg_drop_replication_slot($1)", func(db *fakeDB, args []any) ([][]any, error) {
+		slot := args[0].(string)
+		if !db.cat.slots[slot] {
+			return nil, &pgconn.PgError{Code: "42704", Message: fmt.Sprintf("replication slot %q does not exist", slot)}
+		}
+		delete(db.cat.slots, slot)
+		return nil, nil
+	}},
+	{"SELECT slot_name FROM pg_replication_slots WHERE slot_type = 'logical' AND slot_name LIKE 'spk_%'", func(db *fakeDB, _ []any) ([][]any, error) {
+		var rows [][]any
+		for _, slot := range sortedKeys(db.cat.slots) {
+			rows = append(rows, []any{slot})
+		}
+		return rows, nil
+	}},
+	{"SELECT spock.sync_event()", func(db *fakeDB, _ []any) ([][]any, error) {
+		db.cat.lsn += 0x100
+		return [][]any{{fmt.Sprintf("0/%X", db.cat.lsn)}}, nil
+	}},
+	{"CALL spock.wait_for_sync_event(", func(db *fakeDB, args []any) ([][]any, error) {
+		// The event has arrived once an enabled subscription from its
+		// provider replicates it.
+		for _, sub := range db.cat.subs {
+			if sub.provider == args[0] && sub.enabled {
+				return [][]any{{true}}, nil
+			}
+		}
+		return [][]any{{false}}, nil
+	}},
+}
+
+// fakeCommitTS is the last commit each subscriber applied from its
+// providers, as spock.lag_tracker reports it.
+var fakeCommitTS = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// parseLSN parses a "hi/lo" LSN; malformed ones parse as 0.
+func parseLSN(lsn string) uint64 {
+	hi, lo, _ := strings.Cut(lsn, "/")
+	h, _ := strconv.ParseUint(hi, 16, 32)
+	l, _ := strconv.ParseUint(lo, 16, 32)
+	return h<<32 | l
+}
+
+// remoteLSN returns how far the node has applied peer's changes: all of
+// them, up to its last sync event, while it has an enabled subscription
+// from peer.
+func (db *fakeDB) remoteLSN(peer string) (uint64, error) {
+	for _, sub := range db.cat.subs {
+		if sub.provider != peer || !sub.enabled {
+			continue
+		}
+		for _, p := range db.mesh.dbs {
+			if p.cat.local == peer {
+				return p.cat.lsn, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("fake %s: no progress from %s", db.name, peer)
+}
+
+// setEnabled enables or disables a subscription. Like Spock, it refuses
+// immediate := true in a transaction block; an immediate disable stops the
+// apply worker, and the next enable restarts it.
+func (db *fakeDB) setEnabled(name string, enabled bool) error {
+	stmt := db.log[len(db.log)-1].sql
+	immediate := strings.Contains(stmt, "immediate := true") || strings.Contains(stmt, "$1, true)")
+	if immediate && db.inTx {
+		return &pgconn.PgError{Code: "25001", Message: "sub_enable/sub_disable with immediate = true cannot be run inside a transaction block"}
+	}
+	sub, ok := db.cat.subs[name]
+	if !ok {
+		return &pgconn.PgError{Code: "42704", Message: fmt.Sprintf("subscription %q not found", name)}
+	}
+	switch {
+	case !enabled && immediate:
+		sub.stopped = true
+	case enabled && sub.stopped:
+		sub.stopped = false
+		sub.restarts++
+	}
+	sub.enabled = enabled
+	return nil
+}
+
+func noRows(*fakeDB, []any) ([][]any, error) { return nil, nil }
+
+func exists(b bool) [][]any { return [][]any{{b}} }
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// fakeTx is a transaction on a fakeDB. Methods the resources do not use
+// are left to the embedded nil pgx.Tx and panic.
+type fakeTx struct {
+	pgx.Tx
+	db    *fakeDB
+	id    int
+	saved fakeCatalog
+	done  bool
+}
+
+func (tx *fakeTx) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return tx.db.exec(tx, sql, args)
+}
+
+func (tx *fakeTx) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return tx.db.query(tx, sql, args)
+}
+
+func (tx *fakeTx) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	return tx.db.queryRow(tx, sql, args)
+}
+
+func (tx *fakeTx) Commit(_ context.Context) error {
+	return tx.end("COMMIT")
+}
+
+func (tx *fakeTx) Rollback(_ context.Context) error {
+	return tx.end("ROLLBACK")
+}
+
+func (tx *fakeTx) end(stmt string) error {
+	tx.db.mesh.mu.Lock()
+	defer tx.db.mesh.mu.Unlock()
+	if tx.done {
+		return pgx.ErrTxClosed
+	}
+	tx.done = true
+	tx.db.log = append(tx.db.log, fakeStmt{sql: stmt, tx: tx.id})
+	if stmt == "ROLLBACK" {
+		tx.db.cat = tx.saved
+	}
+	return nil
+}
+
+// fakeRows iterates over a fakeDB result. Methods the resources do not
+// use are left to the embedded nil pgx.Rows and panic.
+type fakeRows struct {
+	pgx.Rows
+	rows [][]any
+	i    int
+}
+
+func (r *fakeRows) Next() bool {
+	r.i++
+	return r.i < len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error { return scanFake(r.rows[r.i], dest) }
+func (r *fakeRows) Close()                 {}
+func (r *fakeRows) Err() error             { return nil }
+
+type fakeRow struct {
+	rows [][]any
+	err  error
+}
+
+func (r *fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	if len(r.rows) == 0 {
+		return pgx.ErrNoRows
+	}
+	return scanFake(r.rows[0], dest)
+}
+
+// scanFake assigns a row's values to dest, converting between numeric
+// types the way pgx does.
+func scanFake(row []any, dest []any) error {
+	if len(row) != len(dest) {
+		return fmt.Errorf("fake: scanning %d values into %d destinations", len(row), len(dest))
+	}
+	for i, v := range row {
+		d := reflect.ValueOf(dest[i]).Elem()
+		if v == nil {
+			d.SetZero()
+			continue
+		}
+		rv := reflect.ValueOf(v)
+		if !rv.Type().ConvertibleTo(d.Type()) || (rv.Kind() == reflect.String) != (d.Kind() == reflect.String) {
+			return fmt.Errorf("fake: cannot scan %T into %s", v, d.Type())
+		}
+		d.Set(rv.Convert(d.Type()))
+	}
+	return nil
+}
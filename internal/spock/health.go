@@ -0,0 +1,190 @@
+// internal/spock/health.go
+package spock
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+
+	"github.com/pgEdge/pgedge-helm/internal/config"
+)
+
+// SubscriptionHealth is the state of one subscription as reported by
+// spock.sub_show_status() on its subscriber, together with its provider
+// slot and the subscriber's spock.lag_tracker entry.
+type SubscriptionHealth struct {
+	Name       string `json:"name"`
+	Provider   string `json:"provider"`
+	Subscriber string `json:"subscriber"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+
+	Slot       string `json:"slot"`
+	SlotExists bool   `json:"slotExists"`
+	SlotActive bool   `json:"slotActive"`
+	// LagBytes is the provider's WAL not yet confirmed by the subscriber
+	// (pg_current_wal_lsn() - confirmed_flush_lsn); nil if unknown.
+	LagBytes *int64 `json:"lagBytes,omitempty"`
+	// CommitLagMs is the age in milliseconds of the last commit replicated
+	// per spock.lag_tracker; nil if unknown.
+	CommitLagMs *int64 `json:"commitLagMs,omitempty"`
+}
+
+// Healthy reports whether the subscription is replicating through an
+// active slot.
+func (h SubscriptionHealth) Healthy() bool {
+	return h.Error == "" && h.Status == "replicating" && h.SlotExists && h.SlotActive
+}
+
+// slotState is one provider-side Spock replication slot.
+type slotState struct {
+	active   bool
+	lagBytes *int64
+}
+
+// nodeState is what CheckSubscriptions reads from one node.
+type nodeState struct {
+	subs     map[string]string    // subscription name → status
+	slots    map[string]slotState // slot name → state
+	lagMs    map[string]int64     // origin node → commit lag
+	subsErr  error
+	slotsErr error
+}
+
+// CheckSubscriptions reports the status of every expected subscription in
+// the mesh. Subscriptions that cannot be inspected or do not exist are
+// included with Error set.
+func CheckSubscriptions(ctx context.Context, cfg *config.Config, conns map[string]DB) []SubscriptionHealth {
+	states := make(map[string]nodeState, len(cfg.Nodes))
+	for _, node := range cfg.Nodes {
+		states[node.Name] = readNodeState(ctx, node.Name, conns[node.Name])
+	}
+
+	var health []SubscriptionHealth
+	for _, dst := range cfg.Nodes {
+		for _, src := range cfg.Nodes {
+			if src.Name == dst.Name {
+				continue
+			}
+			health = append(health, pairHealth(cfg.DBName, src.Name, dst.Name, states[src.Name], states[dst.Name]))
+		}
+	}
+	sort.Slice(health, func(i, j int) bool { return health[i].Name < health[j].Name })
+	return health
+}
+
+func pairHealth(dbName, src, dst string, provider, subscriber nodeState) SubscriptionHealth {
+	h := SubscriptionHealth{
+		Name:       spockSubName(src, dst),
+		Provider:   src,
+		Subscriber: dst,
+		Slot:       spockSlotName(dbName, src, dst),
+	}
+	switch status, ok := subscriber.subs[h.Name]; {
+	case subscriber.subsErr != nil:
+		h.Error = subscriber.subsErr.Error()
+	case !ok:
+		h.Error = "subscription not found"
+	default:
+		h.Status = status
+	}
+
+	if provider.slotsErr != nil {
+		if h.Error == "" {
+			h.Error = "provider: " + provider.slotsErr.Error()
+		}
+	} else if slot, ok := provider.slots[h.Slot]; ok {
+		h.SlotExists = true
+		h.SlotActive = slot.active
+		h.LagBytes = slot.lagBytes
+	}
+	if lag, ok := subscriber.lagMs[src]; ok {
+		h.CommitLagMs = &lag
+	}
+	return h
+}
+
+func readNodeState(ctx context.Context, node string, conn DB) nodeState {
+	var s nodeState
+	if conn == nil {
+		s.subsErr = errors.New("not connected")
+		s.slotsErr = s.subsErr
+		return s
+	}
+	if s.subs, s.subsErr = subscriptionStatuses(ctx, conn); s.subsErr != nil {
+		slog.Warn("query subscription status", "node", node, "error", s.subsErr)
+	}
+	if s.slots, s.slotsErr = slotStates(ctx, conn); s.slotsErr != nil {
+		slog.Warn("query replication slots", "node", node, "error", s.slotsErr)
+	}
+	// The lag tracker is informational; a failure leaves CommitLagMs unset.
+	var err error
+	if s.lagMs, err = commitLags(ctx, conn, node); err != nil {
+		slog.Warn("query lag tracker", "node", node, "error", err)
+	}
+	return s
+}
+
+// subscriptionStatuses returns sub_show_status() keyed by subscription name.
+func subscriptionStatuses(ctx context.Context, conn DB) (map[string]string, error) {
+	rows, err := conn.Query(ctx, "SELECT subscription_name, status FROM spock.sub_show_status()")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	statuses := map[string]string{}
+	for rows.Next() {
+		var name, status string
+		if err := rows.Scan(&name, &status); err != nil {
+			return nil, err
+		}
+		statuses[name] = status
+	}
+	return statuses, rows.Err()
+}
+
+// slotStates returns the node's Spock logical slots keyed by name.
+func slotStates(ctx context.Context, conn DB) (map[string]slotState, error) {
+	rows, err := conn.Query(ctx, `
+		SELECT slot_name, active, pg_wal_lsn_diff(pg_current_wal_lsn(), confirmed_flush_lsn)::bigint
+		FROM pg_replication_slots
+		WHERE slot_type = 'logical' AND slot_name LIKE 'spk_%'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	slots := map[string]slotState{}
+	for rows.Next() {
+		var name string
+		var s slotState
+		if err := rows.Scan(&name, &s.active, &s.lagBytes); err != nil {
+			return nil, err
+		}
+		slots[name] = s
+	}
+	return slots, rows.Err()
+}
+
+// commitLags returns, per origin node, how old in milliseconds the last
+// commit replicated to receiver is according to spock.lag_tracker.
+func commitLags(ctx context.Context, conn DB, receiver string) (map[string]int64, error) {
+	rows, err := conn.Query(ctx, `
+		SELECT origin_name, (extract(epoch FROM now() - commit_timestamp) * 1000)::bigint
+		FROM spock.lag_tracker
+		WHERE receiver_name = $1 AND commit_timestamp IS NOT NULL`, receiver)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	lags := map[string]int64{}
+	for rows.Next() {
+		var origin string
+		var lag int64
+		if err := rows.Scan(&origin, &lag); err != nil {
+			return nil, err
+		}
+		lags[origin] = lag
+	}
+	return lags, rows.Err()
+}
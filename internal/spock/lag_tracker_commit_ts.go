@@ -9,7 +9,6 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/pgEdge/pgedge-helm/internal/resource"
 )
@@ -20,13 +19,13 @@ import (
 type LagTrackerCommitTimestamp struct {
 	originName   string // peer node
 	receiverName string // new node
-	conn         *pgxpool.Pool
+	conn         DB
 	extraDeps    []resource.Identifier
 	status       resource.Status
 	CommitTS     *time.Time // populated during Create
 }
 
-func NewLagTrackerCommitTimestamp(originName, receiverName string, conn *pgxpool.Pool, extraDeps ...resource.Identifier) *LagTrackerCommitTimestamp {
+func NewLagTrackerCommitTimestamp(originName, receiverName string, conn DB, extraDeps ...resource.Identifier) *LagTrackerCommitTimestamp {
 	return &LagTrackerCommitTimestamp{
 		originName:   originName,
 		receiverName: receiverName,
@@ -8,7 +8,6 @@ import (
 	"log/slog"
 
 	"github.com/jackc/pgx/v5/pgconn"
-	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/pgEdge/pgedge-helm/internal/config"
 	"github.com/pgEdge/pgedge-helm/internal/resource"
@@ -24,11 +23,12 @@ type SpockNode struct {
 	node       config.Node
 	dbName     string
 	pgedgeUser string
-	conn       *pgxpool.Pool
+	conn       DB
 	status     resource.Status
+	survivor   string // set for orphans: the node whose connection drops it
 }
 
-func NewSpockNode(node config.Node, dbName, pgedgeUser string, conn *pgxpool.Pool) *SpockNode {
+func NewSpockNode(node config.Node, dbName, pgedgeUser string, conn DB) *SpockNode {
 	return &SpockNode{
 		node:       node,
 		dbName:     dbName,
@@ -37,7 +37,12 @@ func NewSpockNode(node config.Node, dbName, pgedgeUser string, conn *pgxpool.Poo
 	}
 }
 
+// Identifier is scoped to the survivor for orphans, which are dropped once
+// per surviving node: "n3@n1".
 func (n *SpockNode) Identifier() resource.Identifier {
+	if n.survivor != "" {
+		return resource.Identifier{Type: ResourceTypeNode, ID: fmt.Sprintf("%s@%s", n.node.Name, n.survivor)}
+	}
 	return resource.Identifier{Type: ResourceTypeNode, ID: n.node.Name}
 }
 
@@ -61,7 +66,7 @@ func (n *SpockNode) Refresh(ctx context.Context) error {
 func (n *SpockNode) Status() resource.Status { return n.status }
 
 func (n *SpockNode) Create(ctx context.Context) error {
-	tx, err := n.conn.Begin(ctx)
+	tx, err := beginOp(ctx, n.conn, opCatalog)
 	if err != nil {
 		return fmt.Errorf("begin tx on %s: %w", n.node.Name, err)
 	}
@@ -106,7 +111,7 @@ func (n *SpockNode) Delete(ctx context.Context) error {
 
 // deleteOne drops a single node reference (orphan cleanup).
 func (n *SpockNode) deleteOne(ctx context.Context) error {
-	tx, err := n.conn.Begin(ctx)
+	tx, err := beginOp(ctx, n.conn, opCatalog)
 	if err != nil {
 		return fmt.Errorf("begin delete tx on %s: %w", n.node.Name, err)
 	}
@@ -7,13 +7,10 @@ import (
 	"log/slog"
 	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
-
+	"github.com/pgEdge/pgedge-helm/internal/config"
 	"github.com/pgEdge/pgedge-helm/internal/resource"
 )
 
-const peerCatchupPollInterval = 500 * time.Millisecond
-
 // PeerCatchup waits until the source node's apply progress from the peer
 // node has reached the peer's sync event LSN. This ensures the source→new
 // COPY snapshot includes all peer writes up to the slot creation point,
@@ -26,20 +23,25 @@ const peerCatchupPollInterval = 500 * time.Millisecond
 // Reads the target LSN from the paired SyncEvent (peer→source) via
 // struct pointer.
 //
+// Polls every wait.Interval and fails once wait.Timeout expires, reporting
+// the remote_lsn reached, so a stalled peer does not use up the whole run.
+//
 // Ephemeral — re-executes every run.
 type PeerCatchup struct {
 	peerName   string
 	sourceName string
 	syncEvent  *SyncEvent
-	conn       *pgxpool.Pool // source's connection
+	wait       config.Wait
+	conn       DB // source's connection
 	status     resource.Status
 }
 
-func NewPeerCatchup(peerName, sourceName string, syncEvent *SyncEvent, conn *pgxpool.Pool) *PeerCatchup {
+func NewPeerCatchup(peerName, sourceName string, syncEvent *SyncEvent, wait config.Wait, conn DB) *PeerCatchup {
 	return &PeerCatchup{
 		peerName:   peerName,
 		sourceName: sourceName,
 		syncEvent:  syncEvent,
+		wait:       wait,
 		conn:       conn,
 	}
 }
@@ -73,13 +75,13 @@ func (r *PeerCatchup) Create(ctx context.Context) error {
 	slog.Info("waiting for peer apply to catch up",
 		"peer", r.peerName, "source", r.sourceName, "target_lsn", r.syncEvent.LSN)
 
-	for {
-		if err := ctx.Err(); err != nil {
-			return err
-		}
+	waitCtx, cancel := r.wait.Context(ctx)
+	defer cancel()
+	step := fmt.Sprintf("peer catchup of %s on %s", r.peerName, r.sourceName)
 
+	for {
 		var reached bool
-		err := r.conn.QueryRow(ctx, `
+		err := r.conn.QueryRow(waitCtx, `
 			SELECT COALESCE(
 				(SELECT p.remote_lsn >= $1::pg_lsn
 				 FROM spock.progress p
@@ -90,6 +92,9 @@ func (r *PeerCatchup) Create(ctx context.Context) error {
 			)`, r.syncEvent.LSN, r.peerName,
 		).Scan(&reached)
 		if err != nil {
+			if waitCtx.Err() != nil {
+				return waitError(ctx, step, r.wait.Timeout, describeApply(ctx, r.conn, r.peerName, r.syncEvent.LSN))
+			}
 			return fmt.Errorf("query spock.progress on %s for peer %s: %w",
 				r.sourceName, r.peerName, err)
 		}
@@ -101,9 +106,9 @@ func (r *PeerCatchup) Create(ctx context.Context) error {
 		}
 
 		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(peerCatchupPollInterval):
+		case <-waitCtx.Done():
+			return waitError(ctx, step, r.wait.Timeout, describeApply(ctx, r.conn, r.peerName, r.syncEvent.LSN))
+		case <-time.After(r.wait.Interval):
 		}
 	}
 }
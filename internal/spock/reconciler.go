@@ -4,8 +4,6 @@ package spock
 import (
 	"context"
 
-	"github.com/jackc/pgx/v5/pgxpool"
-
 	"github.com/pgEdge/pgedge-helm/internal/config"
 	"github.com/pgEdge/pgedge-helm/internal/resource"
 )
@@ -16,16 +14,19 @@ var _ resource.Reconciler = (*SpockReconciler)(nil)
 // SpockReconciler implements resource.Reconciler for Spock replication resources.
 type SpockReconciler struct {
 	cfg   *config.Config
-	conns map[string]*pgxpool.Pool
+	conns map[string]DB
+	dump  SchemaDumper
 }
 
-// NewReconciler creates a SpockReconciler from config and database connections.
-func NewReconciler(cfg *config.Config, conns map[string]*pgxpool.Pool) *SpockReconciler {
-	return &SpockReconciler{cfg: cfg, conns: conns}
+// NewReconciler creates a SpockReconciler from config and database
+// connections. dump copies schemas for nodes bootstrapped with structure
+// "dump".
+func NewReconciler(cfg *config.Config, conns map[string]DB, dump SchemaDumper) *SpockReconciler {
+	return &SpockReconciler{cfg: cfg, conns: conns, dump: dump}
 }
 
 func (r *SpockReconciler) ComputeDesired() map[resource.Identifier]resource.Resource {
-	return ComputeDesired(r.cfg, r.conns)
+	return ComputeDesired(r.cfg, r.conns, r.dump)
 }
 
 func (r *SpockReconciler) RefreshActual(ctx context.Context, desired map[resource.Identifier]resource.Resource) (map[resource.Identifier]resource.Resource, error) {
@@ -6,8 +6,6 @@ import (
 	"fmt"
 	"log/slog"
 
-	"github.com/jackc/pgx/v5/pgxpool"
-
 	"github.com/pgEdge/pgedge-helm/internal/config"
 	"github.com/pgEdge/pgedge-helm/internal/resource"
 )
@@ -19,13 +17,13 @@ import (
 func RefreshActual(
 	ctx context.Context,
 	cfg *config.Config,
-	conns map[string]*pgxpool.Pool,
+	conns map[string]DB,
 	desired map[resource.Identifier]resource.Resource,
 ) (map[resource.Identifier]resource.Resource, error) {
 	actual := make(map[resource.Identifier]resource.Resource)
 
 	for id, r := range desired {
-		if err := r.Refresh(ctx); err != nil {
+		if err := r.Refresh(resource.WithIdentifier(ctx, id)); err != nil {
 			return nil, fmt.Errorf("refresh %s/%s: %w", id.Type, id.ID, err)
 		}
 		if r.Status().Exists {
@@ -66,25 +64,28 @@ func checkSlotHealth(actual map[resource.Identifier]resource.Resource) {
 
 // discoverOrphans queries each surviving node for Spock nodes, subscriptions,
 // and replication slots not in the config, adding them to the actual map.
+// Nodes in cfg.Excluded are not orphans.
 func discoverOrphans(
 	ctx context.Context,
 	cfg *config.Config,
-	conns map[string]*pgxpool.Pool,
+	conns map[string]DB,
 	actual map[resource.Identifier]resource.Resource,
 ) {
-	configNames := make([]string, len(cfg.Nodes))
-	for i, n := range cfg.Nodes {
-		configNames[i] = n.Name
+	// Excluded nodes are still part of the mesh, only left out of this run.
+	configNames := make([]string, 0, len(cfg.Nodes)+len(cfg.Excluded))
+	for _, n := range cfg.Nodes {
+		configNames = append(configNames, n.Name)
 	}
+	configNames = append(configNames, cfg.Excluded...)
 
 	// Compute expected slot names for orphan slot detection.
 	expectedSlots := make(map[string]bool)
-	for _, src := range cfg.Nodes {
-		for _, dst := range cfg.Nodes {
-			if src.Name == dst.Name {
+	for _, src := range configNames {
+		for _, dst := range configNames {
+			if src == dst {
 				continue
 			}
-			slot := NewReplicationSlot(src.Name, dst.Name, cfg.DBName, nil)
+			slot := NewReplicationSlot(src, dst, cfg.DBName, nil)
 			expectedSlots[slot.slotName()] = true
 		}
 	}
@@ -101,7 +102,7 @@ func discoverOrphans(
 func discoverOrphanNodes(
 	ctx context.Context,
 	cfg *config.Config,
-	conn *pgxpool.Pool,
+	conn DB,
 	survivor config.Node,
 	configNames []string,
 	actual map[resource.Identifier]resource.Resource,
@@ -122,21 +123,19 @@ func discoverOrphanNodes(
 		orphanCfg := config.Node{Name: orphanName}
 
 		// Create one SpockNode per (orphan, survivor) pair so node_drop
-		// runs on every survivor's connection. Use a survivor-scoped ID
-		// to avoid collisions in the actual map.
-		nodeID := resource.Identifier{
-			Type: ResourceTypeNode,
-			ID:   fmt.Sprintf("%s@%s", orphanName, survivor.Name),
-		}
+		// runs on every survivor's connection. Its identifier is scoped to
+		// the survivor to avoid collisions in the actual map and the plan.
 		n := NewSpockNode(orphanCfg, cfg.DBName, cfg.PgEdgeUser, conn)
 		n.status = resource.Status{Exists: true}
-		actual[nodeID] = n
+		n.survivor = survivor.Name
+		actual[n.Identifier()] = n
 		slog.Info("discovered orphan node", "orphan", orphanName, "survivor", survivor.Name)
 
 		// Infer one orphan subscription per surviving connection.
 		// The topology is fully meshed, so each surviving node had a subscription
 		// from the orphan. sub_drop($1, true) is safe if it no longer exists.
-		sub := NewSubscription(orphanCfg, survivor, cfg.DBName, cfg.PgEdgeUser, false, conn)
+		// It is dropped before the survivor's orphan node.
+		sub := NewSubscription(orphanCfg, survivor, cfg.DBName, cfg.PgEdgeUser, false, conn, n.Identifier())
 		sub.status = resource.Status{Exists: true}
 		actual[sub.Identifier()] = sub
 		slog.Info("discovered orphan subscription", "sub", sub.subName(), "survivor", survivor.Name)
@@ -151,7 +150,7 @@ func discoverOrphanNodes(
 func discoverOrphanSlots(
 	ctx context.Context,
 	cfg *config.Config,
-	conn *pgxpool.Pool,
+	conn DB,
 	survivor config.Node,
 	expectedSlots map[string]bool,
 	actual map[resource.Identifier]resource.Resource,
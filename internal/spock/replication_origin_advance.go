@@ -6,8 +6,6 @@ import (
 	"fmt"
 	"log/slog"
 
-	"github.com/jackc/pgx/v5/pgxpool"
-
 	"github.com/pgEdge/pgedge-helm/internal/resource"
 )
 
@@ -29,11 +27,11 @@ type ReplicationOriginAdvance struct {
 	subscriberName string
 	dbName         string
 	slotAdvance    *ReplicationSlotAdvanceFromCTS
-	conn           *pgxpool.Pool // subscriber's connection
+	conn           DB // subscriber's connection
 	status         resource.Status
 }
 
-func NewReplicationOriginAdvance(providerName, subscriberName, dbName string, slotAdvance *ReplicationSlotAdvanceFromCTS, conn *pgxpool.Pool) *ReplicationOriginAdvance {
+func NewReplicationOriginAdvance(providerName, subscriberName, dbName string, slotAdvance *ReplicationSlotAdvanceFromCTS, conn DB) *ReplicationOriginAdvance {
 	return &ReplicationOriginAdvance{
 		providerName:   providerName,
 		subscriberName: subscriberName,
@@ -83,7 +81,7 @@ func (r *ReplicationOriginAdvance) Create(ctx context.Context) error {
 	// Create the origin if it doesn't already exist. spock.sub_create
 	// usually creates it, but the NOT EXISTS guard makes this safe in
 	// any ordering. Matches upstream's EnsureReplicationOriginExists.
-	_, err := r.conn.Exec(ctx, `
+	err := execOp(ctx, r.conn, opCatalog, `
 		SELECT pg_replication_origin_create($1)
 		WHERE NOT EXISTS (
 			SELECT 1 FROM pg_replication_origin WHERE roname = $1
@@ -92,7 +90,7 @@ func (r *ReplicationOriginAdvance) Create(ctx context.Context) error {
 		return fmt.Errorf("ensure replication origin %s: %w", originName, err)
 	}
 
-	_, err = r.conn.Exec(ctx,
+	err = execOp(ctx, r.conn, opCatalog,
 		"SELECT pg_replication_origin_advance($1, $2::pg_lsn)",
 		originName, targetLSN,
 	)
@@ -6,8 +6,6 @@ import (
 	"fmt"
 	"log/slog"
 
-	"github.com/jackc/pgx/v5/pgxpool"
-
 	"github.com/pgEdge/pgedge-helm/internal/resource"
 )
 
@@ -18,11 +16,11 @@ type ReplicationSlot struct {
 	subscriberName string
 	dbName         string
 	nameOverride   string // set for orphan slots discovered by raw name
-	conn           *pgxpool.Pool
+	conn           DB
 	status         resource.Status
 }
 
-func NewReplicationSlot(providerName, subscriberName, dbName string, conn *pgxpool.Pool) *ReplicationSlot {
+func NewReplicationSlot(providerName, subscriberName, dbName string, conn DB) *ReplicationSlot {
 	return &ReplicationSlot{
 		providerName:   providerName,
 		subscriberName: subscriberName,
@@ -70,7 +68,7 @@ func (r *ReplicationSlot) Update(_ context.Context) error { return nil }
 
 // Delete terminates any active walsender and drops the replication slot.
 func (r *ReplicationSlot) Delete(ctx context.Context) error {
-	tx, err := r.conn.Begin(ctx)
+	tx, err := beginOp(ctx, r.conn, opCatalog)
 	if err != nil {
 		return fmt.Errorf("begin delete tx for slot %s: %w", r.slotName(), err)
 	}
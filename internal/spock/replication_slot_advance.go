@@ -6,8 +6,6 @@ import (
 	"fmt"
 	"log/slog"
 
-	"github.com/jackc/pgx/v5/pgxpool"
-
 	"github.com/pgEdge/pgedge-helm/internal/resource"
 )
 
@@ -27,7 +25,7 @@ type ReplicationSlotAdvanceFromCTS struct {
 	subscriberName string // new node
 	dbName         string
 	lagTracker     *LagTrackerCommitTimestamp
-	conn           *pgxpool.Pool // peer's connection
+	conn           DB // peer's connection
 	status         resource.Status
 
 	// AdvancedToLSN records the LSN the slot was advanced to.
@@ -37,7 +35,7 @@ type ReplicationSlotAdvanceFromCTS struct {
 	AdvancedToLSN string
 }
 
-func NewReplicationSlotAdvanceFromCTS(providerName, subscriberName, dbName string, lagTracker *LagTrackerCommitTimestamp, conn *pgxpool.Pool) *ReplicationSlotAdvanceFromCTS {
+func NewReplicationSlotAdvanceFromCTS(providerName, subscriberName, dbName string, lagTracker *LagTrackerCommitTimestamp, conn DB) *ReplicationSlotAdvanceFromCTS {
 	return &ReplicationSlotAdvanceFromCTS{
 		providerName:   providerName,
 		subscriberName: subscriberName,
@@ -137,7 +135,7 @@ func (r *ReplicationSlotAdvanceFromCTS) Create(ctx context.Context) error {
 	}
 
 	// Advance the slot.
-	_, err = r.conn.Exec(ctx, `
+	err = execOp(ctx, r.conn, opLong, `
 		WITH current AS (
 			SELECT confirmed_flush_lsn
 			FROM pg_replication_slots
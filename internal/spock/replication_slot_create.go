@@ -6,8 +6,6 @@ import (
 	"fmt"
 	"log/slog"
 
-	"github.com/jackc/pgx/v5/pgxpool"
-
 	"github.com/pgEdge/pgedge-helm/internal/resource"
 )
 
@@ -18,11 +16,11 @@ type ReplicationSlotCreate struct {
 	providerName   string
 	subscriberName string
 	dbName         string
-	conn           *pgxpool.Pool
+	conn           DB
 	status         resource.Status
 }
 
-func NewReplicationSlotCreate(providerName, subscriberName, dbName string, conn *pgxpool.Pool) *ReplicationSlotCreate {
+func NewReplicationSlotCreate(providerName, subscriberName, dbName string, conn DB) *ReplicationSlotCreate {
 	return &ReplicationSlotCreate{
 		providerName:   providerName,
 		subscriberName: subscriberName,
@@ -65,7 +63,7 @@ func (r *ReplicationSlotCreate) Create(ctx context.Context) error {
 		return nil
 	}
 
-	_, err = r.conn.Exec(ctx,
+	err = execOp(ctx, r.conn, opLong,
 		"SELECT pg_create_logical_replication_slot($1, 'spock_output')",
 		r.slotName(),
 	)
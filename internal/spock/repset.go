@@ -5,37 +5,58 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-
-	"github.com/jackc/pgx/v5/pgxpool"
+	"strings"
 )
 
-// replicationSet holds an in-memory snapshot of a spock replication set.
+// replicationSet holds a snapshot of a spock replication set.
 type replicationSet struct {
-	name             string
-	replicateInsert  bool
-	replicateUpdate  bool
-	replicateDelete  bool
-	replicateTruncate bool
+	Name              string `json:"name"`
+	ReplicateInsert   bool   `json:"replicateInsert"`
+	ReplicateUpdate   bool   `json:"replicateUpdate"`
+	ReplicateDelete   bool   `json:"replicateDelete"`
+	ReplicateTruncate bool   `json:"replicateTruncate"`
 }
 
-// replicationSetTable holds an in-memory snapshot of a table assignment.
+// replicationSetTable holds a snapshot of a table assignment.
 type replicationSetTable struct {
-	setName   string
-	tableName string
-	attList   *string
-	rowFilter *string
+	SetName   string  `json:"setName"`
+	TableName string  `json:"tableName"`
+	AttList   *string `json:"attList,omitempty"`
+	RowFilter *string `json:"rowFilter,omitempty"`
+}
+
+// replicationSetSeq holds a snapshot of a sequence assignment.
+type replicationSetSeq struct {
+	SetName string `json:"setName"`
+	SeqName string `json:"seqName"`
 }
 
-// repsetSnapshot holds the in-memory backup of replication set configuration.
+// repsetSnapshot holds the backup of replication set configuration taken
+// before a reset. It is persisted by saveSnapshot so that a crash between
+// dropping and restoring Spock does not lose it.
 type repsetSnapshot struct {
-	sets   []replicationSet
-	tables []replicationSetTable
+	Sets      []replicationSet      `json:"sets"`
+	Tables    []replicationSetTable `json:"tables"`
+	Sequences []replicationSetSeq   `json:"sequences,omitempty"`
 }
 
-// backupRepsets reads replication set configuration into memory.
+// backupRepsets reads replication set configuration.
 // This must be called before DROP EXTENSION since the spock schema
 // is destroyed by the cascade.
-func backupRepsets(ctx context.Context, conn *pgxpool.Pool, nodeName string) (*repsetSnapshot, error) {
+func backupRepsets(ctx context.Context, conn DB, nodeName string) (*repsetSnapshot, error) {
+	snap, err := readRepsets(ctx, conn, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("backed up repsets", "node", nodeName, "sets", len(snap.Sets), "tables", len(snap.Tables), "sequences", len(snap.Sequences))
+	return snap, nil
+}
+
+// readRepsets reads the replication sets of a node and their members.
+// Partitions are read individually, like any other table, so a restore
+// puts back exactly the partitions that were members. Members whose
+// relation no longer resolves are logged and left out.
+func readRepsets(ctx context.Context, conn DB, nodeName string) (*repsetSnapshot, error) {
 	snap := &repsetSnapshot{}
 
 	// Read all replication sets (including built-in).
@@ -51,54 +72,89 @@ func backupRepsets(ctx context.Context, conn *pgxpool.Pool, nodeName string) (*r
 	defer rows.Close()
 	for rows.Next() {
 		var s replicationSet
-		if err := rows.Scan(&s.name, &s.replicateInsert, &s.replicateUpdate, &s.replicateDelete, &s.replicateTruncate); err != nil {
+		if err := rows.Scan(&s.Name, &s.ReplicateInsert, &s.ReplicateUpdate, &s.ReplicateDelete, &s.ReplicateTruncate); err != nil {
 			return nil, fmt.Errorf("scan replication set on %s: %w", nodeName, err)
 		}
-		snap.sets = append(snap.sets, s)
+		snap.Sets = append(snap.Sets, s)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("read replication sets on %s: %w", nodeName, err)
 	}
 
-	// Read replication set table assignments.
+	// Read replication set table assignments, partitioned parents first.
 	trows, err := conn.Query(ctx, `
-		SELECT rs.set_name, rst.set_reloid::regclass::text, rst.set_att_list, rst.set_row_filter
+		SELECT rs.set_name, rst.set_reloid::oid::text, c.oid::regclass::text, rst.set_att_list, rst.set_row_filter
 		FROM spock.replication_set_table rst
 		JOIN spock.replication_set rs ON rst.set_id = rs.set_id
-		WHERE EXISTS (
-			SELECT 1 FROM pg_class c
-			JOIN pg_namespace n ON n.oid = c.relnamespace
-			WHERE c.oid = rst.set_reloid AND c.relkind IN ('r', 'v')
-		)
-		ORDER BY rst.set_id, rst.set_reloid`)
+		LEFT JOIN pg_class c ON c.oid = rst.set_reloid AND c.relkind IN ('r', 'v', 'p')
+		ORDER BY rst.set_id, coalesce(c.relispartition, false), rst.set_reloid`)
 	if err != nil {
 		return nil, fmt.Errorf("query replication set tables on %s: %w", nodeName, err)
 	}
 	defer trows.Close()
 	for trows.Next() {
 		var t replicationSetTable
-		if err := trows.Scan(&t.setName, &t.tableName, &t.attList, &t.rowFilter); err != nil {
+		var oid string
+		var name *string
+		if err := trows.Scan(&t.SetName, &oid, &name, &t.AttList, &t.RowFilter); err != nil {
 			return nil, fmt.Errorf("scan replication set table on %s: %w", nodeName, err)
 		}
-		snap.tables = append(snap.tables, t)
+		if name == nil {
+			slog.Warn("skipping replication set table that no longer resolves", "node", nodeName, "set", t.SetName, "oid", oid)
+			continue
+		}
+		t.TableName = *name
+		snap.Tables = append(snap.Tables, t)
 	}
 	if err := trows.Err(); err != nil {
 		return nil, fmt.Errorf("read replication set tables on %s: %w", nodeName, err)
 	}
 
-	slog.Info("backed up repsets to memory", "node", nodeName, "sets", len(snap.sets), "tables", len(snap.tables))
+	// Read replication set sequence assignments.
+	srows, err := conn.Query(ctx, `
+		SELECT rs.set_name, rss.set_seqoid::oid::text, c.oid::regclass::text
+		FROM spock.replication_set_seq rss
+		JOIN spock.replication_set rs ON rss.set_id = rs.set_id
+		LEFT JOIN pg_class c ON c.oid = rss.set_seqoid AND c.relkind = 'S'
+		ORDER BY rss.set_id, rss.set_seqoid`)
+	if err != nil {
+		return nil, fmt.Errorf("query replication set sequences on %s: %w", nodeName, err)
+	}
+	defer srows.Close()
+	for srows.Next() {
+		var q replicationSetSeq
+		var oid string
+		var name *string
+		if err := srows.Scan(&q.SetName, &oid, &name); err != nil {
+			return nil, fmt.Errorf("scan replication set sequence on %s: %w", nodeName, err)
+		}
+		if name == nil {
+			slog.Warn("skipping replication set sequence that no longer resolves", "node", nodeName, "set", q.SetName, "oid", oid)
+			continue
+		}
+		q.SeqName = *name
+		snap.Sequences = append(snap.Sequences, q)
+	}
+	if err := srows.Err(); err != nil {
+		return nil, fmt.Errorf("read replication set sequences on %s: %w", nodeName, err)
+	}
+
 	return snap, nil
 }
 
-// restoreRepsets writes the in-memory replication set snapshot back to the database.
-// Must be called after the spock extension and local node have been recreated.
-func restoreRepsets(ctx context.Context, conn *pgxpool.Pool, nodeName string, snap *repsetSnapshot) error {
-	if snap == nil || (len(snap.sets) == 0 && len(snap.tables) == 0) {
+// restoreRepsets writes a replication set snapshot back to the database
+// and verifies that every set and member in it is present afterwards.
+// Must be called after the spock extension and local node have been
+// recreated. Sets and members that already exist are left alone, so
+// restoring a snapshot that was partly or fully applied before a crash is
+// safe.
+func restoreRepsets(ctx context.Context, conn DB, nodeName string, snap *repsetSnapshot) error {
+	if snap == nil || (len(snap.Sets) == 0 && len(snap.Tables) == 0 && len(snap.Sequences) == 0) {
 		slog.Info("no repsets to restore", "node", nodeName)
 		return nil
 	}
 
-	tx, err := conn.Begin(ctx)
+	tx, err := beginOp(ctx, conn, opCatalog)
 	if err != nil {
 		return fmt.Errorf("begin repset restore on %s: %w", nodeName, err)
 	}
@@ -106,32 +162,96 @@ func restoreRepsets(ctx context.Context, conn *pgxpool.Pool, nodeName string, sn
 
 	// Recreate custom replication sets (built-in ones already exist after CREATE EXTENSION).
 	builtinSets := map[string]bool{"default": true, "default_insert_only": true, "ddl_sql": true}
-	for _, s := range snap.sets {
-		if builtinSets[s.name] {
+	for _, s := range snap.Sets {
+		if builtinSets[s.Name] {
 			continue
 		}
-		_, err := tx.Exec(ctx,
-			"SELECT spock.repset_create($1, $2, $3, $4, $5)",
-			s.name, s.replicateInsert, s.replicateUpdate, s.replicateDelete, s.replicateTruncate)
+		_, err := tx.Exec(ctx, `
+			SELECT spock.repset_create($1, $2, $3, $4, $5)
+			WHERE NOT EXISTS (SELECT 1 FROM spock.replication_set WHERE set_name = $1)`,
+			s.Name, s.ReplicateInsert, s.ReplicateUpdate, s.ReplicateDelete, s.ReplicateTruncate)
+		if err != nil {
+			return fmt.Errorf("recreate repset %q on %s: %w", s.Name, nodeName, err)
+		}
+	}
+
+	// Re-add tables to replication sets. Partitions were snapshotted as
+	// members of their own, so they are not added along with their parent.
+	for _, t := range snap.Tables {
+		_, err := tx.Exec(ctx, `
+			SELECT spock.repset_add_table($1, $2, false, $3, $4, false)
+			WHERE NOT EXISTS (
+				SELECT 1 FROM spock.replication_set_table rst
+				JOIN spock.replication_set rs ON rs.set_id = rst.set_id
+				WHERE rs.set_name = $1 AND rst.set_reloid = $2::regclass
+			)`,
+			t.SetName, t.TableName, t.AttList, t.RowFilter)
 		if err != nil {
-			return fmt.Errorf("recreate repset %q on %s: %w", s.name, nodeName, err)
+			return fmt.Errorf("add table %q to repset %q on %s: %w", t.TableName, t.SetName, nodeName, err)
 		}
+		slog.Info("restored table to repset", "node", nodeName, "table", t.TableName, "set", t.SetName)
 	}
 
-	// Re-add tables to replication sets.
-	for _, t := range snap.tables {
-		_, err := tx.Exec(ctx,
-			"SELECT spock.repset_add_table($1, $2, false, $3, $4)",
-			t.setName, t.tableName, t.attList, t.rowFilter)
+	// Re-add sequences to replication sets.
+	for _, q := range snap.Sequences {
+		_, err := tx.Exec(ctx, `
+			SELECT spock.repset_add_seq($1, $2, false)
+			WHERE NOT EXISTS (
+				SELECT 1 FROM spock.replication_set_seq rss
+				JOIN spock.replication_set rs ON rs.set_id = rss.set_id
+				WHERE rs.set_name = $1 AND rss.set_seqoid = $2::regclass
+			)`,
+			q.SetName, q.SeqName)
 		if err != nil {
-			return fmt.Errorf("add table %q to repset %q on %s: %w", t.tableName, t.setName, nodeName, err)
+			return fmt.Errorf("add sequence %q to repset %q on %s: %w", q.SeqName, q.SetName, nodeName, err)
 		}
-		slog.Info("restored table to repset", "node", nodeName, "table", t.tableName, "set", t.setName)
+		slog.Info("restored sequence to repset", "node", nodeName, "sequence", q.SeqName, "set", q.SetName)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("commit repset restore on %s: %w", nodeName, err)
 	}
+
+	actual, err := readRepsets(ctx, conn, nodeName)
+	if err != nil {
+		return fmt.Errorf("verify repset restore: %w", err)
+	}
+	if missing := missingRepsetMembers(snap, actual); len(missing) > 0 {
+		return fmt.Errorf("repset restore on %s is incomplete, missing %s", nodeName, strings.Join(missing, ", "))
+	}
 	slog.Info("restored repsets", "node", nodeName)
 	return nil
 }
+
+// missingRepsetMembers lists the sets, tables and sequences of want that
+// are not in got, e.g. `table public.orders in set "custom"`.
+func missingRepsetMembers(want, got *repsetSnapshot) []string {
+	have := make(map[string]bool)
+	for _, s := range got.Sets {
+		have["set "+s.Name] = true
+	}
+	for _, t := range got.Tables {
+		have[fmt.Sprintf("table %s in set %q", t.TableName, t.SetName)] = true
+	}
+	for _, q := range got.Sequences {
+		have[fmt.Sprintf("sequence %s in set %q", q.SeqName, q.SetName)] = true
+	}
+
+	var missing []string
+	for _, s := range want.Sets {
+		if key := "set " + s.Name; !have[key] {
+			missing = append(missing, fmt.Sprintf("set %q", s.Name))
+		}
+	}
+	for _, t := range want.Tables {
+		if key := fmt.Sprintf("table %s in set %q", t.TableName, t.SetName); !have[key] {
+			missing = append(missing, key)
+		}
+	}
+	for _, q := range want.Sequences {
+		if key := fmt.Sprintf("sequence %s in set %q", q.SeqName, q.SetName); !have[key] {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
@@ -5,8 +5,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-
-	"github.com/jackc/pgx/v5/pgxpool"
+	"slices"
 
 	"github.com/pgEdge/pgedge-helm/internal/config"
 )
@@ -14,33 +13,113 @@ import (
 // ResetSpock drops and recreates Spock on every node connection.
 // This follows the Control Plane pattern: backup repsets, nuke spock,
 // reinitialize with correct config, restore repsets.
-func ResetSpock(ctx context.Context, cfg *config.Config, conns map[string]*pgxpool.Pool) error {
+func ResetSpock(ctx context.Context, cfg *config.Config, conns map[string]DB, mirror SnapshotMirror) error {
 	for _, node := range cfg.Nodes {
 		conn := conns[node.Name]
-		if err := resetNode(ctx, conn, node, cfg.DBName, cfg.PgEdgeUser); err != nil {
+		if err := resetNode(ctx, conn, node, cfg.DBName, cfg.PgEdgeUser, mirror); err != nil {
 			return fmt.Errorf("reset spock on %s: %w", node.Name, err)
 		}
 	}
 	return nil
 }
 
+// ResetNodes drops and recreates Spock on the named nodes only. Their
+// peers keep replicating among themselves: the subscriptions the peers
+// have to each reset node, with their origins, and the slots the reset
+// nodes' own subscriptions use on the peers are removed, so the reconcile
+// that follows rebuilds both directions from scratch.
+func ResetNodes(ctx context.Context, cfg *config.Config, conns map[string]DB, names []string, mirror SnapshotMirror) error {
+	for _, name := range names {
+		var node config.Node
+		for _, n := range cfg.Nodes {
+			if n.Name == name {
+				node = n
+			}
+		}
+		if node.Name == "" {
+			return fmt.Errorf("reset spock: unknown node %q", name)
+		}
+		slog.Info("resetting node", "node", name)
+
+		// Drop the peers' subscriptions first, while the node still has
+		// the slots they use, so Spock can drop those cleanly too.
+		for _, peer := range cfg.Nodes {
+			if peer.Name == name {
+				continue
+			}
+			if err := dropPeerSubscription(ctx, conns[peer.Name], node, peer, cfg.DBName); err != nil {
+				return fmt.Errorf("reset spock on %s: %w", name, err)
+			}
+		}
+		if err := resetNode(ctx, conns[name], node, cfg.DBName, cfg.PgEdgeUser, mirror); err != nil {
+			return fmt.Errorf("reset spock on %s: %w", name, err)
+		}
+		// The node's own subscriptions went with its Spock catalog; their
+		// slots on the peers are left behind.
+		for _, peer := range cfg.Nodes {
+			if peer.Name == name {
+				continue
+			}
+			slot := NewReplicationSlot(peer.Name, name, cfg.DBName, conns[peer.Name])
+			if err := slot.Delete(ctx); err != nil {
+				return fmt.Errorf("reset spock on %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// dropPeerSubscription drops the subscription a peer has to node, if any,
+// and the replication origin it leaves on the peer.
+func dropPeerSubscription(ctx context.Context, conn DB, node, peer config.Node, dbName string) error {
+	var spockExists bool
+	err := conn.QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM pg_extension WHERE extname = 'spock')",
+	).Scan(&spockExists)
+	if err != nil {
+		return fmt.Errorf("check spock extension on %s: %w", peer.Name, err)
+	}
+	if spockExists {
+		sub := NewSubscription(node, peer, dbName, "", false, conn)
+		if err := sub.Refresh(ctx); err != nil {
+			return err
+		}
+		if sub.Status().Exists {
+			if err := sub.Delete(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	origin := spockSlotName(dbName, node.Name, peer.Name)
+	err = execOp(ctx, conn, opCatalog, `
+		SELECT pg_replication_origin_drop(roname)
+		  FROM pg_replication_origin
+		 WHERE roname = $1`, origin)
+	if err != nil {
+		return fmt.Errorf("drop replication origin %s on %s: %w", origin, peer.Name, err)
+	}
+	return nil
+}
+
 // ResetBootstrappedNodes resets Spock on nodes bootstrapped via CNPG restore.
 // These nodes have stale spock catalog state from the backup source.
-func ResetBootstrappedNodes(ctx context.Context, cfg *config.Config, conns map[string]*pgxpool.Pool) error {
+// Nodes listed in cfg.ResetNodes are skipped; ResetNodes resets them.
+func ResetBootstrappedNodes(ctx context.Context, cfg *config.Config, conns map[string]DB, mirror SnapshotMirror) error {
 	for _, node := range cfg.Nodes {
-		if node.Bootstrap.Mode != "cnpg" {
+		if node.Bootstrap.Mode != "cnpg" || slices.Contains(cfg.ResetNodes, node.Name) {
 			continue
 		}
 		slog.Info("resetting CNPG-bootstrapped node", "node", node.Name)
 		conn := conns[node.Name]
-		if err := resetNode(ctx, conn, node, cfg.DBName, cfg.PgEdgeUser); err != nil {
+		if err := resetNode(ctx, conn, node, cfg.DBName, cfg.PgEdgeUser, mirror); err != nil {
 			return fmt.Errorf("reset bootstrapped node %s: %w", node.Name, err)
 		}
 	}
 	return nil
 }
 
-func resetNode(ctx context.Context, conn *pgxpool.Pool, node config.Node, dbName, pgedgeUser string) error {
+func resetNode(ctx context.Context, conn DB, node config.Node, dbName, pgedgeUser string, mirror SnapshotMirror) error {
 	var spockExists bool
 	err := conn.QueryRow(ctx,
 		"SELECT EXISTS(SELECT 1 FROM pg_extension WHERE extname = 'spock')",
@@ -49,17 +128,22 @@ func resetNode(ctx context.Context, conn *pgxpool.Pool, node config.Node, dbName
 		return fmt.Errorf("check spock extension on %s: %w", node.Name, err)
 	}
 
-	// Backup repsets into memory before nuking — the spock schema is
-	// destroyed by DROP EXTENSION CASCADE, so SQL-table backups won't survive.
+	// Back up repsets before nuking. The spock schema is destroyed by
+	// DROP EXTENSION CASCADE, so the snapshot is saved outside it until
+	// the restore below completes; RestorePendingSnapshots finishes the
+	// job if this run dies in between.
 	var snap *repsetSnapshot
 	if spockExists {
 		snap, err = backupRepsets(ctx, conn, node.Name)
 		if err != nil {
 			return fmt.Errorf("backup repsets on %s: %w", node.Name, err)
 		}
+		if err := saveSnapshot(ctx, conn, node.Name, snap, mirror); err != nil {
+			return err
+		}
 	}
 
-	_, err = conn.Exec(ctx, "DROP EXTENSION IF EXISTS spock CASCADE")
+	err = execOp(ctx, conn, opDDL, "DROP EXTENSION IF EXISTS spock CASCADE")
 	if err != nil {
 		return fmt.Errorf("drop spock extension on %s: %w", node.Name, err)
 	}
@@ -74,7 +158,7 @@ func resetNode(ctx context.Context, conn *pgxpool.Pool, node config.Node, dbName
 		slog.Warn("terminate walsenders failed (continuing)", "node", node.Name, "error", err)
 	}
 
-	_, err = conn.Exec(ctx, `
+	err = execOp(ctx, conn, opCatalog, `
 		SELECT pg_drop_replication_slot(slot_name)
 		  FROM pg_replication_slots
 		 WHERE slot_type = 'logical' AND slot_name LIKE 'spk_%'`)
@@ -82,7 +166,7 @@ func resetNode(ctx context.Context, conn *pgxpool.Pool, node config.Node, dbName
 		slog.Warn("drop replication slots failed (continuing)", "node", node.Name, "error", err)
 	}
 
-	_, err = conn.Exec(ctx, `
+	err = execOp(ctx, conn, opCatalog, `
 		SELECT pg_replication_origin_drop(roname)
 		  FROM pg_replication_origin
 		 WHERE roname LIKE 'spk_%'`)
@@ -90,22 +174,42 @@ func resetNode(ctx context.Context, conn *pgxpool.Pool, node config.Node, dbName
 		slog.Warn("drop replication origins failed (continuing)", "node", node.Name, "error", err)
 	}
 
-	_, err = conn.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS spock")
+	if err := ensureLocalNode(ctx, conn, node, dbName, pgedgeUser); err != nil {
+		return err
+	}
+
+	if err := restoreRepsets(ctx, conn, node.Name, snap); err != nil {
+		return fmt.Errorf("restore repsets on %s: %w", node.Name, err)
+	}
+	if snap != nil {
+		return clearSnapshot(ctx, conn, node.Name, mirror)
+	}
+	return nil
+}
+
+// ensureLocalNode creates the spock extension and the node's local Spock
+// node if either is missing.
+func ensureLocalNode(ctx context.Context, conn DB, node config.Node, dbName, pgedgeUser string) error {
+	err := execOp(ctx, conn, opDDL, "CREATE EXTENSION IF NOT EXISTS spock")
 	if err != nil {
 		return fmt.Errorf("create spock extension on %s: %w", node.Name, err)
 	}
 
+	var exists bool
+	err = conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM spock.node WHERE node_name = $1)", node.Name).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("check spock node on %s: %w", node.Name, err)
+	}
+	if exists {
+		return nil
+	}
+
 	dsn := fmt.Sprintf("host=%s dbname=%s user=%s %s port=5432",
 		node.Hostname, dbName, pgedgeUser, sslSettings)
-	_, err = conn.Exec(ctx, "SELECT spock.node_create($1, $2)", node.Name, dsn)
+	err = execOp(ctx, conn, opCatalog, "SELECT spock.node_create($1, $2)", node.Name, dsn)
 	if err != nil {
 		return fmt.Errorf("create spock node on %s: %w", node.Name, err)
 	}
 	slog.Info("recreated spock node", "node", node.Name)
-
-	if err := restoreRepsets(ctx, conn, node.Name, snap); err != nil {
-		return fmt.Errorf("restore repsets on %s: %w", node.Name, err)
-	}
-
 	return nil
 }
@@ -0,0 +1,116 @@
+// internal/spock/schema_copy.go
+package spock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/pgEdge/pgedge-helm/internal/config"
+	"github.com/pgEdge/pgedge-helm/internal/resource"
+)
+
+// SchemaDumper returns a schema-only SQL dump of a node, restricted by
+// filters of the form "include|exclude schema|table|extension PATTERN".
+type SchemaDumper func(ctx context.Context, node config.Node, filters []string) (string, error)
+
+// SchemaCopy copies the schema of a new node's source node to the new node
+// before the source→new subscription is created, for nodes bootstrapped
+// with structure "dump". The subscription then synchronizes data only,
+// instead of relying on Spock's synchronize_structure.
+//
+// Refresh reports the copy as done once the new node has any user relation
+// or the source→new subscription exists, so a rerun never applies the dump
+// on top of an already populated node.
+type SchemaCopy struct {
+	source  config.Node
+	newNode config.Node
+	dbName  string
+	dump    SchemaDumper
+	conn    DB // new node's connection
+	status  resource.Status
+}
+
+func NewSchemaCopy(source, newNode config.Node, dbName string, dump SchemaDumper, conn DB) *SchemaCopy {
+	return &SchemaCopy{
+		source:  source,
+		newNode: newNode,
+		dbName:  dbName,
+		dump:    dump,
+		conn:    conn,
+	}
+}
+
+func (r *SchemaCopy) Identifier() resource.Identifier {
+	return resource.Identifier{Type: ResourceTypeSchemaCopy, ID: r.newNode.Name}
+}
+
+func (r *SchemaCopy) Dependencies() []resource.Identifier {
+	return []resource.Identifier{
+		{Type: ResourceTypeNode, ID: r.newNode.Name},
+	}
+}
+
+func (r *SchemaCopy) Refresh(ctx context.Context) error {
+	var populated bool
+	err := r.conn.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM spock.subscription WHERE sub_name = $1
+		) OR EXISTS(
+			SELECT 1 FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE c.relkind IN ('r', 'p', 'v', 'm', 'S')
+			  AND n.nspname NOT IN ('pg_catalog', 'information_schema', 'spock', 'pgedge_init_spock')
+			  AND n.nspname NOT LIKE 'pg\_%'
+		)`, spockSubName(r.source.Name, r.newNode.Name),
+	).Scan(&populated)
+	if err != nil {
+		return fmt.Errorf("inspect schema of %s: %w", r.newNode.Name, err)
+	}
+	r.status = resource.Status{Exists: populated}
+	return nil
+}
+
+func (r *SchemaCopy) Status() resource.Status { return r.status }
+
+func (r *SchemaCopy) Create(ctx context.Context) error {
+	if r.dump == nil {
+		return errors.New("schema copy requires a schema dumper")
+	}
+	filters := r.newNode.Bootstrap.SchemaFilter
+	script, err := r.dump(ctx, r.source, filters)
+	if err != nil {
+		return fmt.Errorf("dump schema of %s: %w", r.source.Name, err)
+	}
+
+	tx, err := beginOp(ctx, r.conn, opLong)
+	if err != nil {
+		return fmt.Errorf("begin schema copy to %s: %w", r.newNode.Name, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT spock.repair_mode('True')`); err != nil {
+		return fmt.Errorf("repair mode for schema copy to %s: %w", r.newNode.Name, err)
+	}
+	// Without arguments Exec uses the simple protocol, which runs the whole
+	// multi-statement script. The script changes session settings such as
+	// search_path, and lifts lock_timeout, which is harmless on a node
+	// nothing uses yet; reset them before the connection returns to the
+	// pool.
+	if _, err := tx.Exec(ctx, script); err != nil {
+		return fmt.Errorf("apply schema of %s to %s: %w", r.source.Name, r.newNode.Name, err)
+	}
+	if _, err := tx.Exec(ctx, "RESET ALL"); err != nil {
+		return fmt.Errorf("reset session after schema copy to %s: %w", r.newNode.Name, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit schema copy to %s: %w", r.newNode.Name, err)
+	}
+	slog.Info("copied schema", "source", r.source.Name, "node", r.newNode.Name, "filters", len(filters))
+	return nil
+}
+
+func (r *SchemaCopy) Update(_ context.Context) error { return nil }
+
+func (r *SchemaCopy) Delete(_ context.Context) error { return nil }
@@ -0,0 +1,175 @@
+// internal/spock/snapshot.go
+package spock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/pgEdge/pgedge-helm/internal/config"
+)
+
+// snapshotTable holds the repset snapshot of a node being reset. It lives
+// in its own schema so DROP EXTENSION spock CASCADE leaves it alone; a row
+// is present only between the backup and the completed restore.
+const snapshotTable = "pgedge_init_spock.repset_snapshot"
+
+// SnapshotMirror keeps a second copy of repset snapshots outside the
+// database, e.g. in a Kubernetes ConfigMap. Load returns nil data when
+// there is no snapshot for the node.
+type SnapshotMirror interface {
+	Save(ctx context.Context, node string, data []byte) error
+	Load(ctx context.Context, node string) ([]byte, error)
+	Clear(ctx context.Context, node string) error
+}
+
+// saveSnapshot persists a node's snapshot before Spock is dropped. It runs
+// in repair mode so neither the DDL nor the row is replicated to peers,
+// and keeps the table out of every replication set in case DDL
+// replication added it to one.
+func saveSnapshot(ctx context.Context, conn DB, node string, snap *repsetSnapshot, mirror SnapshotMirror) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("encode repset snapshot of %s: %w", node, err)
+	}
+
+	tx, err := beginOp(ctx, conn, opCatalog)
+	if err != nil {
+		return fmt.Errorf("begin snapshot save on %s: %w", node, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT spock.repair_mode('True')`); err != nil {
+		return fmt.Errorf("repair mode for snapshot save on %s: %w", node, err)
+	}
+	for _, stmt := range []string{
+		"CREATE SCHEMA IF NOT EXISTS pgedge_init_spock",
+		"CREATE TABLE IF NOT EXISTS " + snapshotTable + " (node text PRIMARY KEY, taken_at timestamptz NOT NULL DEFAULT now(), snapshot jsonb NOT NULL)",
+		`SELECT spock.repset_remove_table(rs.set_name, rst.set_reloid)
+		   FROM spock.replication_set_table rst
+		   JOIN spock.replication_set rs ON rs.set_id = rst.set_id
+		  WHERE rst.set_reloid = '` + snapshotTable + `'::regclass`,
+	} {
+		if _, err := tx.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("prepare snapshot table on %s: %w", node, err)
+		}
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO `+snapshotTable+` (node, snapshot) VALUES ($1, $2)
+		ON CONFLICT (node) DO UPDATE SET taken_at = now(), snapshot = EXCLUDED.snapshot`, node, data)
+	if err != nil {
+		return fmt.Errorf("save repset snapshot on %s: %w", node, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit repset snapshot on %s: %w", node, err)
+	}
+
+	if mirror != nil {
+		if err := mirror.Save(ctx, node, data); err != nil {
+			return fmt.Errorf("mirror repset snapshot of %s: %w", node, err)
+		}
+	}
+	slog.Info("saved repset snapshot", "node", node, "sets", len(snap.Sets), "tables", len(snap.Tables))
+	return nil
+}
+
+// loadSnapshot returns the pending snapshot of a node, or nil if there is
+// none. The database copy wins; the mirror is consulted only when the
+// table holds no row for the node.
+func loadSnapshot(ctx context.Context, conn DB, node string, mirror SnapshotMirror) (*repsetSnapshot, error) {
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", snapshotTable).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("check snapshot table on %s: %w", node, err)
+	}
+	var data []byte
+	if exists {
+		err := conn.QueryRow(ctx, "SELECT snapshot FROM "+snapshotTable+" WHERE node = $1", node).Scan(&data)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("read repset snapshot on %s: %w", node, err)
+		}
+	}
+	if data == nil && mirror != nil {
+		var err error
+		if data, err = mirror.Load(ctx, node); err != nil {
+			return nil, fmt.Errorf("read mirrored repset snapshot of %s: %w", node, err)
+		}
+	}
+	if data == nil {
+		return nil, nil
+	}
+	snap := &repsetSnapshot{}
+	if err := json.Unmarshal(data, snap); err != nil {
+		return nil, fmt.Errorf("decode repset snapshot of %s: %w", node, err)
+	}
+	return snap, nil
+}
+
+// clearSnapshot removes a node's snapshot once it has been restored.
+// Spock exists again at this point, so the delete runs in repair mode.
+func clearSnapshot(ctx context.Context, conn DB, node string, mirror SnapshotMirror) error {
+	tx, err := beginOp(ctx, conn, opCatalog)
+	if err != nil {
+		return fmt.Errorf("begin snapshot clear on %s: %w", node, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT spock.repair_mode('True')`); err != nil {
+		return fmt.Errorf("repair mode for snapshot clear on %s: %w", node, err)
+	}
+	var exists bool
+	if err := tx.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", snapshotTable).Scan(&exists); err != nil {
+		return fmt.Errorf("check snapshot table on %s: %w", node, err)
+	}
+	if exists {
+		if _, err := tx.Exec(ctx, "DELETE FROM "+snapshotTable+" WHERE node = $1", node); err != nil {
+			return fmt.Errorf("clear repset snapshot on %s: %w", node, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit snapshot clear on %s: %w", node, err)
+	}
+
+	if mirror != nil {
+		// With the database copy gone, loadSnapshot would fall back to a
+		// mirror left behind on every later run and put back replication set
+		// members removed since. Fail instead: the next run restores the
+		// same snapshot again and retries the clear.
+		if err := mirror.Clear(ctx, node); err != nil {
+			return fmt.Errorf("clear mirrored repset snapshot of %s: %w", node, err)
+		}
+	}
+	return nil
+}
+
+// RestorePendingSnapshots finishes resets that were interrupted between
+// saving a node's repset snapshot and restoring it: it makes sure the
+// spock extension and local node exist, restores the snapshot and clears
+// it. Nodes without a pending snapshot are left untouched. It must run
+// before anything else inspects or changes Spock.
+func RestorePendingSnapshots(ctx context.Context, cfg *config.Config, conns map[string]DB, mirror SnapshotMirror) error {
+	for _, node := range cfg.Nodes {
+		conn := conns[node.Name]
+		snap, err := loadSnapshot(ctx, conn, node.Name, mirror)
+		if err != nil {
+			return err
+		}
+		if snap == nil {
+			continue
+		}
+		slog.Warn("found repset snapshot of an interrupted reset, restoring", "node", node.Name)
+		if err := ensureLocalNode(ctx, conn, node, cfg.DBName, cfg.PgEdgeUser); err != nil {
+			return err
+		}
+		if err := restoreRepsets(ctx, conn, node.Name, snap); err != nil {
+			return fmt.Errorf("restore pending repsets on %s: %w", node.Name, err)
+		}
+		if err := clearSnapshot(ctx, conn, node.Name, mirror); err != nil {
+			return err
+		}
+	}
+	return nil
+}
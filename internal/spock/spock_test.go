@@ -2,9 +2,15 @@
 package spock
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/pgconn"
 
 	"github.com/pgEdge/pgedge-helm/internal/config"
 	"github.com/pgEdge/pgedge-helm/internal/resource"
@@ -262,7 +268,7 @@ var _ resource.Resource = (*SyncEvent)(nil)
 
 func TestWaitForSyncEventIdentifier(t *testing.T) {
 	syncEvt := &SyncEvent{providerName: "n1", subscriberName: "n3"}
-	r := NewWaitForSyncEvent("n1", "n3", syncEvt, nil)
+	r := NewWaitForSyncEvent("n1", "n3", syncEvt, config.Wait{}, nil)
 	id := r.Identifier()
 	if id.Type != ResourceTypeWaitForSyncEvent {
 		t.Errorf("type: got %q, want %q", id.Type, ResourceTypeWaitForSyncEvent)
@@ -274,7 +280,7 @@ func TestWaitForSyncEventIdentifier(t *testing.T) {
 
 func TestWaitForSyncEventDependsOnSyncEvent(t *testing.T) {
 	syncEvt := &SyncEvent{providerName: "n1", subscriberName: "n3"}
-	r := NewWaitForSyncEvent("n1", "n3", syncEvt, nil)
+	r := NewWaitForSyncEvent("n1", "n3", syncEvt, config.Wait{}, nil)
 	deps := r.Dependencies()
 	if len(deps) != 1 {
 		t.Fatalf("expected 1 dep, got %d: %v", len(deps), deps)
@@ -285,7 +291,7 @@ func TestWaitForSyncEventDependsOnSyncEvent(t *testing.T) {
 }
 
 func TestWaitForSyncEventEphemeral(t *testing.T) {
-	r := NewWaitForSyncEvent("n1", "n3", nil, nil)
+	r := NewWaitForSyncEvent("n1", "n3", nil, config.Wait{}, nil)
 	if r.Status().Exists {
 		t.Error("ephemeral resource should not exist")
 	}
@@ -368,7 +374,7 @@ func TestComputeDesiredSingleNode(t *testing.T) {
 		DBName: "app", AdminUser: "admin", PgEdgeUser: "pgedge",
 		Nodes: []config.Node{{Name: "n1", Hostname: "pgedge-n1-rw"}},
 	}
-	resources := ComputeDesired(cfg, map[string]*pgxpool.Pool{"n1": nil})
+	resources := ComputeDesired(cfg, map[string]DB{"n1": nil}, nil)
 
 	// 1 user + 1 node + 0 subscriptions
 	if len(resources) != 2 {
@@ -390,8 +396,8 @@ func TestComputeDesiredTwoNodes(t *testing.T) {
 			{Name: "n2", Hostname: "pgedge-n2-rw"},
 		},
 	}
-	conns := map[string]*pgxpool.Pool{"n1": nil, "n2": nil}
-	resources := ComputeDesired(cfg, conns)
+	conns := map[string]DB{"n1": nil, "n2": nil}
+	resources := ComputeDesired(cfg, conns, nil)
 
 	// 2 users + 2 nodes + 2 slots + 2 subscriptions = 8
 	if len(resources) != 8 {
@@ -414,8 +420,8 @@ func TestComputeDesiredThreeNodes(t *testing.T) {
 			{Name: "n3", Hostname: "h3"},
 		},
 	}
-	conns := map[string]*pgxpool.Pool{"n1": nil, "n2": nil, "n3": nil}
-	resources := ComputeDesired(cfg, conns)
+	conns := map[string]DB{"n1": nil, "n2": nil, "n3": nil}
+	resources := ComputeDesired(cfg, conns, nil)
 
 	// 3 users + 3 nodes + 6 slots + 6 subscriptions = 18
 	if len(resources) != 18 {
@@ -431,8 +437,8 @@ func TestComputeDesiredSyncFlag(t *testing.T) {
 			{Name: "n2", Hostname: "h2", Bootstrap: config.NodeBootstrap{Mode: "spock", SourceNode: "n1"}},
 		},
 	}
-	conns := map[string]*pgxpool.Pool{"n1": nil, "n2": nil}
-	resources := ComputeDesired(cfg, conns)
+	conns := map[string]DB{"n1": nil, "n2": nil}
+	resources := ComputeDesired(cfg, conns, nil)
 
 	// sub from n1→n2 should have sync=true (populate sync subscription)
 	subN1N2 := mustSubscription(t, resources, "sub_n1_n2")
@@ -459,8 +465,8 @@ func TestComputeDesiredPopulateTwoNodeAdd(t *testing.T) {
 			{Name: "n2", Hostname: "h2", Bootstrap: config.NodeBootstrap{Mode: "spock", SourceNode: "n1"}},
 		},
 	}
-	conns := map[string]*pgxpool.Pool{"n1": nil, "n2": nil}
-	resources := ComputeDesired(cfg, conns)
+	conns := map[string]DB{"n1": nil, "n2": nil}
+	resources := ComputeDesired(cfg, conns, nil)
 
 	// Should have populate resources: sync event, wait for sync event
 	assertResource(t, resources, ResourceTypeSyncEvent, "n1_n2")
@@ -501,8 +507,8 @@ func TestComputeDesiredPopulateThreeNodeAdd(t *testing.T) {
 			{Name: "n3", Hostname: "h3", Bootstrap: config.NodeBootstrap{Mode: "spock", SourceNode: "n1"}},
 		},
 	}
-	conns := map[string]*pgxpool.Pool{"n1": nil, "n2": nil, "n3": nil}
-	resources := ComputeDesired(cfg, conns)
+	conns := map[string]DB{"n1": nil, "n2": nil, "n3": nil}
+	resources := ComputeDesired(cfg, conns, nil)
 
 	// Peer resources for n2
 	assertResource(t, resources, ResourceTypeReplicationSlotCreate, "spk_app_n2_sub_n2_n3")
@@ -557,8 +563,8 @@ func TestComputeDesiredNoNewNodesUnchanged(t *testing.T) {
 			{Name: "n2", Hostname: "h2"},
 		},
 	}
-	conns := map[string]*pgxpool.Pool{"n1": nil, "n2": nil}
-	resources := ComputeDesired(cfg, conns)
+	conns := map[string]DB{"n1": nil, "n2": nil}
+	resources := ComputeDesired(cfg, conns, nil)
 
 	// 2 users + 2 nodes + 2 slots + 2 subscriptions = 8 (unchanged)
 	if len(resources) != 8 {
@@ -592,6 +598,116 @@ func TestComputeDesiredNoNewNodesUnchanged(t *testing.T) {
 	}
 }
 
+func TestComputeDesiredPopulateTwoNewNodes(t *testing.T) {
+	cfg := &config.Config{
+		DBName: "app", AdminUser: "admin", PgEdgeUser: "pgedge",
+		Nodes: []config.Node{
+			{Name: "n1", Hostname: "h1"},
+			{Name: "n2", Hostname: "h2"},
+			{Name: "n3", Hostname: "h3", Bootstrap: config.NodeBootstrap{Mode: "spock", SourceNode: "n1"}},
+			{Name: "n4", Hostname: "h4", Bootstrap: config.NodeBootstrap{Mode: "spock", SourceNode: "n2"}},
+		},
+	}
+	conns := map[string]DB{"n1": nil, "n2": nil, "n3": nil, "n4": nil}
+	resources := ComputeDesired(cfg, conns, nil)
+
+	// Each new node populates from its source and the other existing node.
+	assertResource(t, resources, ResourceTypeSyncEvent, "n1_n3")
+	assertResource(t, resources, ResourceTypeSyncEvent, "n2_n1")
+	assertResource(t, resources, ResourceTypeReplicationOriginAdvance, "n2_n3")
+	assertResource(t, resources, ResourceTypeSyncEvent, "n2_n4")
+	assertResource(t, resources, ResourceTypeSyncEvent, "n1_n2")
+	assertResource(t, resources, ResourceTypeReplicationOriginAdvance, "n1_n4")
+
+	// Neither new node is a populate peer of the other.
+	for id := range resources {
+		switch id.Type {
+		case ResourceTypeReplicationSlotCreate, ResourceTypeSyncEvent, ResourceTypeWaitForSyncEvent,
+			ResourceTypePeerCatchup, ResourceTypeDisabledSubscription, ResourceTypeLagTrackerCommitTS,
+			ResourceTypeReplicationSlotAdvanceFromCTS, ResourceTypeReplicationOriginAdvance:
+			if (strings.Contains(id.ID, "n3") && strings.Contains(id.ID, "n4")) ||
+				id.ID == "n3_n1" || id.ID == "n3_n2" || id.ID == "n4_n1" || id.ID == "n4_n2" {
+				t.Errorf("unexpected populate resource involving a new peer: %v", id)
+			}
+		}
+	}
+
+	// New→new subscriptions start after both nodes have populated.
+	for _, subID := range []string{"sub_n3_n4", "sub_n4_n3"} {
+		sub := mustSubscription(t, resources, subID)
+		if sub.sync {
+			t.Errorf("%s should not sync data", subID)
+		}
+		deps := map[resource.Identifier]bool{}
+		for _, d := range sub.Dependencies() {
+			deps[d] = true
+		}
+		for _, wait := range []string{"n1_n3", "n2_n4"} {
+			if !deps[resource.Identifier{Type: ResourceTypeWaitForSyncEvent, ID: wait}] {
+				t.Errorf("%s should depend on wait_for_sync_event/%s", subID, wait)
+			}
+		}
+		for d := range deps {
+			if d.Type == ResourceTypeReplicationOriginAdvance {
+				t.Errorf("%s should not depend on %v", subID, d)
+			}
+		}
+	}
+
+	assertAcyclicPlan(t, resources)
+}
+
+func TestComputeDesiredPopulateTwoNewNodesSameSource(t *testing.T) {
+	cfg := &config.Config{
+		DBName: "app", AdminUser: "admin", PgEdgeUser: "pgedge",
+		Nodes: []config.Node{
+			{Name: "n1", Hostname: "h1"},
+			{Name: "n2", Hostname: "h2", Bootstrap: config.NodeBootstrap{Mode: "spock", SourceNode: "n1"}},
+			{Name: "n3", Hostname: "h3", Bootstrap: config.NodeBootstrap{Mode: "spock", SourceNode: "n1"}},
+		},
+	}
+	conns := map[string]DB{"n1": nil, "n2": nil, "n3": nil}
+	resources := ComputeDesired(cfg, conns, nil)
+
+	// With no other existing node, each new node populates from n1 alone.
+	for id := range resources {
+		if id.Type == ResourceTypeReplicationSlotCreate || id.Type == ResourceTypePeerCatchup {
+			t.Errorf("unexpected populate peer resource: %v", id)
+		}
+	}
+	if !mustSubscription(t, resources, "sub_n1_n2").sync || !mustSubscription(t, resources, "sub_n1_n3").sync {
+		t.Error("source→new subscriptions should sync data")
+	}
+	assertAcyclicPlan(t, resources)
+}
+
+// assertAcyclicPlan plans creating every resource and checks that each
+// one runs in a later phase than all of its dependencies.
+func assertAcyclicPlan(t *testing.T, resources map[resource.Identifier]resource.Resource) {
+	t.Helper()
+	plan, err := resource.Plan(nil, resources)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	phaseOf := map[resource.Identifier]int{}
+	for i, phase := range plan {
+		for _, event := range phase {
+			phaseOf[event.Resource.Identifier()] = i
+		}
+	}
+	for id, r := range resources {
+		for _, dep := range r.Dependencies() {
+			if _, ok := resources[dep]; !ok {
+				t.Errorf("%v depends on %v, which is not desired", id, dep)
+				continue
+			}
+			if phaseOf[dep] >= phaseOf[id] {
+				t.Errorf("%v (phase %d) does not run after its dependency %v (phase %d)", id, phaseOf[id], dep, phaseOf[dep])
+			}
+		}
+	}
+}
+
 func assertResource(t *testing.T, resources map[resource.Identifier]resource.Resource, resType, id string) {
 	t.Helper()
 	key := resource.Identifier{Type: resType, ID: id}
@@ -649,7 +765,7 @@ func TestReplicationOriginAdvanceEphemeral(t *testing.T) {
 
 func TestPeerCatchupIdentifier(t *testing.T) {
 	syncEvt := &SyncEvent{providerName: "n2", subscriberName: "n1"}
-	r := NewPeerCatchup("n2", "n1", syncEvt, nil)
+	r := NewPeerCatchup("n2", "n1", syncEvt, config.Wait{}, nil)
 	id := r.Identifier()
 	if id.Type != ResourceTypePeerCatchup {
 		t.Errorf("type: got %q, want %q", id.Type, ResourceTypePeerCatchup)
@@ -661,7 +777,7 @@ func TestPeerCatchupIdentifier(t *testing.T) {
 
 func TestPeerCatchupDependsOnSyncEvent(t *testing.T) {
 	syncEvt := &SyncEvent{providerName: "n2", subscriberName: "n1"}
-	r := NewPeerCatchup("n2", "n1", syncEvt, nil)
+	r := NewPeerCatchup("n2", "n1", syncEvt, config.Wait{}, nil)
 	deps := r.Dependencies()
 	if len(deps) != 1 {
 		t.Fatalf("expected 1 dep, got %d: %v", len(deps), deps)
@@ -672,7 +788,7 @@ func TestPeerCatchupDependsOnSyncEvent(t *testing.T) {
 }
 
 func TestPeerCatchupEphemeral(t *testing.T) {
-	r := NewPeerCatchup("n2", "n1", nil, nil)
+	r := NewPeerCatchup("n2", "n1", nil, config.Wait{}, nil)
 	if r.Status().Exists {
 		t.Error("ephemeral resource should not exist")
 	}
@@ -693,8 +809,8 @@ func TestComputeDesiredPopulateIncludesPeerCatchup(t *testing.T) {
 			{Name: "n3", Hostname: "h3", Bootstrap: config.NodeBootstrap{Mode: "spock", SourceNode: "n1"}},
 		},
 	}
-	conns := map[string]*pgxpool.Pool{"n1": nil, "n2": nil, "n3": nil}
-	resources := ComputeDesired(cfg, conns)
+	conns := map[string]DB{"n1": nil, "n2": nil, "n3": nil}
+	resources := ComputeDesired(cfg, conns, nil)
 
 	// PeerCatchup(peer=n2, source=n1) — id is "n2_n1".
 	assertResource(t, resources, ResourceTypePeerCatchup, "n2_n1")
@@ -709,8 +825,8 @@ func TestComputeDesiredPopulateIncludesOriginAdvance(t *testing.T) {
 			{Name: "n3", Hostname: "h3", Bootstrap: config.NodeBootstrap{Mode: "spock", SourceNode: "n1"}},
 		},
 	}
-	conns := map[string]*pgxpool.Pool{"n1": nil, "n2": nil, "n3": nil}
-	resources := ComputeDesired(cfg, conns)
+	conns := map[string]DB{"n1": nil, "n2": nil, "n3": nil}
+	resources := ComputeDesired(cfg, conns, nil)
 
 	// One OriginAdvance per (peer, new) pair. Only peer is n2 here.
 	assertResource(t, resources, ResourceTypeReplicationOriginAdvance, "n2_n3")
@@ -725,8 +841,8 @@ func TestComputeDesiredSourceSubscriptionWaitsOnPeerCatchup(t *testing.T) {
 			{Name: "n3", Hostname: "h3", Bootstrap: config.NodeBootstrap{Mode: "spock", SourceNode: "n1"}},
 		},
 	}
-	conns := map[string]*pgxpool.Pool{"n1": nil, "n2": nil, "n3": nil}
-	resources := ComputeDesired(cfg, conns)
+	conns := map[string]DB{"n1": nil, "n2": nil, "n3": nil}
+	resources := ComputeDesired(cfg, conns, nil)
 
 	// Source→new subscription (sub_n1_n3) must depend on PeerCatchup(n2_n1)
 	// AND on WaitForSyncEvent(n2_n1). The first proves apply-progress
@@ -749,6 +865,113 @@ func TestComputeDesiredSourceSubscriptionWaitsOnPeerCatchup(t *testing.T) {
 	}
 }
 
+func TestComputeDesiredSchemaCopy(t *testing.T) {
+	cfg := &config.Config{
+		DBName: "app", AdminUser: "admin", PgEdgeUser: "pgedge",
+		Nodes: []config.Node{
+			{Name: "n1", Hostname: "h1"},
+			{Name: "n2", Hostname: "h2"},
+			{Name: "n3", Hostname: "h3", Bootstrap: config.NodeBootstrap{
+				Mode: "spock", SourceNode: "n1", Structure: config.StructureDump,
+				SchemaFilter: []string{"exclude schema audit"},
+			}},
+		},
+	}
+	conns := map[string]DB{"n1": nil, "n2": nil, "n3": nil}
+	resources := ComputeDesired(cfg, conns, nil)
+
+	copyID := resource.Identifier{Type: ResourceTypeSchemaCopy, ID: "n3"}
+	schemaCopy, ok := resources[copyID].(*SchemaCopy)
+	if !ok {
+		t.Fatal("expected schema_copy/n3")
+	}
+	if schemaCopy.source.Name != "n1" {
+		t.Errorf("schema copy source: got %s, want n1", schemaCopy.source.Name)
+	}
+
+	// Only the source→new subscription skips structure sync, after the copy.
+	sub := mustSubscription(t, resources, "sub_n1_n3")
+	if !sub.sync || !sub.noSchema {
+		t.Errorf("sub_n1_n3 should sync data without structure, got sync=%v noSchema=%v", sub.sync, sub.noSchema)
+	}
+	if !slices.Contains(sub.Dependencies(), copyID) {
+		t.Error("sub_n1_n3 should depend on schema_copy/n3")
+	}
+	if mustSubscription(t, resources, "sub_n2_n3").noSchema {
+		t.Error("sub_n2_n3 should not be marked noSchema")
+	}
+	assertAcyclicPlan(t, resources)
+
+	// The default structure leaves the copy to Spock.
+	cfg.Nodes[2].Bootstrap.Structure = ""
+	resources = ComputeDesired(cfg, conns, nil)
+	if _, ok := resources[copyID]; ok {
+		t.Error("expected no schema copy without structure dump")
+	}
+	if mustSubscription(t, resources, "sub_n1_n3").noSchema {
+		t.Error("sub_n1_n3 should synchronize structure by default")
+	}
+}
+
+func TestComputeDesiredVerifyData(t *testing.T) {
+	cfg := &config.Config{
+		DBName: "app", AdminUser: "admin", PgEdgeUser: "pgedge",
+		Nodes: []config.Node{
+			{Name: "n1", Hostname: "h1"},
+			{Name: "n2", Hostname: "h2"},
+			{Name: "n3", Hostname: "h3", Bootstrap: config.NodeBootstrap{Mode: "spock", SourceNode: "n1"}},
+		},
+	}
+	conns := map[string]DB{"n1": nil, "n2": nil, "n3": nil}
+	verifyID := resource.Identifier{Type: ResourceTypeVerifyData, ID: "n3"}
+
+	if _, ok := ComputeDesired(cfg, conns, nil)[verifyID]; ok {
+		t.Error("expected no data verification by default")
+	}
+
+	cfg.VerifyData = config.VerifyDataFail
+	resources := ComputeDesired(cfg, conns, nil)
+	v, ok := resources[verifyID].(*VerifyData)
+	if !ok {
+		t.Fatal("expected verify_data/n3")
+	}
+	if v.source.Name != "n1" || v.mode != config.VerifyDataFail {
+		t.Errorf("got source %s mode %s", v.source.Name, v.mode)
+	}
+	// Verification runs only after every subscription to the new node.
+	for _, sub := range []string{"sub_n1_n3", "sub_n2_n3"} {
+		if !slices.Contains(v.Dependencies(), resource.Identifier{Type: ResourceTypeSubscription, ID: sub}) {
+			t.Errorf("verify_data/n3 should depend on %s", sub)
+		}
+	}
+	assertAcyclicPlan(t, resources)
+}
+
+func TestDiffChunks(t *testing.T) {
+	source := tableChecksum{0: {Rows: 10, Sum: "123"}, 5: {Rows: 2, Sum: "-7"}}
+	same := tableChecksum{0: {Rows: 10, Sum: "123"}, 5: {Rows: 2, Sum: "-7"}}
+	if chunks := diffChunks(source, same); len(chunks) != 0 {
+		t.Errorf("expected no differing chunks, got %v", chunks)
+	}
+
+	// A missing row, an updated row and a chunk only one side has.
+	other := tableChecksum{0: {Rows: 9, Sum: "100"}, 5: {Rows: 2, Sum: "-8"}, 9: {Rows: 1, Sum: "4"}}
+	if chunks := diffChunks(source, other); fmt.Sprint(chunks) != "[0 5 9]" {
+		t.Errorf("expected chunks [0 5 9] to differ, got %v", chunks)
+	}
+	if source.rows() != 12 || other.rows() != 12 {
+		t.Errorf("rows: got %d and %d, want 12", source.rows(), other.rows())
+	}
+}
+
+func TestChecksumQueryHashesWholeRow(t *testing.T) {
+	// In a table with a column named t, a bare t would be that column.
+	q := strings.Join(strings.Fields(checksumQuery(`public."t"`)), " ")
+	if !strings.Contains(q, "hashtextextended((t.*)::text, 0)") || !strings.Contains(q, `FROM public."t" t`) {
+		t.Errorf("expected the whole row of public.\"t\" to be hashed, got %s", q)
+	}
+}
+
 func TestComputeDesiredPeerSubscriptionWaitsOnOriginAdvance(t *testing.T) {
 	cfg := &config.Config{
 		DBName: "app", AdminUser: "admin", PgEdgeUser: "pgedge",
@@ -758,8 +981,8 @@ func TestComputeDesiredPeerSubscriptionWaitsOnOriginAdvance(t *testing.T) {
 			{Name: "n3", Hostname: "h3", Bootstrap: config.NodeBootstrap{Mode: "spock", SourceNode: "n1"}},
 		},
 	}
-	conns := map[string]*pgxpool.Pool{"n1": nil, "n2": nil, "n3": nil}
-	resources := ComputeDesired(cfg, conns)
+	conns := map[string]DB{"n1": nil, "n2": nil, "n3": nil}
+	resources := ComputeDesired(cfg, conns, nil)
 
 	// Peer→new end-state subscription (sub_n2_n3) should now depend on
 	// ReplicationOriginAdvance (which itself depends on slot advance,
@@ -782,4 +1005,669 @@ func TestComputeDesiredPeerSubscriptionWaitsOnOriginAdvance(t *testing.T) {
 	if foundSlotAdvanceDirect {
 		t.Error("sub_n2_n3 should NOT directly depend on replication_slot_advance_from_cts (gated via OriginAdvance instead)")
 	}
+	if !sub.populate || mustSubscription(t, resources, "sub_n3_n2").populate || mustSubscription(t, resources, "sub_n1_n2").populate {
+		t.Error("only the peer→new subscription sub_n2_n3 should be enabled regardless of its origin's exception log")
+	}
+}
+
+func TestDescribeEvent(t *testing.T) {
+	n1, n2 := config.Node{Name: "n1"}, config.Node{Name: "n2"}
+	orphan := NewSpockNode(config.Node{Name: "n9"}, "app", "pgedge", nil)
+	orphan.survivor = "n2"
+	recreated := NewSubscription(n1, n2, "app", "pgedge", false, nil)
+	recreated.status = resource.Status{Exists: true, NeedsRecreate: true, Reason: "provider DSN changed"}
+
+	tests := []struct {
+		name   string
+		event  resource.Event
+		err    error
+		want   Notice
+		report bool
+	}{
+		{"node created", resource.Event{Action: resource.ActionCreate, Resource: NewSpockNode(n1, "app", "pgedge", nil)}, nil,
+			Notice{Node: "n1", Reason: "NodeCreated"}, true},
+		{"orphan node dropped on survivor", resource.Event{Action: resource.ActionDelete, Resource: orphan}, nil,
+			Notice{Node: "n2", Reason: "OrphanDropped"}, true},
+		{"subscription created", resource.Event{Action: resource.ActionCreate, Resource: NewSubscription(n1, n2, "app", "pgedge", false, nil)}, nil,
+			Notice{Node: "n2", Reason: "SubscriptionCreated"}, true},
+		{"subscription recreated", resource.Event{Action: resource.ActionCreate, Resource: recreated}, nil,
+			Notice{Node: "n2", Reason: "SubscriptionRecreated"}, true},
+		{"recreate delete not reported", resource.Event{Action: resource.ActionDelete, Resource: recreated}, nil,
+			Notice{}, false},
+		{"populate step not reported", resource.Event{Action: resource.ActionCreate, Resource: NewSyncEvent("n1", "n2", nil)}, nil,
+			Notice{}, false},
+		{"failure is a warning", resource.Event{Action: resource.ActionCreate, Resource: NewSyncEvent("n1", "n2", nil)}, errors.New("boom"),
+			Notice{Node: "n1", Warning: true, Reason: "CreateFailed"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := DescribeEvent(tt.event, tt.err)
+			if ok != tt.report {
+				t.Fatalf("reported = %v, want %v", ok, tt.report)
+			}
+			if !ok {
+				return
+			}
+			if got.Node != tt.want.Node || got.Reason != tt.want.Reason || got.Warning != tt.want.Warning {
+				t.Errorf("got %+v, want node=%s reason=%s warning=%v", got, tt.want.Node, tt.want.Reason, tt.want.Warning)
+			}
+			if got.Message == "" {
+				t.Error("expected a message")
+			}
+		})
+	}
+}
+
+func TestPopulateTarget(t *testing.T) {
+	n1, n2 := config.Node{Name: "n1"}, config.Node{Name: "n2"}
+	sync := resource.Event{Action: resource.ActionCreate, Resource: NewSubscription(n1, n2, "app", "pgedge", true, nil)}
+	if node, ok := PopulateTarget(sync); !ok || node != "n2" {
+		t.Errorf("expected populate target n2, got %q (%v)", node, ok)
+	}
+	plain := resource.Event{Action: resource.ActionCreate, Resource: NewSubscription(n2, n1, "app", "pgedge", false, nil)}
+	if _, ok := PopulateTarget(plain); ok {
+		t.Error("subscription without sync should not be a populate target")
+	}
+}
+
+func TestPairHealth(t *testing.T) {
+	lag := int64(128)
+	provider := nodeState{slots: map[string]slotState{
+		spockSlotName("app", "n1", "n2"): {active: true, lagBytes: &lag},
+	}}
+	subscriber := nodeState{
+		subs:  map[string]string{"sub_n1_n2": "replicating"},
+		lagMs: map[string]int64{"n1": 250},
+	}
+
+	h := pairHealth("app", "n1", "n2", provider, subscriber)
+	if !h.Healthy() || *h.LagBytes != 128 || *h.CommitLagMs != 250 {
+		t.Errorf("expected healthy pair with lag, got %+v", h)
+	}
+
+	provider.slots[h.Slot] = slotState{active: false}
+	if h := pairHealth("app", "n1", "n2", provider, subscriber); h.Healthy() {
+		t.Error("inactive slot should be unhealthy")
+	}
+
+	delete(provider.slots, h.Slot)
+	if h := pairHealth("app", "n1", "n2", provider, subscriber); h.Healthy() || h.SlotExists {
+		t.Error("missing slot should be unhealthy")
+	}
+
+	h = pairHealth("app", "n1", "n2", provider, nodeState{subsErr: errors.New("not connected")})
+	if h.Error != "not connected" || h.Healthy() {
+		t.Errorf("expected subscriber error, got %+v", h)
+	}
+}
+
+func TestOriginNodes(t *testing.T) {
+	cfg := &config.Config{DBName: "app", Nodes: []config.Node{{Name: "n1"}, {Name: "n2"}}}
+	origins := originNodes(cfg)
+	if len(origins) != 2 || origins[spockSlotName("app", "n1", "n2")] != "n1" || origins[spockSlotName("app", "n2", "n1")] != "n2" {
+		t.Errorf("unexpected origins: %v", origins)
+	}
+}
+
+func TestSummarizeConflicts(t *testing.T) {
+	base := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	groups := []ConflictGroup{
+		{Table: "public.a", Type: "update_update", Origin: "n1", Receiver: "n2", Count: 2, First: base, Last: base.Add(2 * time.Minute)},
+		{Table: "public.b", Type: "insert_exists", Origin: "n2", Receiver: "n1", Count: 1, First: base.Add(time.Minute), Last: base.Add(time.Minute)},
+		{Table: "public.a", Type: "update_update", Origin: "n3", Receiver: "n2", Count: 1, First: base.Add(3 * time.Minute), Last: base.Add(3 * time.Minute)},
+		// Two origins of n1 known as the same provider.
+		{Table: "public.b", Type: "insert_exists", Origin: "n2", Receiver: "n1", Count: 3, First: base, Last: base},
+	}
+	latest := []Conflict{
+		{Time: base.Add(2 * time.Minute), Table: "public.a", Receiver: "n2"},
+		{Time: base.Add(3 * time.Minute), Table: "public.a", Receiver: "n2"},
+		{Time: base.Add(time.Minute), Table: "public.b", Receiver: "n1"},
+	}
+
+	r := summarizeConflicts(groups, latest, base, 2)
+	if r.Total != 7 || len(r.Groups) != 3 {
+		t.Fatalf("expected 7 conflicts in 3 groups, got %+v", r)
+	}
+	top := r.Groups[0]
+	if top.Table != "public.b" || top.Count != 4 || !top.First.Equal(base) || !top.Last.Equal(base.Add(time.Minute)) {
+		t.Errorf("unexpected top group: %+v", top)
+	}
+	if r.Groups[1].Origin != "n1" || r.Groups[2].Origin != "n3" {
+		t.Errorf("expected groups ordered by count, got %+v", r.Groups)
+	}
+	if len(r.Recent) != 2 || !r.Recent[0].Time.Equal(base.Add(3*time.Minute)) || !r.Recent[1].Time.Equal(base.Add(2*time.Minute)) {
+		t.Errorf("expected the two newest conflicts, newest first, got %+v", r.Recent)
+	}
+
+	if empty := summarizeConflicts(nil, nil, base, 5); empty.Total != 0 || empty.Groups == nil || empty.Recent == nil {
+		t.Errorf("expected empty, non-nil lists, got %+v", empty)
+	}
+}
+
+func TestReadConflictsAggregatesOnNodes(t *testing.T) {
+	ctx := context.Background()
+	_, dbs, cfg := fakeNodes("n1", "n2")
+	since := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	origin := spockSlotName("app", "n1", "n2")
+	dbs["n1"].on("FROM spock.resolutions", noRows)
+	dbs["n2"].on("GROUP BY r.relname, r.conflict_type, r.remote_origin", func(_ *fakeDB, _ []any) ([][]any, error) {
+		return [][]any{{"public.a", "update_update", origin, 40, since, since.Add(time.Hour)}}, nil
+	})
+	dbs["n2"].on("ORDER BY r.log_time DESC LIMIT $2", func(_ *fakeDB, args []any) ([][]any, error) {
+		if args[1] != 1 {
+			t.Errorf("expected the example limit to be passed, got %v", args[1])
+		}
+		return [][]any{{since.Add(time.Hour), "public.a", "update_update", "apply_remote", origin, "", "(1)"}}, nil
+	})
+
+	r := ReadConflicts(ctx, cfg, fakeConns(dbs), since, 1)
+	if r.Total != 40 || len(r.Groups) != 1 || r.Groups[0].Origin != "n1" || r.Groups[0].Receiver != "n2" {
+		t.Errorf("expected 40 conflicts from n1 on n2, got %+v", r)
+	}
+	if len(r.Recent) != 1 || r.Recent[0].Origin != "n1" || r.Recent[0].RemoteTuple != "(1)" {
+		t.Errorf("expected the latest conflict, got %+v", r.Recent)
+	}
+
+	// Without examples, only the aggregate is read.
+	dbs["n2"].clearLog()
+	ReadConflicts(ctx, cfg, fakeConns(dbs), since, 0)
+	if examples := dbs["n2"].ran("LIMIT $2"); len(examples) != 0 {
+		t.Errorf("expected no example query, ran %v", examples)
+	}
+}
+
+func TestFindPair(t *testing.T) {
+	cfg := &config.Config{Nodes: []config.Node{{Name: "n1"}, {Name: "n2"}}}
+	src, dst, err := findPair(cfg, "sub_n2_n1")
+	if err != nil || src.Name != "n2" || dst.Name != "n1" {
+		t.Errorf("expected n2→n1, got %s→%s (%v)", src.Name, dst.Name, err)
+	}
+	if _, _, err := findPair(cfg, "sub_n1_n3"); err == nil {
+		t.Error("expected an error for a subscription outside the mesh")
+	}
+}
+
+func TestMissingRepsetMembers(t *testing.T) {
+	want := &repsetSnapshot{
+		Sets: []replicationSet{{Name: "default"}, {Name: "custom"}},
+		Tables: []replicationSetTable{
+			{SetName: "default", TableName: "public.orders"},
+			{SetName: "custom", TableName: "public.events_2026"},
+		},
+		Sequences: []replicationSetSeq{{SetName: "default", SeqName: "public.orders_id_seq"}},
+	}
+	got := &repsetSnapshot{
+		Sets:   []replicationSet{{Name: "default"}},
+		Tables: []replicationSetTable{{SetName: "default", TableName: "public.orders"}},
+	}
+	missing := missingRepsetMembers(want, got)
+	expected := []string{
+		`set "custom"`,
+		`table public.events_2026 in set "custom"`,
+		`sequence public.orders_id_seq in set "default"`,
+	}
+	if strings.Join(missing, "; ") != strings.Join(expected, "; ") {
+		t.Errorf("expected %v, got %v", expected, missing)
+	}
+	if missing := missingRepsetMembers(want, want); len(missing) != 0 {
+		t.Errorf("expected nothing missing, got %v", missing)
+	}
+}
+
+func TestSyncProgressObserve(t *testing.T) {
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	p := newSyncProgress("sub_n1_n3", nil, nil)
+	p.started = start
+	p.totalTables, p.totalBytes = 4, 1000
+
+	got := p.observe("d", []copyProgress{{relid: 1, table: "public.a", bytes: 100}}, start.Add(time.Minute))
+	if got.Done != 0 || got.Total != 4 || got.Bytes != 100 || got.Message != "copying data, public.a" {
+		t.Errorf("unexpected progress while copying the first table: %+v", got)
+	}
+	if got.ETA != 9*time.Minute {
+		t.Errorf("expected ETA 9m, got %s", got.ETA)
+	}
+
+	got = p.observe("d", []copyProgress{{relid: 2, table: "public.b", bytes: 400}}, start.Add(2*time.Minute))
+	if got.Done != 1 || got.Bytes != 500 || got.ETA != 2*time.Minute {
+		t.Errorf("expected 1 table done, 500 bytes, ETA 2m, got %+v", got)
+	}
+
+	got = p.observe("u", nil, start.Add(3*time.Minute))
+	if got.Done != 4 || got.Total != 4 || got.ETA != 0 || got.Message != "catching up" {
+		t.Errorf("expected all tables done once catching up, got %+v", got)
+	}
+}
+
+func TestWaitError(t *testing.T) {
+	err := waitError(context.Background(), "peer catchup of n2 on n1", time.Minute, "remote_lsn 0/10, target 0/20")
+	want := "peer catchup of n2 on n1 did not finish within 1m0s (remote_lsn 0/10, target 0/20): context deadline exceeded"
+	if err.Error() != want || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = waitError(ctx, "peer catchup of n2 on n1", time.Minute, "remote_lsn 0/10, target 0/20")
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "interrupted (remote_lsn 0/10, target 0/20)") {
+		t.Errorf("expected an interruption with the progress, got %v", err)
+	}
+}
+
+func TestOpTimeoutsSettings(t *testing.T) {
+	cases := []struct {
+		op              opTimeouts
+		lock, statement string
+	}{
+		{opCatalog, "10000", "60000"},
+		{opDDL, "30000", "600000"},
+		{opLong, "30000", "0"},
+	}
+	for _, tc := range cases {
+		lock, statement := tc.op.settings()
+		if lock != tc.lock || statement != tc.statement {
+			t.Errorf("%+v: settings = %s, %s, want %s, %s", tc.op, lock, statement, tc.lock, tc.statement)
+		}
+	}
+}
+
+// fakeNodes returns a fake mesh with a database per node and the config of
+// those nodes.
+func fakeNodes(names ...string) (*fakeMesh, map[string]*fakeDB, *config.Config) {
+	mesh := newFakeMesh("app")
+	dbs := map[string]*fakeDB{}
+	cfg := &config.Config{DBName: "app", PgEdgeUser: "pgedge"}
+	for _, name := range names {
+		node := config.Node{Name: name, Hostname: "pgedge-" + name + "-rw"}
+		dbs[name] = mesh.add(name, node.Hostname)
+		cfg.Nodes = append(cfg.Nodes, node)
+	}
+	return mesh, dbs, cfg
+}
+
+func fakeConns(dbs map[string]*fakeDB) map[string]DB {
+	conns := make(map[string]DB, len(dbs))
+	for name, db := range dbs {
+		conns[name] = db
+	}
+	return conns
+}
+
+func TestSpockNodeLifecycle(t *testing.T) {
+	ctx := context.Background()
+	_, dbs, cfg := fakeNodes("n1")
+	db := dbs["n1"]
+	n := NewSpockNode(cfg.Nodes[0], "app", "pgedge", db)
+
+	if err := n.Refresh(ctx); err != nil || n.Status().Exists {
+		t.Fatalf("expected no node before Create, got %+v, %v", n.Status(), err)
+	}
+	if err := n.Create(ctx); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := n.Refresh(ctx); err != nil || !n.Status().Exists {
+		t.Fatalf("expected the node after Create, got %+v, %v", n.Status(), err)
+	}
+	if db.cat.local != "n1" || !strings.Contains(db.cat.nodes["n1"], "host=pgedge-n1-rw dbname=app user=pgedge") {
+		t.Errorf("unexpected catalog: local %q, nodes %v", db.cat.local, db.cat.nodes)
+	}
+	if len(db.ran("set_config('lock_timeout'")) != 1 {
+		t.Errorf("expected Create to set its timeouts once, ran %v", db.log)
+	}
+
+	if err := n.Delete(ctx); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := n.Refresh(ctx); err != nil || n.Status().Exists {
+		t.Errorf("expected no node after Delete, got %+v, %v", n.Status(), err)
+	}
+}
+
+func TestSpockNodeCreateFailureRollsBack(t *testing.T) {
+	ctx := context.Background()
+	_, dbs, cfg := fakeNodes("n1")
+	db := dbs["n1"]
+	db.on("spock.node_create(", func(db *fakeDB, _ []any) ([][]any, error) {
+		db.cat.nodes["n1"] = "partial"
+		return nil, &pgconn.PgError{Code: "55P03", Message: "canceling statement due to lock timeout"}
+	})
+
+	err := NewSpockNode(cfg.Nodes[0], "app", "pgedge", db).Create(ctx)
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "55P03" {
+		t.Fatalf("expected the lock timeout to be wrapped, got %v", err)
+	}
+	if len(db.cat.nodes) != 0 {
+		t.Errorf("expected the failed transaction to be rolled back, got nodes %v", db.cat.nodes)
+	}
+}
+
+func TestSubscriptionLifecycle(t *testing.T) {
+	ctx := context.Background()
+	_, dbs, cfg := fakeNodes("n1", "n2")
+	for i, node := range cfg.Nodes {
+		if err := NewSpockNode(node, "app", "pgedge", dbs[node.Name]).Create(ctx); err != nil {
+			t.Fatalf("create node %d: %v", i, err)
+		}
+	}
+	sub := NewSubscription(cfg.Nodes[0], cfg.Nodes[1], "app", "pgedge", false, dbs["n2"])
+	slot := NewReplicationSlot("n1", "n2", "app", dbs["n1"])
+
+	if err := sub.Create(ctx); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := slot.Refresh(ctx); err != nil || !slot.Status().Exists {
+		t.Fatalf("expected sub_create to create the provider's slot, got %+v, %v", slot.Status(), err)
+	}
+	if got := dbs["n2"].cat.subs["sub_n1_n2"].repsets; !slices.Equal(got, subscriptionRepsets) {
+		t.Errorf("expected replication sets %v, got %v", subscriptionRepsets, got)
+	}
+
+	dbs["n2"].cat.subs["sub_n1_n2"].enabled = false
+	if err := sub.Refresh(ctx); err != nil || !sub.Status().NeedsUpdate {
+		t.Fatalf("expected a disabled subscription to need an update, got %+v, %v", sub.Status(), err)
+	}
+	if err := sub.Update(ctx); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := sub.Refresh(ctx); err != nil || sub.Status() != (resource.Status{Exists: true}) {
+		t.Fatalf("expected an enabled subscription, got %+v, %v", sub.Status(), err)
+	}
+
+	// Logged exceptions only make a stopped subscription unhealthy.
+	dbs["n2"].cat.exceptions[sub.replicationSlotName()] = []fakeException{{err: "duplicate key value"}}
+	if err := sub.Refresh(ctx); err != nil || sub.Status() != (resource.Status{Exists: true}) {
+		t.Fatalf("expected a replicating subscription to stay healthy despite discarded transactions, got %+v, %v", sub.Status(), err)
+	}
+	dbs["n2"].cat.subs["sub_n1_n2"].enabled = false
+	if err := sub.Refresh(ctx); err != nil || !sub.Status().Unhealthy || sub.Status().NeedsUpdate ||
+		!strings.Contains(sub.Status().Reason, "1 failed apply operations") || !strings.Contains(sub.Status().Reason, "duplicate key value") {
+		t.Fatalf("expected exceptions to keep a disabled subscription disabled, got %+v, %v", sub.Status(), err)
+	}
+	populated := NewSubscription(cfg.Nodes[0], cfg.Nodes[1], "app", "pgedge", false, dbs["n2"]).fromPopulate()
+	if err := populated.Refresh(ctx); err != nil || !populated.Status().NeedsUpdate || populated.Status().Unhealthy {
+		t.Fatalf("expected a populate's disabled subscription to be enabled despite old exceptions, got %+v, %v", populated.Status(), err)
+	}
+	dbs["n2"].cat.subs["sub_n1_n2"].enabled = true
+	dbs["n2"].on("FROM spock.sub_show_status()", func(*fakeDB, []any) ([][]any, error) {
+		return [][]any{{"down"}}, nil
+	})
+	if err := sub.Refresh(ctx); err != nil || !sub.Status().Unhealthy {
+		t.Fatalf("expected exceptions to mark a subscription whose worker is down unhealthy, got %+v, %v", sub.Status(), err)
+	}
+	delete(dbs["n2"].cat.exceptions, sub.replicationSlotName())
+	if err := sub.Refresh(ctx); err != nil || sub.Status() != (resource.Status{Exists: true}) {
+		t.Fatalf("expected a down subscription without exceptions to be left alone, got %+v, %v", sub.Status(), err)
+	}
+	dbs["n2"].handlers = nil
+
+	if err := sub.Delete(ctx); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := slot.Refresh(ctx); err != nil || slot.Status().Exists {
+		t.Errorf("expected sub_drop to drop the provider's slot, got %+v, %v", slot.Status(), err)
+	}
+}
+
+// fakeSubscribed returns two fake nodes with a subscription from n1 to n2.
+func fakeSubscribed(t *testing.T) (map[string]*fakeDB, *config.Config, *Subscription) {
+	t.Helper()
+	ctx := context.Background()
+	_, dbs, cfg := fakeNodes("n1", "n2")
+	for _, node := range cfg.Nodes {
+		if err := NewSpockNode(node, "app", "pgedge", dbs[node.Name]).Create(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sub := NewSubscription(cfg.Nodes[0], cfg.Nodes[1], "app", "pgedge", false, dbs["n2"])
+	if err := sub.Create(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return dbs, cfg, sub
+}
+
+// assertRestartedAfterCommit checks that the exception log changes were
+// committed before the subscription was disabled and enabled again, both
+// outside a transaction.
+func assertRestartedAfterCommit(t *testing.T, db *fakeDB, changes ...string) {
+	t.Helper()
+	got := db.ranIn(append(changes, "COMMIT", "spock.sub_disable(", "spock.sub_enable(")...)
+	if len(got) != len(changes)+3 {
+		t.Fatalf("unexpected statements: %v", got)
+	}
+	tx := got[0].tx
+	for i, stmt := range got[:len(changes)+1] {
+		if stmt.tx != tx || tx == 0 || (i < len(changes) && !strings.Contains(stmt.sql, changes[i])) {
+			t.Errorf("expected %q in one transaction, committed before the restart, got %v", changes, got)
+			return
+		}
+	}
+	for _, stmt := range got[len(changes)+1:] {
+		if stmt.tx != 0 {
+			t.Errorf("expected the restart outside a transaction, got %v", got)
+		}
+	}
+}
+
+func TestSkipException(t *testing.T) {
+	ctx := context.Background()
+	dbs, cfg, sub := fakeSubscribed(t)
+	first := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	dbs["n2"].cat.exceptions[sub.replicationSlotName()] = []fakeException{
+		{commitTS: first, xid: 741, err: "duplicate key value"},
+		{commitTS: first.Add(time.Second), xid: 742, err: "null value in column"},
+	}
+	dbs["n2"].clearLog()
+
+	e, err := SkipException(ctx, cfg, fakeConns(dbs), "sub_n1_n2")
+	if err != nil {
+		t.Fatalf("SkipException: %v", err)
+	}
+	if e.RemoteXID != 741 || e.Origin != "n1" || e.Receiver != "n2" {
+		t.Errorf("expected the oldest exception to be skipped, got %+v", e)
+	}
+	got := dbs["n2"].cat.subs["sub_n1_n2"]
+	if want := fmt.Sprintf("0/%X", first.Unix()); got.skipLSN != want {
+		t.Errorf("expected skip LSN %s, got %q", want, got.skipLSN)
+	}
+	if left := dbs["n2"].cat.exceptions[sub.replicationSlotName()]; len(left) != 1 || left[0].xid != 742 {
+		t.Errorf("expected only the skipped exception to be cleared, left %+v", left)
+	}
+	if !got.enabled || got.restarts != 1 {
+		t.Errorf("expected the subscription to be restarted once, got %+v", got)
+	}
+	assertRestartedAfterCommit(t, dbs["n2"], "spock.sub_alter_skiplsn(", "DELETE FROM spock.exception_log")
+}
+
+func TestRetryException(t *testing.T) {
+	ctx := context.Background()
+	dbs, cfg, sub := fakeSubscribed(t)
+	dbs["n2"].cat.exceptions[sub.replicationSlotName()] = []fakeException{{xid: 741, err: "duplicate key value"}}
+	dbs["n2"].cat.subs["sub_n1_n2"].enabled = false
+	dbs["n2"].clearLog()
+
+	if err := RetryException(ctx, cfg, fakeConns(dbs), "sub_n1_n2"); err != nil {
+		t.Fatalf("RetryException: %v", err)
+	}
+	if left := dbs["n2"].cat.exceptions[sub.replicationSlotName()]; len(left) != 0 {
+		t.Errorf("expected the exception log to be cleared, left %+v", left)
+	}
+	if got := dbs["n2"].cat.subs["sub_n1_n2"]; !got.enabled || got.restarts != 1 {
+		t.Errorf("expected the subscription to be restarted once, got %+v", got)
+	}
+	assertRestartedAfterCommit(t, dbs["n2"], "DELETE FROM spock.exception_log")
+
+	if _, err := SkipException(ctx, cfg, fakeConns(dbs), "sub_n1_n2"); err == nil || !strings.Contains(err.Error(), "no exceptions logged") {
+		t.Errorf("expected no exception left to skip, got %v", err)
+	}
+}
+
+// failingMirror is a SnapshotMirror holding one node's snapshot whose
+// Clear fails.
+type failingMirror struct{ data []byte }
+
+func (m *failingMirror) Save(_ context.Context, _ string, data []byte) error {
+	m.data = data
+	return nil
+}
+
+func (m *failingMirror) Load(context.Context, string) ([]byte, error) { return m.data, nil }
+
+func (m *failingMirror) Clear(context.Context, string) error {
+	return errors.New("configmaps \"app-repset-snapshot\" is forbidden")
+}
+
+func TestClearSnapshotFailsWhenMirrorIsLeft(t *testing.T) {
+	ctx := context.Background()
+	_, dbs, _ := fakeNodes("n1")
+	db := dbs["n1"]
+	db.on("SELECT to_regclass($1) IS NOT NULL", func(*fakeDB, []any) ([][]any, error) { return exists(true), nil })
+	db.on("DELETE FROM "+snapshotTable, noRows)
+	db.on("SELECT snapshot FROM "+snapshotTable, noRows)
+	mirror := &failingMirror{data: []byte(`{"sets":[],"tables":[]}`)}
+
+	err := clearSnapshot(ctx, db, "n1", mirror)
+	if err == nil || !strings.Contains(err.Error(), "clear mirrored repset snapshot of n1") {
+		t.Fatalf("expected the run to fail while the mirror still holds the snapshot, got %v", err)
+	}
+	if snap, err := loadSnapshot(ctx, db, "n1", mirror); err != nil || snap == nil {
+		t.Errorf("expected the next run to find the mirrored snapshot and retry, got %+v, %v", snap, err)
+	}
+}
+
+func TestSyncEventWait(t *testing.T) {
+	ctx := context.Background()
+	_, dbs, cfg := fakeNodes("n1", "n2")
+	for _, node := range cfg.Nodes {
+		if err := NewSpockNode(node, "app", "pgedge", dbs[node.Name]).Create(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := NewSubscription(cfg.Nodes[0], cfg.Nodes[1], "app", "pgedge", true, dbs["n2"]).Create(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	event := NewSyncEvent("n1", "n2", dbs["n1"])
+	if err := event.Create(ctx); err != nil {
+		t.Fatalf("sync event: %v", err)
+	}
+	if event.LSN != "0/100" {
+		t.Errorf("expected LSN 0/100, got %q", event.LSN)
+	}
+	wait := config.Wait{Timeout: time.Second, Interval: time.Millisecond}
+	if err := NewWaitForSyncEvent("n1", "n2", event, wait, dbs["n2"]).Create(ctx); err != nil {
+		t.Fatalf("wait for sync event: %v", err)
+	}
+
+	dbs["n2"].cat.subs["sub_n1_n2"].enabled = false
+	err := NewWaitForSyncEvent("n1", "n2", event, wait, dbs["n2"]).Create(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a disabled subscription to time out, got %v", err)
+	}
+}
+
+func TestReconcileMesh(t *testing.T) {
+	ctx := context.Background()
+	_, dbs, cfg := fakeNodes("n1", "n2", "n3")
+	if err := resource.Reconcile(ctx, NewReconciler(cfg, fakeConns(dbs), nil)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	for _, dst := range cfg.Nodes {
+		db := dbs[dst.Name]
+		if db.cat.local != dst.Name || !db.cat.roles["pgedge"] {
+			t.Errorf("%s: expected the pgedge role and local node, got %+v", dst.Name, db.cat)
+		}
+		for _, src := range cfg.Nodes {
+			if src.Name == dst.Name {
+				continue
+			}
+			if sub := db.cat.subs[spockSubName(src.Name, dst.Name)]; sub == nil || !sub.enabled || sub.sync {
+				t.Errorf("expected an enabled subscription %s→%s without sync, got %+v", src.Name, dst.Name, sub)
+			}
+			if !dbs[src.Name].cat.slots[spockSlotName("app", src.Name, dst.Name)] {
+				t.Errorf("expected slot %s on %s", spockSlotName("app", src.Name, dst.Name), src.Name)
+			}
+		}
+	}
+
+	// A second run finds everything in place and changes nothing.
+	for _, db := range dbs {
+		db.clearLog()
+	}
+	if err := resource.Reconcile(ctx, NewReconciler(cfg, fakeConns(dbs), nil)); err != nil {
+		t.Fatalf("second Reconcile: %v", err)
+	}
+	for name, db := range dbs {
+		if writes := db.ran("set_config('lock_timeout'"); len(writes) > 0 {
+			t.Errorf("%s: expected no changes on the second run, ran %v", name, db.log)
+		}
+	}
+}
+
+func TestReconcileRemovesNode(t *testing.T) {
+	ctx := context.Background()
+	_, dbs, cfg := fakeNodes("n1", "n2", "n3")
+	if err := resource.Reconcile(ctx, NewReconciler(cfg, fakeConns(dbs), nil)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	cfg.Nodes = cfg.Nodes[:2]
+	survivors := map[string]*fakeDB{"n1": dbs["n1"], "n2": dbs["n2"]}
+	if err := resource.Reconcile(ctx, NewReconciler(cfg, fakeConns(survivors), nil)); err != nil {
+		t.Fatalf("Reconcile without n3: %v", err)
+	}
+	for name, db := range survivors {
+		if _, ok := db.cat.nodes["n3"]; ok {
+			t.Errorf("%s: expected n3 to be dropped, nodes %v", name, sortedKeys(db.cat.nodes))
+		}
+		if subs := sortedKeys(db.cat.subs); len(subs) != 1 || strings.Contains(subs[0], "n3") {
+			t.Errorf("%s: expected only the subscription from the other survivor, got %v", name, subs)
+		}
+		if slots := sortedKeys(db.cat.slots); len(slots) != 1 || strings.Contains(slots[0], "n3") {
+			t.Errorf("%s: expected only the slot for the other survivor, got %v", name, slots)
+		}
+	}
+}
+
+func TestReconcileAddsNodeWithSpockBootstrap(t *testing.T) {
+	ctx := context.Background()
+	mesh, dbs, cfg := fakeNodes("n1", "n2")
+	if err := resource.Reconcile(ctx, NewReconciler(cfg, fakeConns(dbs), nil)); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	n3 := config.Node{
+		Name:      "n3",
+		Hostname:  "pgedge-n3-rw",
+		Bootstrap: config.NodeBootstrap{Mode: "spock", SourceNode: "n1"},
+	}
+	dbs["n3"] = mesh.add("n3", n3.Hostname)
+	cfg.Nodes = append(cfg.Nodes, n3)
+	if err := resource.Reconcile(ctx, NewReconciler(cfg, fakeConns(dbs), nil)); err != nil {
+		t.Fatalf("Reconcile with n3: %v", err)
+	}
+	for _, dst := range cfg.Nodes {
+		for _, src := range cfg.Nodes {
+			if src.Name == dst.Name {
+				continue
+			}
+			if sub := dbs[dst.Name].cat.subs[spockSubName(src.Name, dst.Name)]; sub == nil || !sub.enabled {
+				t.Errorf("expected an enabled subscription %s→%s, got %+v", src.Name, dst.Name, sub)
+			}
+		}
+	}
+	if sub := dbs["n3"].cat.subs[spockSubName("n1", "n3")]; sub == nil || !sub.sync {
+		t.Errorf("expected n3 to sync from its source n1, got %+v", sub)
+	}
+	if sub := dbs["n3"].cat.subs[spockSubName("n2", "n3")]; sub == nil || sub.sync || !slices.Equal(sub.repsets, subscriptionRepsets) {
+		t.Errorf("expected n3's subscription from n2 without sync, got %+v", sub)
+	}
+	// The peer's subscription is created disabled and enabled once its slot
+	// has been advanced past what n3 copied from n1.
+	if created := dbs["n3"].ran("enabled := 'false'"); len(created) != 1 {
+		t.Errorf("expected n3 to create one disabled subscription, ran %v", dbs["n3"].log)
+	}
+	if advanced := dbs["n2"].ran("pg_replication_slot_advance("); len(advanced) != 1 {
+		t.Errorf("expected n2 to advance n3's slot once, ran %v", dbs["n2"].log)
+	}
 }
@@ -7,13 +7,16 @@ import (
 	"fmt"
 	"log/slog"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
-	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/pgEdge/pgedge-helm/internal/config"
 	"github.com/pgEdge/pgedge-helm/internal/resource"
 )
 
+// subscriptionRepsets are the replication sets every subscription uses.
+var subscriptionRepsets = []string{"default", "default_insert_only", "ddl_sql"}
+
 // Subscription manages a Spock subscription between two nodes.
 // Executes on the subscriber (dst) node's connection.
 type Subscription struct {
@@ -22,12 +25,14 @@ type Subscription struct {
 	dbName     string
 	pgedgeUser string
 	sync       bool
-	conn       *pgxpool.Pool // dst node's connection
+	noSchema   bool // sync data only, the schema was copied before
+	populate   bool // created disabled by a populate, enabled once it is done
+	conn       DB   // dst node's connection
 	status     resource.Status
 	extraDeps  []resource.Identifier
 }
 
-func NewSubscription(src, dst config.Node, dbName, pgedgeUser string, sync bool, conn *pgxpool.Pool, extraDeps ...resource.Identifier) *Subscription {
+func NewSubscription(src, dst config.Node, dbName, pgedgeUser string, sync bool, conn DB, extraDeps ...resource.Identifier) *Subscription {
 	return &Subscription{
 		src:        src,
 		dst:        dst,
@@ -39,6 +44,21 @@ func NewSubscription(src, dst config.Node, dbName, pgedgeUser string, sync bool,
 	}
 }
 
+// withoutStructureSync makes an initial sync copy data only, for nodes
+// whose schema SchemaCopy applied beforehand.
+func (s *Subscription) withoutStructureSync() *Subscription {
+	s.noSchema = true
+	return s
+}
+
+// fromPopulate marks a peer→new subscription a populate creates disabled
+// with DisabledSubscription, so Refresh enables it even with entries for
+// its origin in the exception log.
+func (s *Subscription) fromPopulate() *Subscription {
+	s.populate = true
+	return s
+}
+
 func (s *Subscription) subName() string {
 	return spockSubName(s.src.Name, s.dst.Name)
 }
@@ -84,6 +104,41 @@ func (s *Subscription) Refresh(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("check subscription enabled %s: %w", s.subName(), err)
 	}
+
+	// Failed apply transactions stay in the exception log until they are
+	// skipped or retried with init-spock exceptions. They only matter while
+	// they stop the subscription: one Spock disabled, or whose worker is
+	// down, is not restarted, as it would fail on the same transaction
+	// again. With exception_behaviour transdiscard the subscription keeps
+	// replicating past them. A populate's disabled subscription has never
+	// applied anything, so entries for its origin are older than it.
+	down := !isEnabled && !s.populate
+	if isEnabled {
+		var status string
+		err = s.conn.QueryRow(ctx,
+			"SELECT status FROM spock.sub_show_status() WHERE subscription_name = $1",
+			s.subName(),
+		).Scan(&status)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("check subscription status %s: %w", s.subName(), err)
+		}
+		down = status == "down"
+	}
+	if down {
+		exceptions, lastErr, err := subscriptionExceptions(ctx, s.conn, s.replicationSlotName())
+		if err != nil {
+			return fmt.Errorf("check exception log for %s: %w", s.subName(), err)
+		}
+		if exceptions > 0 {
+			s.status = resource.Status{
+				Exists:    true,
+				Unhealthy: true,
+				Reason:    fmt.Sprintf("%d failed apply operations in spock.exception_log, latest: %s", exceptions, lastErr),
+			}
+			return nil
+		}
+	}
+
 	if !isEnabled {
 		s.status = resource.Status{Exists: true, NeedsUpdate: true, Reason: "subscription is disabled"}
 		return nil
@@ -96,7 +151,7 @@ func (s *Subscription) Refresh(ctx context.Context) error {
 func (s *Subscription) Status() resource.Status { return s.status }
 
 func (s *Subscription) Create(ctx context.Context) error {
-	tx, err := s.conn.Begin(ctx)
+	tx, err := beginOp(ctx, s.conn, opCatalog)
 	if err != nil {
 		return fmt.Errorf("begin tx for subscription %s: %w", s.subName(), err)
 	}
@@ -137,7 +192,7 @@ func (s *Subscription) Create(ctx context.Context) error {
 		SELECT spock.sub_create(
 			subscription_name := $1,
 			provider_dsn := $2,
-			replication_sets := '{default, default_insert_only, ddl_sql}',
+			replication_sets := $5,
 			synchronize_structure := $3,
 			synchronize_data := $4,
 			forward_origins := '{}',
@@ -145,7 +200,7 @@ func (s *Subscription) Create(ctx context.Context) error {
 			enabled := 'true'
 		)
 		WHERE $1 NOT IN (SELECT sub_name FROM spock.subscription)
-	`, s.subName(), dsn, s.sync, s.sync)
+	`, s.subName(), dsn, s.sync && !s.noSchema, s.sync, subscriptionRepsets)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == pgCodeDuplicateObject {
@@ -167,7 +222,7 @@ func (s *Subscription) Create(ctx context.Context) error {
 }
 
 func (s *Subscription) Update(ctx context.Context) error {
-	tx, err := s.conn.Begin(ctx)
+	tx, err := beginOp(ctx, s.conn, opCatalog)
 	if err != nil {
 		return fmt.Errorf("begin tx for subscription update %s: %w", s.subName(), err)
 	}
@@ -191,7 +246,7 @@ func (s *Subscription) Update(ctx context.Context) error {
 }
 
 func (s *Subscription) Delete(ctx context.Context) error {
-	tx, err := s.conn.Begin(ctx)
+	tx, err := beginOp(ctx, s.conn, opCatalog)
 	if err != nil {
 		return fmt.Errorf("begin delete tx for %s: %w", s.subName(), err)
 	}
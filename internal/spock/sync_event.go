@@ -6,8 +6,6 @@ import (
 	"fmt"
 	"log/slog"
 
-	"github.com/jackc/pgx/v5/pgxpool"
-
 	"github.com/pgEdge/pgedge-helm/internal/resource"
 )
 
@@ -17,13 +15,13 @@ import (
 type SyncEvent struct {
 	providerName   string
 	subscriberName string
-	conn           *pgxpool.Pool
+	conn           DB
 	extraDeps      []resource.Identifier
 	status         resource.Status
 	LSN            string // populated during Create
 }
 
-func NewSyncEvent(providerName, subscriberName string, conn *pgxpool.Pool, extraDeps ...resource.Identifier) *SyncEvent {
+func NewSyncEvent(providerName, subscriberName string, conn DB, extraDeps ...resource.Identifier) *SyncEvent {
 	return &SyncEvent{
 		providerName:   providerName,
 		subscriberName: subscriberName,
@@ -55,7 +53,7 @@ func (r *SyncEvent) Refresh(_ context.Context) error {
 func (r *SyncEvent) Status() resource.Status { return r.status }
 
 func (r *SyncEvent) Create(ctx context.Context) error {
-	tx, err := r.conn.Begin(ctx)
+	tx, err := beginOp(ctx, r.conn, opCatalog)
 	if err != nil {
 		return fmt.Errorf("begin tx for sync event %s→%s: %w", r.providerName, r.subscriberName, err)
 	}
@@ -0,0 +1,181 @@
+// internal/spock/sync_progress.go
+package spock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/pgEdge/pgedge-helm/internal/resource"
+)
+
+// syncProgressInterval is how often the progress of an initial data sync
+// is logged and reported.
+const syncProgressInterval = 30 * time.Second
+
+// syncPhases names the values of spock.local_sync_status.sync_status.
+var syncPhases = map[string]string{
+	"i": "initializing",
+	"s": "copying structure",
+	"d": "copying data",
+	"c": "creating constraints",
+	"w": "waiting for catchup",
+	"u": "catching up",
+	"y": "synchronized",
+	"r": "ready",
+}
+
+// copyProgress is a COPY in pg_stat_progress_copy on the subscriber.
+type copyProgress struct {
+	relid uint32
+	table string
+	bytes int64
+}
+
+// syncProgress tracks the initial data sync of a subscription. Spock
+// copies every table in one worker and records only the subscription's
+// overall sync status, so the tables done are those whose COPY was seen
+// in pg_stat_progress_copy and has since finished. Totals come from the
+// provider's replication sets; bytes copied are measured in COPY format,
+// so the ETA is an estimate.
+type syncProgress struct {
+	subName     string
+	provider    DB
+	subscriber  DB
+	started     time.Time
+	lastReport  time.Time
+	totalsRead  bool
+	totalTables int
+	totalBytes  int64
+	copied      map[uint32]int64
+	copying     map[uint32]bool
+}
+
+func newSyncProgress(subName string, provider, subscriber DB) *syncProgress {
+	now := time.Now()
+	return &syncProgress{
+		subName:    subName,
+		provider:   provider,
+		subscriber: subscriber,
+		started:    now,
+		lastReport: now,
+		copied:     map[uint32]int64{},
+		copying:    map[uint32]bool{},
+	}
+}
+
+// poll logs and reports the sync progress if syncProgressInterval has
+// passed since the last report. Failures to read it are only logged: they
+// must not interrupt the sync.
+func (p *syncProgress) poll(ctx context.Context) {
+	if time.Since(p.lastReport) < syncProgressInterval {
+		return
+	}
+	p.lastReport = time.Now()
+
+	progress, err := p.read(ctx)
+	if err != nil {
+		slog.Warn("failed to read sync progress", "sub", p.subName, "error", err)
+		return
+	}
+	attrs := []any{"sub", p.subName, "status", progress.Message,
+		"tables_done", progress.Done, "tables_pending", progress.Total - progress.Done,
+		"bytes_copied", progress.Bytes}
+	if progress.ETA > 0 {
+		attrs = append(attrs, "eta", progress.ETA.Round(time.Second).String())
+	}
+	slog.Info("sync progress", attrs...)
+	resource.ReportProgress(ctx, progress)
+}
+
+func (p *syncProgress) read(ctx context.Context) (resource.Progress, error) {
+	if !p.totalsRead {
+		err := p.provider.QueryRow(ctx, `
+			SELECT count(*), coalesce(sum(pg_table_size(reloid)), 0)::bigint
+			FROM (
+				SELECT DISTINCT rst.set_reloid AS reloid
+				FROM spock.replication_set_table rst
+				JOIN spock.replication_set rs ON rs.set_id = rst.set_id
+				WHERE rs.set_name = ANY($1)
+			) t`, subscriptionRepsets,
+		).Scan(&p.totalTables, &p.totalBytes)
+		if err != nil {
+			return resource.Progress{}, fmt.Errorf("read replicated tables on provider: %w", err)
+		}
+		p.totalsRead = true
+	}
+
+	var phase string
+	err := p.subscriber.QueryRow(ctx, `
+		SELECT l.sync_status::text
+		FROM spock.local_sync_status l
+		JOIN spock.subscription s ON s.sub_id = l.sync_subid
+		WHERE s.sub_name = $1 AND l.sync_relname IS NULL`, p.subName,
+	).Scan(&phase)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return resource.Progress{}, fmt.Errorf("read spock.local_sync_status: %w", err)
+	}
+
+	rows, err := p.subscriber.Query(ctx, `
+		SELECT relid, relid::regclass::text, bytes_processed
+		FROM pg_stat_progress_copy
+		WHERE datname = current_database() AND command = 'COPY FROM'`)
+	if err != nil {
+		return resource.Progress{}, fmt.Errorf("read pg_stat_progress_copy: %w", err)
+	}
+	copies, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (copyProgress, error) {
+		var c copyProgress
+		err := row.Scan(&c.relid, &c.table, &c.bytes)
+		return c, err
+	})
+	if err != nil {
+		return resource.Progress{}, fmt.Errorf("read pg_stat_progress_copy: %w", err)
+	}
+	return p.observe(phase, copies, time.Now()), nil
+}
+
+// observe folds the subscription's sync status and the COPYs currently
+// running into the progress so far.
+func (p *syncProgress) observe(phase string, copies []copyProgress, now time.Time) resource.Progress {
+	current := ""
+	copying := make(map[uint32]bool, len(copies))
+	for _, c := range copies {
+		copying[c.relid] = true
+		if c.bytes > p.copied[c.relid] {
+			p.copied[c.relid] = c.bytes
+		}
+		current = c.table
+	}
+	p.copying = copying
+
+	progress := resource.Progress{Total: p.totalTables, Message: syncPhases[phase]}
+	if progress.Message == "" {
+		progress.Message = "starting"
+	}
+	for relid, bytes := range p.copied {
+		progress.Bytes += bytes
+		if !p.copying[relid] {
+			progress.Done++
+		}
+	}
+	switch phase {
+	case "c", "w", "u", "y", "r":
+		// The data copy is over.
+		progress.Done = max(progress.Done, progress.Total)
+	case "d":
+		if current != "" {
+			progress.Message += ", " + current
+		}
+	}
+	progress.Total = max(progress.Total, progress.Done)
+
+	if remaining := p.totalBytes - progress.Bytes; progress.Bytes > 0 && remaining > 0 && progress.Done < progress.Total {
+		elapsed := now.Sub(p.started)
+		progress.ETA = time.Duration(float64(elapsed) * float64(remaining) / float64(progress.Bytes))
+	}
+	return progress
+}
@@ -0,0 +1,80 @@
+// internal/spock/tx.go
+package spock
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// opTimeouts bound the statements of an administrative transaction, so a
+// call such as spock.sub_drop fails instead of queueing indefinitely behind
+// an application's locks. A zero duration disables the timeout.
+//
+// A statement that gives up waiting for a lock fails with lock_not_available
+// (55P03), which pg.IsRetryable treats as transient: the step runs again
+// after a backoff. A statement timeout is not retried.
+type opTimeouts struct {
+	lock      time.Duration
+	statement time.Duration
+}
+
+var (
+	// opCatalog covers changes to Spock's catalog and to replication slots
+	// and origins: node, subscription and replication set changes, sync
+	// events and slot drops.
+	opCatalog = opTimeouts{lock: 10 * time.Second, statement: time.Minute}
+	// opDDL covers DROP and CREATE EXTENSION spock. Dropping the extension
+	// removes its triggers from every replicated table, so it needs a lock
+	// on each of them.
+	opDDL = opTimeouts{lock: 30 * time.Second, statement: 10 * time.Minute}
+	// opLong covers work that is meant to take as long as the data does:
+	// schema copies, checksums, slot creation, which waits for running
+	// transactions, and slot advances, which decode WAL. Only lock waits are
+	// bounded. Waiting for a sync event (WaitForSyncEvent, PeerCatchup) and
+	// the COPY of a subscription's initial sync do not run in these
+	// transactions at all.
+	opLong = opTimeouts{lock: 30 * time.Second}
+)
+
+// settings returns the values of lock_timeout and statement_timeout for op,
+// in milliseconds.
+func (op opTimeouts) settings() (lock, statement string) {
+	return strconv.FormatInt(op.lock.Milliseconds(), 10), strconv.FormatInt(op.statement.Milliseconds(), 10)
+}
+
+// beginOp begins a transaction with lock_timeout and statement_timeout set
+// for op. The settings are local to the transaction, so they never leak to
+// other users of the pooled connection.
+func beginOp(ctx context.Context, conn DB, op opTimeouts) (pgx.Tx, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lock, statement := op.settings()
+	_, err = tx.Exec(ctx,
+		"SELECT set_config('lock_timeout', $1, true), set_config('statement_timeout', $2, true)",
+		lock, statement,
+	)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, fmt.Errorf("set timeouts: %w", err)
+	}
+	return tx, nil
+}
+
+// execOp runs a single statement in its own transaction begun by beginOp.
+func execOp(ctx context.Context, conn DB, op opTimeouts, sql string, args ...any) error {
+	tx, err := beginOp(ctx, conn, op)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
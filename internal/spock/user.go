@@ -8,7 +8,6 @@ import (
 	"log/slog"
 
 	"github.com/jackc/pgx/v5/pgconn"
-	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/pgEdge/pgedge-helm/internal/config"
 	"github.com/pgEdge/pgedge-helm/internal/resource"
@@ -19,11 +18,11 @@ type PgEdgeUser struct {
 	node       config.Node
 	dbName     string
 	pgedgeUser string
-	conn       *pgxpool.Pool
+	conn       DB
 	status     resource.Status
 }
 
-func NewPgEdgeUser(node config.Node, dbName, pgedgeUser string, conn *pgxpool.Pool) *PgEdgeUser {
+func NewPgEdgeUser(node config.Node, dbName, pgedgeUser string, conn DB) *PgEdgeUser {
 	return &PgEdgeUser{
 		node:       node,
 		dbName:     dbName,
@@ -53,7 +52,7 @@ func (u *PgEdgeUser) Refresh(ctx context.Context) error {
 func (u *PgEdgeUser) Status() resource.Status { return u.status }
 
 func (u *PgEdgeUser) Create(ctx context.Context) error {
-	tx, err := u.conn.Begin(ctx)
+	tx, err := beginOp(ctx, u.conn, opCatalog)
 	if err != nil {
 		return fmt.Errorf("begin tx on %s: %w", u.node.Name, err)
 	}
@@ -0,0 +1,286 @@
+// internal/spock/verify_data.go
+package spock
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/pgEdge/pgedge-helm/internal/config"
+	"github.com/pgEdge/pgedge-helm/internal/resource"
+)
+
+const (
+	// verifyChunks is the number of chunks each table's rows are hashed
+	// into, so a mismatch tells roughly how much of a table differs. It must
+	// be a power of two.
+	verifyChunks = 16
+	// verifyAttempts bounds how often a table that differs, or that changed
+	// on the source while it was compared, is compared again.
+	verifyAttempts = 3
+)
+
+// VerifyData compares a newly added node's data with its source node once
+// populate has finished and every subscription to the new node is enabled.
+// For each table in the subscriptions' replication sets it compares the row
+// count and per-chunk hash checksums.
+//
+// Each attempt checksums the source, sends a sync event from the source and
+// waits for the new node to apply it, checksums the new node, then the
+// source again. A table whose two source checksums differ was written to
+// meanwhile and is compared again; so is a table that differs. Tables still
+// differing after verifyAttempts are reported: as warnings with
+// VerifyDataWarn, as an error with VerifyDataFail.
+//
+// Tables whose replication set membership has a column list or row filter
+// are skipped, their copies are not meant to be identical.
+//
+// Ephemeral — re-executes every run while the node has a bootstrap block.
+type VerifyData struct {
+	source  config.Node
+	newNode config.Node
+	peers   []string // nodes subscribed to by the new node
+	mode    string
+	wait    config.Wait
+	srcConn DB
+	conn    DB // new node's connection
+	status  resource.Status
+}
+
+func NewVerifyData(source, newNode config.Node, peers []string, mode string, wait config.Wait, srcConn, conn DB) *VerifyData {
+	return &VerifyData{
+		source:  source,
+		newNode: newNode,
+		peers:   peers,
+		mode:    mode,
+		wait:    wait,
+		srcConn: srcConn,
+		conn:    conn,
+	}
+}
+
+func (r *VerifyData) Identifier() resource.Identifier {
+	return resource.Identifier{Type: ResourceTypeVerifyData, ID: r.newNode.Name}
+}
+
+func (r *VerifyData) Dependencies() []resource.Identifier {
+	deps := make([]resource.Identifier, 0, len(r.peers))
+	for _, peer := range r.peers {
+		deps = append(deps, resource.Identifier{Type: ResourceTypeSubscription, ID: spockSubName(peer, r.newNode.Name)})
+	}
+	return deps
+}
+
+func (r *VerifyData) Refresh(_ context.Context) error {
+	r.status = resource.Status{Exists: false}
+	return nil
+}
+
+func (r *VerifyData) Status() resource.Status { return r.status }
+
+// chunkChecksum holds the row count and hash sum of a chunk of a table.
+type chunkChecksum struct {
+	Rows int64
+	Sum  string
+}
+
+// tableChecksum maps chunk numbers to their checksums; empty chunks are
+// absent.
+type tableChecksum map[int]chunkChecksum
+
+func (c tableChecksum) rows() int64 {
+	var n int64
+	for _, chunk := range c {
+		n += chunk.Rows
+	}
+	return n
+}
+
+// diffChunks returns the chunks whose checksums differ, in order.
+func diffChunks(a, b tableChecksum) []int {
+	var chunks []int
+	for i := 0; i < verifyChunks; i++ {
+		if a[i] != b[i] {
+			chunks = append(chunks, i)
+		}
+	}
+	return chunks
+}
+
+// verifyTable is a replicated table and whether its membership filters
+// rows or columns.
+type verifyTable struct {
+	Name     string
+	Filtered bool
+}
+
+func (r *VerifyData) Create(ctx context.Context) error {
+	tables, err := replicatedTables(ctx, r.srcConn, r.source.Name)
+	if err != nil {
+		return err
+	}
+	var pending []string
+	for _, t := range tables {
+		if t.Filtered {
+			slog.Info("not verifying table with a column list or row filter", "node", r.newNode.Name, "table", t.Name)
+			continue
+		}
+		pending = append(pending, t.Name)
+	}
+	slog.Info("verifying data", "source", r.source.Name, "node", r.newNode.Name, "tables", len(pending))
+
+	var differ, busy []string
+	differing := map[string]string{}
+	for attempt := 1; attempt <= verifyAttempts && len(pending) > 0; attempt++ {
+		before, err := checksumTables(ctx, r.srcConn, r.source.Name, pending)
+		if err != nil {
+			return err
+		}
+		// Everything the source had committed when it was checksummed has
+		// reached the new node once it applied the sync event.
+		event := NewSyncEvent(r.source.Name, r.newNode.Name, r.srcConn)
+		if err := event.Create(ctx); err != nil {
+			return err
+		}
+		if err := NewWaitForSyncEvent(r.source.Name, r.newNode.Name, event, r.wait, r.conn).Create(ctx); err != nil {
+			return err
+		}
+		got, err := checksumTables(ctx, r.conn, r.newNode.Name, pending)
+		if err != nil {
+			return err
+		}
+		after, err := checksumTables(ctx, r.srcConn, r.source.Name, pending)
+		if err != nil {
+			return err
+		}
+
+		differ, busy = nil, nil
+		for _, table := range pending {
+			if len(diffChunks(before[table], after[table])) > 0 {
+				busy = append(busy, table)
+				continue
+			}
+			if chunks := diffChunks(before[table], got[table]); len(chunks) > 0 {
+				differ = append(differ, table)
+				differing[table] = fmt.Sprintf("%s: %d rows on %s, %d on %s, %d of %d chunks differ",
+					table, before[table].rows(), r.source.Name, got[table].rows(), r.newNode.Name, len(chunks), verifyChunks)
+			}
+		}
+		pending = append(append([]string{}, differ...), busy...)
+		if len(pending) > 0 && attempt < verifyAttempts {
+			slog.Info("comparing tables again", "node", r.newNode.Name, "differ", len(differ), "changed", len(busy), "attempt", attempt)
+		}
+	}
+
+	for _, table := range busy {
+		slog.Warn("could not verify table, it kept changing on the source", "node", r.newNode.Name, "table", table)
+	}
+	if len(differ) == 0 {
+		slog.Info("verified data", "source", r.source.Name, "node", r.newNode.Name, "unverified", len(busy))
+		return nil
+	}
+	reports := make([]string, len(differ))
+	for i, table := range differ {
+		reports[i] = differing[table]
+		slog.Warn("table differs from source", "node", r.newNode.Name, "source", r.source.Name, "mismatch", reports[i])
+	}
+	if r.mode == config.VerifyDataFail {
+		return fmt.Errorf("data of %s differs from %s in %d tables: %s",
+			r.newNode.Name, r.source.Name, len(differ), strings.Join(reports, "; "))
+	}
+	return nil
+}
+
+func (r *VerifyData) Update(_ context.Context) error { return nil }
+
+func (r *VerifyData) Delete(_ context.Context) error { return nil }
+
+// replicatedTables lists the tables in the replication sets subscriptions
+// use, by qualified name.
+func replicatedTables(ctx context.Context, conn DB, node string) ([]verifyTable, error) {
+	rows, err := conn.Query(ctx, `
+		SELECT quote_ident(n.nspname) || '.' || quote_ident(c.relname), bool_or(rst.set_att_list IS NOT NULL OR rst.set_row_filter IS NOT NULL)
+		FROM spock.replication_set_table rst
+		JOIN spock.replication_set rs ON rs.set_id = rst.set_id
+		JOIN pg_class c ON c.oid = rst.set_reloid AND c.relkind = 'r'
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE rs.set_name = ANY($1)
+		GROUP BY n.nspname, c.relname
+		ORDER BY 1`, subscriptionRepsets)
+	if err != nil {
+		return nil, fmt.Errorf("list replicated tables on %s: %w", node, err)
+	}
+	defer rows.Close()
+	var tables []verifyTable
+	for rows.Next() {
+		var t verifyTable
+		if err := rows.Scan(&t.Name, &t.Filtered); err != nil {
+			return nil, fmt.Errorf("list replicated tables on %s: %w", node, err)
+		}
+		tables = append(tables, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list replicated tables on %s: %w", node, err)
+	}
+	return tables, nil
+}
+
+// checksumSettings make a row's text form the same on every node.
+var checksumSettings = []string{
+	"SET LOCAL TimeZone = 'UTC'",
+	"SET LOCAL DateStyle = 'ISO, YMD'",
+	"SET LOCAL IntervalStyle = 'postgres'",
+	"SET LOCAL extra_float_digits = 3",
+	"SET LOCAL bytea_output = 'hex'",
+}
+
+// checksumQuery returns the per-chunk checksum query of a table. It hashes
+// (t.*), which is the whole row even in a table with a column named t.
+func checksumQuery(table string) string {
+	return fmt.Sprintf(`
+		SELECT (h & %d)::int, count(*), sum(h)::text
+		FROM (SELECT hashtextextended((t.*)::text, 0) AS h FROM %s t) rows
+		GROUP BY 1`, verifyChunks-1, table)
+}
+
+// checksumTables hashes every row of the tables by its text form and
+// returns per-chunk row counts and hash sums. Rows are assigned to chunks
+// by their hash, so the checksum does not depend on row order or keys.
+func checksumTables(ctx context.Context, conn DB, node string, tables []string) (map[string]tableChecksum, error) {
+	tx, err := beginOp(ctx, conn, opLong)
+	if err != nil {
+		return nil, fmt.Errorf("begin checksum on %s: %w", node, err)
+	}
+	defer tx.Rollback(ctx)
+	for _, stmt := range checksumSettings {
+		if _, err := tx.Exec(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("prepare checksum on %s: %w", node, err)
+		}
+	}
+
+	sums := make(map[string]tableChecksum, len(tables))
+	for _, table := range tables {
+		// The name is qualified and quoted by replicatedTables.
+		rows, err := tx.Query(ctx, checksumQuery(table))
+		if err != nil {
+			return nil, fmt.Errorf("checksum %s on %s: %w", table, node, err)
+		}
+		sum := tableChecksum{}
+		for rows.Next() {
+			var chunk int
+			var c chunkChecksum
+			if err := rows.Scan(&chunk, &c.Rows, &c.Sum); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("checksum %s on %s: %w", table, node, err)
+			}
+			sum[chunk] = c
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("checksum %s on %s: %w", table, node, err)
+		}
+		sums[table] = sum
+	}
+	return sums, nil
+}
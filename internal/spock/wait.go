@@ -0,0 +1,59 @@
+// internal/spock/wait.go
+package spock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// describeTimeout bounds the queries describing how far a wait got; they
+// run after the wait's own context is done.
+const describeTimeout = 5 * time.Second
+
+// waitError reports a wait step whose context is done: either its own
+// timeout expired or the run's context (ctx) ended. progress says how far
+// the step got.
+func waitError(ctx context.Context, step string, timeout time.Duration, progress string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s interrupted (%s): %w", step, progress, err)
+	}
+	return fmt.Errorf("%s did not finish within %s (%s): %w", step, timeout, progress, context.DeadlineExceeded)
+}
+
+// remoteLSN returns the LSN up to which the node behind conn has applied
+// the changes of peerName, from spock.progress, or "" if it has none.
+func remoteLSN(ctx context.Context, conn DB, peerName string) (string, error) {
+	var lsn string
+	err := conn.QueryRow(ctx, `
+		SELECT p.remote_lsn::text
+		FROM spock.progress p
+		JOIN spock.node n ON n.node_id = p.remote_node_id
+		WHERE p.node_id = (SELECT node_id FROM spock.node_info())
+		  AND n.node_name = $1`, peerName,
+	).Scan(&lsn)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return lsn, err
+}
+
+// describeApply describes how far the node behind conn has applied
+// peerName's changes relative to target, e.g.
+// "remote_lsn 0/3000060, target 0/3000148".
+func describeApply(ctx context.Context, conn DB, peerName, target string) string {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), describeTimeout)
+	defer cancel()
+	lsn, err := remoteLSN(ctx, conn, peerName)
+	switch {
+	case err != nil:
+		return fmt.Sprintf("target %s, remote_lsn unknown: %v", target, err)
+	case lsn == "":
+		return fmt.Sprintf("target %s, nothing applied from %s yet", target, peerName)
+	default:
+		return fmt.Sprintf("remote_lsn %s, target %s", lsn, target)
+	}
+}
@@ -7,15 +7,10 @@ import (
 	"log/slog"
 	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
-
+	"github.com/pgEdge/pgedge-helm/internal/config"
 	"github.com/pgEdge/pgedge-helm/internal/resource"
 )
 
-const (
-	syncEventTimeout = 10 // seconds, passed to spock.wait_for_sync_event
-)
-
 // WaitForSyncEvent polls on the subscriber until it receives the sync event
 // from the provider. Reads the LSN from the paired SyncEvent via struct pointer.
 //
@@ -24,24 +19,38 @@ const (
 // during add-node. Unknown subscription states are treated as errors
 // (fail-closed default).
 //
+// Each call to spock.wait_for_sync_event blocks for up to wait.Interval.
+// Once wait.Timeout expires the wait fails, reporting the subscription's
+// status and how far it has applied the provider's changes.
+//
 // Ephemeral resource — re-executes every run.
 type WaitForSyncEvent struct {
 	providerName   string
 	subscriberName string
 	syncEvent      *SyncEvent
-	conn           *pgxpool.Pool // subscriber's connection
+	wait           config.Wait
+	conn           DB // subscriber's connection
+	provider       DB // set to report initial sync progress
 	status         resource.Status
 }
 
-func NewWaitForSyncEvent(providerName, subscriberName string, syncEvent *SyncEvent, conn *pgxpool.Pool) *WaitForSyncEvent {
+func NewWaitForSyncEvent(providerName, subscriberName string, syncEvent *SyncEvent, wait config.Wait, conn DB) *WaitForSyncEvent {
 	return &WaitForSyncEvent{
 		providerName:   providerName,
 		subscriberName: subscriberName,
 		syncEvent:      syncEvent,
+		wait:           wait,
 		conn:           conn,
 	}
 }
 
+// withSyncProgress makes the wait report the progress of the initial data
+// sync of the provider→subscriber subscription while it runs.
+func (r *WaitForSyncEvent) withSyncProgress(provider DB) *WaitForSyncEvent {
+	r.provider = provider
+	return r
+}
+
 func (r *WaitForSyncEvent) Identifier() resource.Identifier {
 	return resource.Identifier{
 		Type: ResourceTypeWaitForSyncEvent,
@@ -70,15 +79,30 @@ func (r *WaitForSyncEvent) Create(ctx context.Context) error {
 	slog.Info("waiting for sync event",
 		"provider", r.providerName, "subscriber", r.subscriberName, "lsn", r.syncEvent.LSN)
 
+	subName := spockSubName(r.providerName, r.subscriberName)
+	var progress *syncProgress
+	if r.provider != nil {
+		progress = newSyncProgress(subName, r.provider, r.conn)
+	}
+
+	waitCtx, cancel := r.wait.Context(ctx)
+	defer cancel()
+	status := "unknown"
+	timedOut := func() error {
+		return waitError(ctx, fmt.Sprintf("sync event wait of %s on %s", r.providerName, r.subscriberName), r.wait.Timeout,
+			fmt.Sprintf("subscription %s %s, %s", subName, status, describeApply(ctx, r.conn, r.providerName, r.syncEvent.LSN)))
+	}
+
 	for {
-		if ctx.Err() != nil {
-			return ctx.Err()
+		if waitCtx.Err() != nil {
+			return timedOut()
+		}
+		if progress != nil {
+			progress.poll(waitCtx)
 		}
 
 		// Check subscription health — fail early if broken
-		subName := spockSubName(r.providerName, r.subscriberName)
-		var status string
-		err := r.conn.QueryRow(ctx,
+		err := r.conn.QueryRow(waitCtx,
 			"SELECT status FROM spock.sub_show_status() WHERE subscription_name = $1",
 			subName,
 		).Scan(&status)
@@ -94,9 +118,9 @@ func (r *WaitForSyncEvent) Create(ctx context.Context) error {
 				// before next iteration to avoid busy-looping while the
 				// worker comes up.
 				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				case <-time.After(syncEventTimeout * time.Second):
+				case <-waitCtx.Done():
+					return timedOut()
+				case <-time.After(r.wait.Interval):
 				}
 				continue
 			default:
@@ -108,11 +132,14 @@ func (r *WaitForSyncEvent) Create(ctx context.Context) error {
 		// Wait for sync event — CALL returns the INOUT synced boolean.
 		// The procedure blocks for up to $timeout seconds.
 		var synced bool
-		err = r.conn.QueryRow(ctx,
+		err = r.conn.QueryRow(waitCtx,
 			"CALL spock.wait_for_sync_event(true, $1, $2, $3)",
-			r.providerName, r.syncEvent.LSN, syncEventTimeout,
+			r.providerName, r.syncEvent.LSN, int(r.wait.Interval.Seconds()),
 		).Scan(&synced)
 		if err != nil {
+			if waitCtx.Err() != nil {
+				return timedOut()
+			}
 			return fmt.Errorf("wait_for_sync_event for %s→%s: %w",
 				r.providerName, r.subscriberName, err)
 		}
@@ -0,0 +1,383 @@
+// internal/status/status.go
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/pgEdge/pgedge-helm/internal/cluster"
+	"github.com/pgEdge/pgedge-helm/internal/config"
+	"github.com/pgEdge/pgedge-helm/internal/resource"
+	"github.com/pgEdge/pgedge-helm/internal/spock"
+)
+
+// Compile-time assertion: Recorder implements resource.ProgressObserver.
+var _ resource.ProgressObserver = (*Recorder)(nil)
+
+// component identifies init-spock as the source of the Events it emits.
+const component = "pgedge-init-spock"
+
+const (
+	OutcomeSucceeded = "Succeeded"
+	OutcomeFailed    = "Failed"
+	// OutcomeRunning is the outcome of the report written while a run is
+	// still going, for the progress of its long-running steps.
+	OutcomeRunning = "Running"
+)
+
+// progressWriteTimeout bounds writing a progress report, which blocks the
+// step reporting it.
+const progressWriteTimeout = 10 * time.Second
+
+var cnpgGVK = schema.GroupVersionKind{Group: "postgresql.cnpg.io", Version: "v1", Kind: "Cluster"}
+
+var cnpgGVR = schema.GroupVersionResource{Group: "postgresql.cnpg.io", Version: "v1", Resource: "clusters"}
+
+// ConfigMapName returns the name of the ConfigMap holding the last run's report.
+func ConfigMapName(appName string) string {
+	return appName + "-spock-status"
+}
+
+// PlannedAction is one event of the execution plan.
+type PlannedAction struct {
+	Phase  int    `json:"phase"`
+	Action string `json:"action"`
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+}
+
+// ActionResult is the outcome of one executed event.
+type ActionResult struct {
+	Action     string `json:"action"`
+	Type       string `json:"type"`
+	ID         string `json:"id"`
+	DurationMS int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ActionProgress is the latest progress a running event reported, e.g. a
+// subscription's initial sync.
+type ActionProgress struct {
+	Action     string    `json:"action"`
+	Type       string    `json:"type"`
+	ID         string    `json:"id"`
+	Message    string    `json:"message,omitempty"`
+	Done       int       `json:"done"`
+	Total      int       `json:"total,omitempty"`
+	Bytes      int64     `json:"bytes,omitempty"`
+	ETASeconds int64     `json:"etaSeconds,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Report is the JSON document stored in the status ConfigMap. While the
+// run goes on, its outcome is Running, it has no finish time and Progress
+// lists the events still running.
+type Report struct {
+	Outcome       string                     `json:"outcome"`
+	Error         string                     `json:"error,omitempty"`
+	StartedAt     time.Time                  `json:"startedAt"`
+	FinishedAt    time.Time                  `json:"finishedAt,omitzero"`
+	Plan          []PlannedAction            `json:"plan"`
+	Actions       []ActionResult             `json:"actions"`
+	Progress      []ActionProgress           `json:"progress,omitempty"`
+	Subscriptions []spock.SubscriptionHealth `json:"subscriptions"`
+}
+
+// Recorder implements resource.ProgressObserver. It collects the plan and
+// the outcome of each event during a run, then publishes them as
+// Kubernetes Events on the nodes' CNPG Clusters and as the status
+// ConfigMap. Progress reports update the ConfigMap while the run goes on.
+type Recorder struct {
+	cfg     *config.Config
+	kube    kubernetes.Interface
+	dynamic dynamic.Interface
+	started time.Time
+
+	mu        sync.Mutex
+	plan      []PlannedAction
+	actions   []ActionResult
+	notices   []spock.Notice
+	populated []string
+	progress  map[resource.Identifier]ActionProgress
+	reports   int // progress reports built, to skip writing stale ones
+	published bool
+
+	// writeMu serializes the Kubernetes writes, which are made without
+	// holding mu so that a slow API server does not block the run's
+	// events. It guards uids.
+	writeMu sync.Mutex
+	uids    map[string]types.UID
+}
+
+// NewRecorder creates a Recorder using the given clients.
+func NewRecorder(cfg *config.Config, kube kubernetes.Interface, dyn dynamic.Interface) *Recorder {
+	return &Recorder{
+		cfg:      cfg,
+		kube:     kube,
+		dynamic:  dyn,
+		started:  time.Now().UTC(),
+		progress: map[resource.Identifier]ActionProgress{},
+		uids:     map[string]types.UID{},
+	}
+}
+
+// Planned records the execution plan.
+func (r *Recorder) Planned(phases [][]resource.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, phase := range phases {
+		for _, event := range phase {
+			id := event.Resource.Identifier()
+			r.plan = append(r.plan, PlannedAction{Phase: i, Action: event.Action.String(), Type: id.Type, ID: id.ID})
+		}
+	}
+}
+
+// Finished records the outcome of an executed event.
+func (r *Recorder) Finished(event resource.Event, elapsed time.Duration, err error) {
+	id := event.Resource.Identifier()
+	result := ActionResult{
+		Action:     event.Action.String(),
+		Type:       id.Type,
+		ID:         id.ID,
+		DurationMS: elapsed.Milliseconds(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.actions = append(r.actions, result)
+	delete(r.progress, id)
+	if notice, ok := spock.DescribeEvent(event, err); ok {
+		r.notices = append(r.notices, notice)
+	}
+	if node, ok := spock.PopulateTarget(event); ok && err == nil {
+		r.populated = append(r.populated, node)
+	}
+}
+
+// Progress records the latest progress of a running event and writes the
+// status ConfigMap with the outcome Running, so a long sync can be
+// followed there as well as in the logs. Failures are logged.
+func (r *Recorder) Progress(event resource.Event, p resource.Progress) {
+	id := event.Resource.Identifier()
+	r.mu.Lock()
+	if r.published {
+		r.mu.Unlock()
+		return
+	}
+	r.progress[id] = ActionProgress{
+		Action:     event.Action.String(),
+		Type:       id.Type,
+		ID:         id.ID,
+		Message:    p.Message,
+		Done:       p.Done,
+		Total:      p.Total,
+		Bytes:      p.Bytes,
+		ETASeconds: int64(p.ETA.Seconds()),
+		UpdatedAt:  time.Now().UTC(),
+	}
+	report := r.report(nil, nil)
+	report.Outcome = OutcomeRunning
+	report.FinishedAt = time.Time{}
+	r.reports++
+	seq := r.reports
+	r.mu.Unlock()
+
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+	// Skip the write if a newer report or the final one got ahead of it.
+	r.mu.Lock()
+	stale := r.published || seq != r.reports
+	r.mu.Unlock()
+	if stale {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), progressWriteTimeout)
+	defer cancel()
+	if err := r.writeConfigMap(ctx, report); err != nil {
+		slog.Warn("write status configmap", "name", ConfigMapName(r.cfg.AppName), "error", err)
+	}
+}
+
+// Publish emits the collected Events and writes the status ConfigMap.
+// runErr is the overall outcome of the run; subs is the subscription
+// health observed at the end of it. Failures are logged, not returned, so
+// publishing never changes the Job's result.
+func (r *Recorder) Publish(ctx context.Context, runErr error, subs []spock.SubscriptionHealth) {
+	r.mu.Lock()
+	r.published = true
+	notices := append([]spock.Notice(nil), r.notices...)
+	if runErr == nil {
+		for _, node := range r.populated {
+			notices = append(notices, spock.Notice{Node: node, Reason: "PopulateFinished",
+				Message: fmt.Sprintf("spock node %s populated and replicating with all peers", node)})
+		}
+	}
+	report := r.report(runErr, subs)
+	r.mu.Unlock()
+
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+	for _, n := range notices {
+		if err := r.emit(ctx, n); err != nil {
+			slog.Warn("emit event", "node", n.Node, "reason", n.Reason, "error", err)
+		}
+	}
+	if err := r.writeConfigMap(ctx, report); err != nil {
+		slog.Warn("write status configmap", "name", ConfigMapName(r.cfg.AppName), "error", err)
+		return
+	}
+	slog.Info("published spock status", "configmap", ConfigMapName(r.cfg.AppName),
+		"outcome", report.Outcome, "events", len(notices))
+}
+
+func (r *Recorder) report(runErr error, subs []spock.SubscriptionHealth) Report {
+	report := Report{
+		Outcome:       OutcomeSucceeded,
+		StartedAt:     r.started,
+		FinishedAt:    time.Now().UTC(),
+		Plan:          r.plan,
+		Actions:       append([]ActionResult(nil), r.actions...),
+		Subscriptions: subs,
+	}
+	for _, p := range r.progress {
+		report.Progress = append(report.Progress, p)
+	}
+	if runErr != nil {
+		report.Outcome = OutcomeFailed
+		report.Error = runErr.Error()
+	}
+	// Events in a phase finish in arbitrary order; sort for stable output.
+	sort.SliceStable(report.Actions, func(i, j int) bool {
+		a, b := report.Actions[i], report.Actions[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.ID < b.ID
+	})
+	sort.Slice(report.Progress, func(i, j int) bool {
+		a, b := report.Progress[i], report.Progress[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.ID < b.ID
+	})
+	return report
+}
+
+// emit creates an Event on the CNPG Cluster of the notice's node. Notices
+// for external nodes are skipped: their Clusters live outside this release.
+func (r *Recorder) emit(ctx context.Context, n spock.Notice) error {
+	if !r.isLocal(n.Node) {
+		return nil
+	}
+	name := cluster.ClusterName(r.cfg.AppName, n.Node)
+	uid, err := r.clusterUID(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	eventType := corev1.EventTypeNormal
+	if n.Warning {
+		eventType = corev1.EventTypeWarning
+	}
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", name, now.UnixNano()),
+			Namespace: r.cfg.Namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: cnpgGVK.GroupVersion().String(),
+			Kind:       cnpgGVK.Kind,
+			Name:       name,
+			Namespace:  r.cfg.Namespace,
+			UID:        uid,
+		},
+		Reason:              n.Reason,
+		Message:             n.Message,
+		Type:                eventType,
+		Source:              corev1.EventSource{Component: component},
+		ReportingController: component,
+		FirstTimestamp:      now,
+		LastTimestamp:       now,
+		Count:               1,
+	}
+	_, err = r.kube.CoreV1().Events(r.cfg.Namespace).Create(ctx, event, metav1.CreateOptions{})
+	return err
+}
+
+func (r *Recorder) isLocal(nodeName string) bool {
+	for _, node := range r.cfg.LocalNodes() {
+		if node.Name == nodeName {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterUID looks up and caches the UID of a CNPG Cluster, which Events
+// need so that `kubectl describe cluster` lists them.
+func (r *Recorder) clusterUID(ctx context.Context, name string) (types.UID, error) {
+	if uid, ok := r.uids[name]; ok {
+		return uid, nil
+	}
+	obj, err := r.dynamic.Resource(cnpgGVR).Namespace(r.cfg.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("get CNPG cluster %s: %w", name, err)
+	}
+	r.uids[name] = obj.GetUID()
+	return obj.GetUID(), nil
+}
+
+func (r *Recorder) writeConfigMap(ctx context.Context, report Report) error {
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal status report: %w", err)
+	}
+	data := map[string]string{
+		"outcome":     report.Outcome,
+		"startedAt":   report.StartedAt.Format(time.RFC3339),
+		"status.json": string(body),
+	}
+	if !report.FinishedAt.IsZero() {
+		data["finishedAt"] = report.FinishedAt.Format(time.RFC3339)
+	}
+
+	cms := r.kube.CoreV1().ConfigMaps(r.cfg.Namespace)
+	name := ConfigMapName(r.cfg.AppName)
+	existing, err := cms.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = cms.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: r.cfg.Namespace,
+				Labels:    map[string]string{"pgedge.com/app-name": r.cfg.AppName},
+			},
+			Data: data,
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	existing.Data = data
+	_, err = cms.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
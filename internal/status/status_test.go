@@ -0,0 +1,248 @@
+// internal/status/status_test.go
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/pgEdge/pgedge-helm/internal/config"
+	"github.com/pgEdge/pgedge-helm/internal/resource"
+	"github.com/pgEdge/pgedge-helm/internal/spock"
+)
+
+func newCluster(name, uid string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "postgresql.cnpg.io/v1",
+			"kind":       "Cluster",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": "default",
+				"uid":       uid,
+			},
+		},
+	}
+}
+
+func newTestRecorder(t *testing.T) (*Recorder, *kubefake.Clientset) {
+	t.Helper()
+	cfg := &config.Config{
+		AppName:   "pgedge",
+		Namespace: "default",
+		Nodes: []config.Node{
+			{Name: "n1"},
+			{Name: "n2"},
+			{Name: "n3", External: true},
+		},
+	}
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{cnpgGVR: "ClusterList"},
+		newCluster("pgedge-n1", "uid-n1"),
+		newCluster("pgedge-n2", "uid-n2"),
+	)
+	kube := kubefake.NewSimpleClientset()
+	return NewRecorder(cfg, kube, dyn), kube
+}
+
+func listEvents(t *testing.T, kube *kubefake.Clientset) []corev1.Event {
+	t.Helper()
+	list, err := kube.CoreV1().Events("default").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	return list.Items
+}
+
+func readReport(t *testing.T, kube *kubefake.Clientset) (*corev1.ConfigMap, Report) {
+	t.Helper()
+	cm, err := kube.CoreV1().ConfigMaps("default").Get(context.Background(), "pgedge-spock-status", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get status configmap: %v", err)
+	}
+	var report Report
+	if err := json.Unmarshal([]byte(cm.Data["status.json"]), &report); err != nil {
+		t.Fatalf("parse status.json: %v", err)
+	}
+	return cm, report
+}
+
+func TestRecorderPublishesEventsAndConfigMap(t *testing.T) {
+	rec, kube := newTestRecorder(t)
+	n1, n2, n3 := config.Node{Name: "n1"}, config.Node{Name: "n2"}, config.Node{Name: "n3"}
+
+	createNode := resource.Event{Action: resource.ActionCreate, Resource: spock.NewSpockNode(n2, "app", "pgedge", nil)}
+	populate := resource.Event{Action: resource.ActionCreate, Resource: spock.NewSubscription(n1, n2, "app", "pgedge", true, nil)}
+	external := resource.Event{Action: resource.ActionCreate, Resource: spock.NewSubscription(n1, n3, "app", "pgedge", false, nil)}
+	rec.Planned([][]resource.Event{{createNode}, {populate, external}})
+	rec.Finished(createNode, 20*time.Millisecond, nil)
+	rec.Finished(populate, 3*time.Second, nil)
+	rec.Finished(external, time.Second, nil)
+
+	subs := []spock.SubscriptionHealth{{Name: "sub_n1_n2", Provider: "n1", Subscriber: "n2", Status: "replicating"}}
+	rec.Publish(context.Background(), nil, subs)
+
+	reasons := map[string]bool{}
+	for _, ev := range listEvents(t, kube) {
+		if ev.InvolvedObject.Kind != "Cluster" || ev.InvolvedObject.APIVersion != "postgresql.cnpg.io/v1" {
+			t.Errorf("event %s not attached to a CNPG Cluster: %+v", ev.Reason, ev.InvolvedObject)
+		}
+		if ev.InvolvedObject.Name == "pgedge-n3" {
+			t.Errorf("unexpected event on external node cluster: %s", ev.Reason)
+		}
+		if ev.InvolvedObject.Name == "pgedge-n2" && ev.InvolvedObject.UID != "uid-n2" {
+			t.Errorf("expected uid-n2, got %q", ev.InvolvedObject.UID)
+		}
+		reasons[ev.Reason] = true
+	}
+	for _, want := range []string{"NodeCreated", "SubscriptionCreated", "PopulateFinished"} {
+		if !reasons[want] {
+			t.Errorf("missing %s event, got %v", want, reasons)
+		}
+	}
+
+	cm, report := readReport(t, kube)
+	if cm.Data["outcome"] != OutcomeSucceeded || report.Outcome != OutcomeSucceeded {
+		t.Errorf("expected outcome %s, got %q / %q", OutcomeSucceeded, cm.Data["outcome"], report.Outcome)
+	}
+	if len(report.Plan) != 3 || report.Plan[0].Phase != 0 || report.Plan[2].Phase != 1 {
+		t.Errorf("unexpected plan: %+v", report.Plan)
+	}
+	if len(report.Actions) != 3 {
+		t.Errorf("expected 3 actions, got %d", len(report.Actions))
+	}
+	if len(report.Subscriptions) != 1 || report.Subscriptions[0].Status != "replicating" {
+		t.Errorf("unexpected subscriptions: %+v", report.Subscriptions)
+	}
+}
+
+func TestRecorderReportsFailure(t *testing.T) {
+	rec, kube := newTestRecorder(t)
+	n1, n2 := config.Node{Name: "n1"}, config.Node{Name: "n2"}
+
+	populate := resource.Event{Action: resource.ActionCreate, Resource: spock.NewSubscription(n1, n2, "app", "pgedge", true, nil)}
+	failed := resource.Event{Action: resource.ActionCreate, Resource: spock.NewSyncEvent("n1", "n2", nil)}
+	rec.Planned([][]resource.Event{{populate, failed}})
+	rec.Finished(populate, time.Second, nil)
+	rec.Finished(failed, time.Second, errors.New("connection refused"))
+
+	// A previous run's ConfigMap is updated in place.
+	_, err := kube.CoreV1().ConfigMaps("default").Create(context.Background(), &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "pgedge-spock-status", Namespace: "default"},
+		Data:       map[string]string{"outcome": OutcomeSucceeded},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec.Publish(context.Background(), errors.New("execute: connection refused"), nil)
+
+	var warned bool
+	for _, ev := range listEvents(t, kube) {
+		if ev.Reason == "PopulateFinished" {
+			t.Error("PopulateFinished must not be emitted for a failed run")
+		}
+		if ev.Reason == "CreateFailed" && ev.Type == corev1.EventTypeWarning && ev.InvolvedObject.Name == "pgedge-n1" {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Error("expected a CreateFailed warning on pgedge-n1")
+	}
+
+	cm, report := readReport(t, kube)
+	if cm.Data["outcome"] != OutcomeFailed {
+		t.Errorf("expected outcome %s, got %q", OutcomeFailed, cm.Data["outcome"])
+	}
+	if report.Error != "execute: connection refused" {
+		t.Errorf("unexpected error in report: %q", report.Error)
+	}
+}
+
+func TestRecorderWritesProgress(t *testing.T) {
+	rec, kube := newTestRecorder(t)
+	n1, n2 := config.Node{Name: "n1"}, config.Node{Name: "n2"}
+	populate := resource.Event{Action: resource.ActionCreate, Resource: spock.NewSubscription(n1, n2, "app", "pgedge", true, nil)}
+	rec.Planned([][]resource.Event{{populate}})
+
+	rec.Progress(populate, resource.Progress{Message: "copying data", Done: 3, Total: 10, Bytes: 1 << 20, ETA: 90 * time.Second})
+	cm, report := readReport(t, kube)
+	if cm.Data["outcome"] != OutcomeRunning || report.Outcome != OutcomeRunning {
+		t.Errorf("expected outcome %s while running, got %q / %q", OutcomeRunning, cm.Data["outcome"], report.Outcome)
+	}
+	if _, ok := cm.Data["finishedAt"]; ok || !report.FinishedAt.IsZero() {
+		t.Errorf("expected no finish time while running, got %q / %v", cm.Data["finishedAt"], report.FinishedAt)
+	}
+	want := ActionProgress{Action: "create", Type: spock.ResourceTypeSubscription, ID: "sub_n1_n2",
+		Message: "copying data", Done: 3, Total: 10, Bytes: 1 << 20, ETASeconds: 90}
+	if len(report.Progress) != 1 || report.Progress[0].UpdatedAt.IsZero() {
+		t.Fatalf("expected the sync's progress, got %+v", report.Progress)
+	}
+	got := report.Progress[0]
+	got.UpdatedAt = time.Time{}
+	if got != want {
+		t.Errorf("expected progress %+v, got %+v", want, got)
+	}
+
+	rec.Progress(populate, resource.Progress{Message: "copying data", Done: 7, Total: 10})
+	if _, report := readReport(t, kube); len(report.Progress) != 1 || report.Progress[0].Done != 7 {
+		t.Errorf("expected only the latest progress, got %+v", report.Progress)
+	}
+
+	rec.Finished(populate, time.Minute, nil)
+	rec.Publish(context.Background(), nil, nil)
+	rec.Progress(populate, resource.Progress{Message: "late report", Done: 10, Total: 10})
+	cm, report = readReport(t, kube)
+	if report.Outcome != OutcomeSucceeded || len(report.Progress) != 0 || cm.Data["finishedAt"] == "" {
+		t.Errorf("expected the final report without progress, got %q with %+v", report.Outcome, report.Progress)
+	}
+}
+
+func TestRecorderProgressWritesWithoutBlockingEvents(t *testing.T) {
+	rec, kube := newTestRecorder(t)
+	n1, n2 := config.Node{Name: "n1"}, config.Node{Name: "n2"}
+	populate := resource.Event{Action: resource.ActionCreate, Resource: spock.NewSubscription(n1, n2, "app", "pgedge", true, nil)}
+	other := resource.Event{Action: resource.ActionCreate, Resource: spock.NewSubscription(n2, n1, "app", "pgedge", false, nil)}
+
+	writing, release := make(chan struct{}), make(chan struct{})
+	kube.PrependReactor("create", "configmaps", func(k8stesting.Action) (bool, runtime.Object, error) {
+		close(writing)
+		<-release
+		return false, nil, nil
+	})
+	reported := make(chan struct{})
+	go func() {
+		rec.Progress(populate, resource.Progress{Message: "copying data", Done: 1, Total: 10})
+		close(reported)
+	}()
+	<-writing
+
+	// Another event finishes while the ConfigMap write is stuck.
+	finished := make(chan struct{})
+	go func() {
+		rec.Finished(other, time.Second, nil)
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Finished blocked on the progress write")
+	}
+	close(release)
+	<-reported
+
+	if _, report := readReport(t, kube); len(report.Progress) != 1 || report.Outcome != OutcomeRunning {
+		t.Errorf("expected the running report with the sync's progress, got %q with %+v", report.Outcome, report.Progress)
+	}
+}
@@ -68,6 +68,12 @@ func TestConfigMapExternalNodes(t *testing.T) {
 	if !strings.Contains(nodes, "external-n3.example.com") {
 		t.Error("ConfigMap nodes missing external hostname external-n3.example.com")
 	}
+
+	// Only the external node is marked, so init-spock skips waiting on a
+	// local CNPG Cluster for it.
+	if strings.Count(nodes, "external: true") != 1 {
+		t.Errorf("expected exactly one node marked external, got:\n%s", nodes)
+	}
 }
 
 func TestConfigMapInternalHostname(t *testing.T) {
@@ -98,3 +104,20 @@ func TestConfigMapInternalHostname(t *testing.T) {
 		t.Error("ConfigMap missing internalHostname pgedge-n2-rw")
 	}
 }
+
+func TestConfigMapRemoteExternalNode(t *testing.T) {
+	objects := renderTemplate(t, "remote-external-nodes-values.yaml")
+	cm := findByKindAndName(objects, "ConfigMap", "pgedge-config")
+	if cm == nil {
+		t.Fatal("pgedge-config ConfigMap not found")
+	}
+
+	data, _, _ := unstructured.NestedStringMap(cm.Object, "data")
+	nodes := data["nodes"]
+	if !strings.Contains(nodes, "kubeconfig: /kubeconfigs/n1/kubeconfig") {
+		t.Errorf("expected mounted kubeconfig path for n1, got:\n%s", nodes)
+	}
+	if strings.Count(nodes, "kubeconfig: /kubeconfigs/") != 1 {
+		t.Errorf("only n1 references a remote cluster, got:\n%s", nodes)
+	}
+}
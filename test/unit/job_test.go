@@ -3,6 +3,7 @@
 package unit
 
 import (
+	"slices"
 	"strings"
 	"testing"
 
@@ -271,3 +272,226 @@ func TestSecurityContextCustomOverride(t *testing.T) {
 		t.Errorf("expected readOnlyRootFilesystem=false, got %v (found=%v)", readOnly, found)
 	}
 }
+
+func TestInitSpockRoleAllowsStatusReporting(t *testing.T) {
+	objects := renderTemplate(t, "distributed-values.yaml")
+	role := findByKindAndName(objects, "Role", "pgedge-init-spock")
+	if role == nil {
+		t.Fatal("pgedge-init-spock Role not found")
+	}
+
+	allowed := func(resource, verb, name string) bool {
+		return roleAllows(role, resource, verb, name)
+	}
+
+	checks := []struct{ resource, verb, name string }{
+		{"clusters", "watch", ""},
+		{"events", "create", ""},
+		{"configmaps", "create", ""},
+		{"configmaps", "get", "pgedge-spock-status"},
+		{"configmaps", "update", "pgedge-spock-status"},
+	}
+	for _, c := range checks {
+		if !allowed(c.resource, c.verb, c.name) {
+			t.Errorf("Role does not allow %s on %s %s", c.verb, c.resource, c.name)
+		}
+	}
+	if allowed("configmaps", "update", "pgedge-config") {
+		t.Error("Role must not allow updating arbitrary ConfigMaps")
+	}
+}
+
+// roleAllows reports whether a rendered Role grants verb on resource,
+// optionally restricted to the named object.
+func roleAllows(role *unstructured.Unstructured, resource, verb, name string) bool {
+	rules, _, _ := unstructured.NestedSlice(role.Object, "rules")
+	for _, r := range rules {
+		rule := r.(map[string]interface{})
+		resources, _, _ := unstructured.NestedStringSlice(rule, "resources")
+		verbs, _, _ := unstructured.NestedStringSlice(rule, "verbs")
+		names, _, _ := unstructured.NestedStringSlice(rule, "resourceNames")
+		if !slices.Contains(resources, resource) || !slices.Contains(verbs, verb) {
+			continue
+		}
+		if len(names) == 0 || slices.Contains(names, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// jobEnv returns the init-spock container's env vars by name.
+func jobEnv(t *testing.T, objects []unstructured.Unstructured) map[string]string {
+	t.Helper()
+	jobs := filterByKind(objects, "Job")
+	if len(jobs) != 1 {
+		t.Fatalf("expected 1 Job, got %d", len(jobs))
+	}
+	containers, _, _ := unstructured.NestedSlice(jobs[0].Object, "spec", "template", "spec", "containers")
+	if len(containers) == 0 {
+		t.Fatal("no containers found in job")
+	}
+	envVars, _, _ := unstructured.NestedSlice(containers[0].(map[string]interface{}), "env")
+	env := map[string]string{}
+	for _, e := range envVars {
+		v := e.(map[string]interface{})
+		value, _ := v["value"].(string)
+		env[v["name"].(string)] = value
+	}
+	return env
+}
+
+func TestInitSpockLockDefaults(t *testing.T) {
+	objects := renderTemplate(t, "distributed-values.yaml")
+	env := jobEnv(t, objects)
+	if env["LOCK_MODE"] != "wait" {
+		t.Errorf("expected LOCK_MODE=wait, got %q", env["LOCK_MODE"])
+	}
+	if _, ok := env["LOCK_LEASE"]; ok {
+		t.Error("LOCK_LEASE should not be set by default")
+	}
+	role := findByKindAndName(objects, "Role", "pgedge-init-spock")
+	if roleAllows(role, "leases", "get", "pgedge-init-spock") {
+		t.Error("Role should not grant leases unless lease is enabled")
+	}
+}
+
+func TestInitSpockLockLease(t *testing.T) {
+	objects := renderTemplate(t, "lock-lease-values.yaml")
+	env := jobEnv(t, objects)
+	if env["LOCK_MODE"] != "exit" || env["LOCK_LEASE"] != "true" {
+		t.Errorf("expected LOCK_MODE=exit LOCK_LEASE=true, got %q %q", env["LOCK_MODE"], env["LOCK_LEASE"])
+	}
+	role := findByKindAndName(objects, "Role", "pgedge-init-spock")
+	for _, verb := range []string{"get", "update", "create"} {
+		if !roleAllows(role, "leases", verb, "pgedge-init-spock") {
+			t.Errorf("Role does not allow %s on the init-spock lease", verb)
+		}
+	}
+}
+
+func TestInitSpockResetNodes(t *testing.T) {
+	if _, ok := jobEnv(t, renderTemplate(t, "distributed-values.yaml"))["RESET_SPOCK"]; ok {
+		t.Error("RESET_SPOCK should not be set by default")
+	}
+	env := jobEnv(t, renderTemplate(t, "reset-nodes-values.yaml"))
+	if env["RESET_SPOCK"] != "n2,n3" {
+		t.Errorf("expected RESET_SPOCK=n2,n3, got %q", env["RESET_SPOCK"])
+	}
+}
+
+func TestInitSpockWaits(t *testing.T) {
+	env := jobEnv(t, renderTemplate(t, "waits-values.yaml"))
+	for name, want := range map[string]string{
+		"WAIT_PEER_CATCHUP_TIMEOUT": "1h",
+		"WAIT_SYNC_EVENT_INTERVAL":  "30s",
+		"WAIT_NODE_READY_TIMEOUT":   "0",
+	} {
+		if env[name] != want {
+			t.Errorf("expected %s=%s, got %q", name, want, env[name])
+		}
+	}
+	for _, name := range []string{"WAIT_PEER_CATCHUP_INTERVAL", "WAIT_SYNC_EVENT_TIMEOUT", "WAIT_CLUSTERS_TIMEOUT"} {
+		if _, ok := env[name]; ok {
+			t.Errorf("%s should not be set", name)
+		}
+	}
+}
+
+func TestInitSpockSnapshotConfigMap(t *testing.T) {
+	objects := renderTemplate(t, "distributed-values.yaml")
+	if _, ok := jobEnv(t, objects)["REPSET_SNAPSHOT_CONFIGMAP"]; ok {
+		t.Error("REPSET_SNAPSHOT_CONFIGMAP should not be set by default")
+	}
+	role := findByKindAndName(objects, "Role", "pgedge-init-spock")
+	if roleAllows(role, "configmaps", "update", "pgedge-repset-snapshot") {
+		t.Error("Role should not grant the snapshot ConfigMap unless snapshotConfigMap is enabled")
+	}
+
+	objects = renderTemplate(t, "snapshot-configmap-values.yaml")
+	if env := jobEnv(t, objects); env["REPSET_SNAPSHOT_CONFIGMAP"] != "true" {
+		t.Errorf("expected REPSET_SNAPSHOT_CONFIGMAP=true, got %q", env["REPSET_SNAPSHOT_CONFIGMAP"])
+	}
+	role = findByKindAndName(objects, "Role", "pgedge-init-spock")
+	for _, verb := range []string{"get", "update", "create"} {
+		if !roleAllows(role, "configmaps", verb, "pgedge-repset-snapshot") {
+			t.Errorf("Role does not allow %s on the repset snapshot ConfigMap", verb)
+		}
+	}
+}
+
+func TestInitSpockVerifyData(t *testing.T) {
+	objects := renderTemplate(t, "distributed-values.yaml")
+	if env := jobEnv(t, objects); env["VERIFY_DATA"] != "off" {
+		t.Errorf("expected VERIFY_DATA=off by default, got %q", env["VERIFY_DATA"])
+	}
+
+	objects = renderTemplate(t, "verify-data-values.yaml")
+	if env := jobEnv(t, objects); env["VERIFY_DATA"] != "fail" {
+		t.Errorf("expected VERIFY_DATA=fail, got %q", env["VERIFY_DATA"])
+	}
+}
+
+func TestInitSpockDegradedMode(t *testing.T) {
+	objects := renderTemplate(t, "distributed-values.yaml")
+	if _, ok := jobEnv(t, objects)["DEGRADED_MODE"]; ok {
+		t.Error("DEGRADED_MODE should not be set by default")
+	}
+
+	objects = renderTemplate(t, "degraded-mode-values.yaml")
+	if env := jobEnv(t, objects); env["DEGRADED_MODE"] != "true" {
+		t.Errorf("expected DEGRADED_MODE=true, got %q", env["DEGRADED_MODE"])
+	}
+}
+
+func TestInitSpockSQLTrace(t *testing.T) {
+	objects := renderTemplate(t, "distributed-values.yaml")
+	env := jobEnv(t, objects)
+	for _, name := range []string{"SQL_TRACE", "SQL_TRACE_ARGS"} {
+		if _, ok := env[name]; ok {
+			t.Errorf("%s should not be set by default", name)
+		}
+	}
+
+	objects = renderTemplate(t, "sql-trace-values.yaml")
+	env = jobEnv(t, objects)
+	if env["SQL_TRACE"] != "debug" || env["SQL_TRACE_ARGS"] != "true" {
+		t.Errorf("expected SQL_TRACE=debug and SQL_TRACE_ARGS=true, got %q and %q", env["SQL_TRACE"], env["SQL_TRACE_ARGS"])
+	}
+}
+
+func TestInitSpockJobMountsRemoteKubeconfigs(t *testing.T) {
+	objects := renderTemplate(t, "remote-external-nodes-values.yaml")
+	jobs := filterByKind(objects, "Job")
+	if len(jobs) != 1 {
+		t.Fatalf("expected 1 Job, got %d", len(jobs))
+	}
+
+	volumes, _, _ := unstructured.NestedSlice(jobs[0].Object, "spec", "template", "spec", "volumes")
+	var secretName string
+	for _, v := range volumes {
+		vol := v.(map[string]interface{})
+		if vol["name"] == "kubeconfig-n1" {
+			secretName, _, _ = unstructured.NestedString(vol, "secret", "secretName")
+		}
+		if vol["name"] == "kubeconfig-n3" {
+			t.Error("n3 has no remote cluster and should not get a kubeconfig volume")
+		}
+	}
+	if secretName != "cluster-a-kubeconfig" {
+		t.Errorf("expected kubeconfig-n1 volume from cluster-a-kubeconfig, got %q", secretName)
+	}
+
+	containers, _, _ := unstructured.NestedSlice(jobs[0].Object, "spec", "template", "spec", "containers")
+	mounts, _, _ := unstructured.NestedSlice(containers[0].(map[string]interface{}), "volumeMounts")
+	for _, m := range mounts {
+		mount := m.(map[string]interface{})
+		if mount["name"] == "kubeconfig-n1" {
+			if mount["mountPath"] != "/kubeconfigs/n1" {
+				t.Errorf("expected mount at /kubeconfigs/n1, got %v", mount["mountPath"])
+			}
+			return
+		}
+	}
+	t.Error("kubeconfig-n1 not mounted into the init-spock container")
+}
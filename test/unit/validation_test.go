@@ -35,6 +35,28 @@ func TestNodesInvalidBootstrapMode(t *testing.T) {
 	}
 }
 
+func TestNodesInvalidBootstrapStructure(t *testing.T) {
+	output := renderTemplateExpectError(t, "invalid-bootstrap-structure-values.yaml")
+	if !strings.Contains(output, "structure") {
+		t.Errorf("expected schema validation error about bootstrap.structure enum, got:\n%s", output)
+	}
+}
+
+func TestInvalidLockMode(t *testing.T) {
+	output := renderTemplateExpectError(t, "invalid-lock-mode-values.yaml")
+	if !strings.Contains(output, "lockMode") {
+		t.Errorf("expected schema validation error about initSpockJobConfig.lockMode enum, got:\n%s", output)
+	}
+}
+
+func TestExternalNodeRemoteRejectsUnknownKey(t *testing.T) {
+	// A misspelled kubeconfigSecret would otherwise leave the node unwatched.
+	output := renderTemplateExpectError(t, "invalid-remote-key-values.yaml")
+	if !strings.Contains(output, "kubeConfigSecret") || !strings.Contains(output, "kubeconfigSecret") {
+		t.Errorf("expected schema validation errors about externalNodes[].remote, got:\n%s", output)
+	}
+}
+
 // Tests for validate.newNodes in _helpers.tpl.
 // During helm template --is-upgrade, lookup returns empty so all nodes appear new.
 